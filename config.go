@@ -7,29 +7,76 @@ import (
 	"strings"
 
 	"github.com/qawatake/obsdconv/convert"
+	"github.com/qawatake/obsdconv/process"
 )
 
 const (
-	FLAG_SOURCE             = "src"
-	FLAG_DESTINATION        = "dst"
-	FLAG_TARGET             = "tgt"
-	FLAG_REMOVE_TAGS        = "rmtag"
-	FLAG_COPY_TAGS          = "cptag"
-	FLAG_SYNC_TAGS          = "synctag"
-	FLAG_COPY_TITLE         = "title"
-	FLAG_COPY_ALIASES       = "alias"
-	FLAG_SYNC_TITLE_ALIASES = "synctlal"
-	FLAG_CONVERT_LINKS      = "link"
-	FLAG_REMOVE_COMMENT     = "cmmt"
-	FLAG_PUBLISHABLE        = "pub"
-	FLAG_REMOVE_H1          = "rmh1"
-	FLAG_REMAP_META_KEYS    = "remapkey"
-	FLAG_FILTER             = "filter"
+	FLAG_SOURCE                 = "src"
+	FLAG_DESTINATION            = "dst"
+	FLAG_TARGET                 = "tgt"
+	FLAG_REMOVE_TAGS            = "rmtag"
+	FLAG_LINK_TAGS              = "linktag"
+	FLAG_TAG_LINK_TEMPLATE      = "tagLinkTemplate"
+	FLAG_COPY_TAGS              = "cptag"
+	FLAG_SYNC_TAGS              = "synctag"
+	FLAG_EXPAND_TAGS            = "expandtag"
+	FLAG_LOWER_TAGS             = "lowertag"
+	FLAG_SLUGIFY_TAGS           = "slugtag"
+	FLAG_TAG_MAP                = "tagMap"
+	FLAG_ROUTE_TAG              = "routeTag"
+	FLAG_FRONT_MATTER_TAG_RULES = "fmtagrule"
+	FLAG_COPY_TITLE             = "title"
+	FLAG_FALLBACK_TITLE         = "fallbacktitle"
+	FLAG_TITLE_TEMPLATE         = "titleTemplate"
+	FLAG_COPY_ALIASES           = "alias"
+	FLAG_SYNC_TITLE_ALIASES     = "synctlal"
+	FLAG_CONVERT_LINKS          = "link"
+	FLAG_REMOVE_COMMENT         = "cmmt"
+	FLAG_CONVERT_FOOTNOTES      = "footnote"
+	FLAG_CALLOUT                = "callout"
+	FLAG_CALLOUT_ALIASES        = "calloutAlias"
+	FLAG_HIGHLIGHT              = "highlight"
+	FLAG_HIGHLIGHT_WRAPPER      = "highlightWrapper"
+	FLAG_HTML_COMMENT           = "htmlcmmt"
+	FLAG_DATAVIEW               = "dataview"
+	FLAG_INLINE_FIELDS          = "inlinefield"
+	FLAG_REMOVE_INLINE_FIELDS   = "rminlinefield"
+	FLAG_INLINE_FIELD_MERGE     = "inlineFieldMerge"
+	FLAG_TASK_COUNTS            = "taskcount"
+	FLAG_TASK_REPORT            = "taskReport"
+	FLAG_MATH                   = "math"
+	FLAG_SET_MATH               = "setmath"
+	FLAG_SUMMARY                = "summary"
+	FLAG_SUMMARY_LENGTH         = "summaryLength"
+	FLAG_READING_TIME           = "readingtime"
+	FLAG_SET_DATE               = "setdate"
+	FLAG_SET_LASTMOD            = "setlastmod"
+	FLAG_DATE_KEY               = "dateKey"
+	FLAG_LASTMOD_KEY            = "lastmodKey"
+	FLAG_DATE_FORMAT            = "dateFormat"
+	FLAG_OVERWRITE_DATES        = "overwritedate"
+	FLAG_FIX_FRONT_MATTER       = "fixfm"
+	FLAG_PUBLISHABLE            = "pub"
+	FLAG_REMOVE_H1              = "rmh1"
+	FLAG_SHIFT_HEADINGS         = "shiftHeadings"
+	FLAG_TOC                    = "toc"
+	FLAG_TOC_AFTER_H1           = "toch1"
+	FLAG_TOC_MIN_LEVEL          = "tocMinLevel"
+	FLAG_TOC_MAX_LEVEL          = "tocMaxLevel"
+	FLAG_REMAP_META_KEYS        = "remapkey"
+	FLAG_FRONT_MATTER_RULES     = "fmrules"
+	FLAG_SCHEMA                 = "schema"
+	FLAG_STRICT_SCHEMA          = "strictschema"
+	FLAG_FILTER                 = "filter"
+	FLAG_FRONT_MATTER_FORMAT    = "frontmatter"
 	// FLAG_BASE_URL           = "baseUrl"
 	FLAG_REMAP_PATH_PREFIX = "remapPathPrefix"
 	FLAG_FORMAT_LINK       = "formatLink"
 	FLAG_FORMAT_ANCHOR     = "formatAnchor"
+	FLAG_VAULTS            = "vaults"
 	FLAG_STRICT_REF        = "strictref"
+	FLAG_IMPORT            = "import"
+	FLAG_IMPORT_TAGS       = "importtag"
 	FLAG_OBSIDIAN_USAGE    = "obs"
 	FLAG_STANDARD_USAGE    = "std"
 	FLAG_VERSION           = "version"
@@ -37,30 +84,76 @@ const (
 )
 
 type configuration struct {
-	src         string
-	dst         string
-	tgt         string
-	rmtag       bool
-	cptag       bool
-	synctag     bool
-	title       bool
-	alias       bool
-	synctlal    bool
-	link        bool
-	cmmt        bool
-	publishable bool
-	rmH1        bool
-	strictref   bool
-	remapkey    string
-	filter      string
+	src           string
+	dst           string
+	tgt           string
+	rmtag         bool
+	linktag       bool
+	cptag         bool
+	synctag       bool
+	expandtag     bool
+	lowertag      bool
+	slugtag       bool
+	fmtagrule     bool
+	title         bool
+	fallbackTitle bool
+	alias         bool
+	synctlal      bool
+	link          bool
+	cmmt          bool
+	footnote      bool
+	highlight     bool
+	htmlcmmt      bool
+	dataview      bool
+	inlineField   bool
+	rmInlineField bool
+	taskCount     bool
+	setmath       bool
+	summary       bool
+	readingTime   bool
+	setdate       bool
+	setlastmod    bool
+	overwriteDate bool
+	fixfm         bool
+	publishable   bool
+	rmH1          bool
+	toc           bool
+	tocAfterH1    bool
+	strictref     bool
+	imprt         bool
+	importtag     bool
+	remapkey      string
+	fmrules       string
+	schema        string
+	strictSchema  bool
+	tagMap        string
+	routeTag      string
+	filter        string
+	fmformat      string
 	// baseUrl         string
-	remapPathPrefix string
-	formatLink      bool
-	formatAnchor    string
-	obs             bool
-	std             bool
-	ver             bool
-	debug           bool
+	remapPathPrefix  string
+	formatLink       bool
+	formatAnchor     string
+	vaults           string
+	callout          string
+	calloutAlias     string
+	highlightWrapper string
+	inlineFieldMerge string
+	taskReport       string
+	math             string
+	tagLinkTemplate  string
+	titleTemplate    string
+	shiftHeadings    int
+	tocMinLevel      int
+	tocMaxLevel      int
+	summaryLength    int
+	dateKey          string
+	lastmodKey       string
+	dateFormat       string
+	obs              bool
+	std              bool
+	ver              bool
+	debug            bool
 }
 
 type mainErrKind int
@@ -80,6 +173,41 @@ const (
 	MAIN_ERR_KIND_INVALID_ANCHOR_FORMATTING_STYLE
 	MAIN_ERR_KIND_REMAP_PATH_PREFIX_NEEDS_LINK
 	MAIN_ERR_KIND_INVALID_REMAP_PATH_PREFIX_FORMAT
+	MAIN_ERR_KIND_IMPORT_CONFLICTS_WITH_LINK
+	MAIN_ERR_KIND_IMPORT_TAGS_NEEDS_IMPORT
+	MAIN_ERR_KIND_VAULTS_NEEDS_LINK
+	MAIN_ERR_KIND_INVALID_VAULTS_FORMAT
+	MAIN_ERR_KIND_INVALID_CALLOUT_STYLE
+	MAIN_ERR_KIND_CALLOUT_ALIASES_NEEDS_CALLOUT
+	MAIN_ERR_KIND_INVALID_CALLOUT_ALIASES_FORMAT
+	MAIN_ERR_KIND_HTML_COMMENT_CONFLICTS_WITH_CMMT
+	MAIN_ERR_KIND_HIGHLIGHT_WRAPPER_NEEDS_HIGHLIGHT
+	MAIN_ERR_KIND_INVALID_HIGHLIGHT_WRAPPER_FORMAT
+	MAIN_ERR_KIND_REMOVE_INLINE_FIELDS_NEEDS_INLINE_FIELDS
+	MAIN_ERR_KIND_INVALID_INLINE_FIELD_MERGE_POLICY
+	MAIN_ERR_KIND_INLINE_FIELD_MERGE_NEEDS_INLINE_FIELDS
+	MAIN_ERR_KIND_INVALID_TASK_REPORT_FORMAT
+	MAIN_ERR_KIND_INVALID_MATH_STYLE
+	MAIN_ERR_KIND_INVALID_TAG_MAP_FORMAT
+	MAIN_ERR_KIND_INVALID_ROUTE_TAG_FORMAT
+	MAIN_ERR_KIND_LINK_TAGS_CONFLICTS_WITH_RMTAG
+	MAIN_ERR_KIND_TAG_LINK_TEMPLATE_NEEDS_LINK_TAGS
+	MAIN_ERR_KIND_INVALID_TAG_LINK_TEMPLATE_FORMAT
+	MAIN_ERR_KIND_FALLBACK_TITLE_NEEDS_TITLE
+	MAIN_ERR_KIND_TITLE_TEMPLATE_NEEDS_TITLE
+	MAIN_ERR_KIND_INVALID_TITLE_TEMPLATE
+	MAIN_ERR_KIND_TOC_LEVEL_NEEDS_TOC
+	MAIN_ERR_KIND_INVALID_TOC_LEVEL
+	MAIN_ERR_KIND_SUMMARY_LENGTH_NEEDS_SUMMARY
+	MAIN_ERR_KIND_INVALID_SUMMARY_LENGTH
+	MAIN_ERR_KIND_DATE_KEY_NEEDS_SET_DATE
+	MAIN_ERR_KIND_LASTMOD_KEY_NEEDS_SET_LASTMOD
+	MAIN_ERR_KIND_DATE_OPTIONS_NEED_SET_DATES
+	MAIN_ERR_KIND_INVALID_FRONT_MATTER_RULES_FORMAT
+	MAIN_ERR_KIND_STRICT_SCHEMA_NEEDS_SCHEMA
+	MAIN_ERR_KIND_INVALID_SCHEMA_FORMAT
+	MAIN_ERR_KIND_SCHEMA_VIOLATED
+	MAIN_ERR_KIND_INVALID_FRONT_MATTER_FORMAT
 	// MAIN_ERR_KIND_BASE_URL_NEEDS_LINK
 )
 
@@ -128,6 +256,60 @@ func newMainErr(kind mainErrKind) mainErr {
 		err.message = fmt.Sprintf("%s set but not %s", FLAG_FORMAT_LINK, FLAG_CONVERT_LINKS)
 	case MAIN_ERR_KIND_REMAP_PATH_PREFIX_NEEDS_LINK:
 		err.message = fmt.Sprintf("%s set but not %s", FLAG_REMAP_PATH_PREFIX, FLAG_CONVERT_LINKS)
+	case MAIN_ERR_KIND_IMPORT_CONFLICTS_WITH_LINK:
+		err.message = fmt.Sprintf("%s and %s cannot be set at the same time", FLAG_IMPORT, FLAG_CONVERT_LINKS)
+	case MAIN_ERR_KIND_IMPORT_TAGS_NEEDS_IMPORT:
+		err.message = fmt.Sprintf("%s set but not %s", FLAG_IMPORT_TAGS, FLAG_IMPORT)
+	case MAIN_ERR_KIND_VAULTS_NEEDS_LINK:
+		err.message = fmt.Sprintf("%s set but not %s", FLAG_VAULTS, FLAG_CONVERT_LINKS)
+	case MAIN_ERR_KIND_INVALID_CALLOUT_STYLE:
+		err.message = fmt.Sprintf("%s is invalid. must choose from %s", FLAG_CALLOUT, strings.Join(convert.CALLOUT_STYLES, ", "))
+	case MAIN_ERR_KIND_CALLOUT_ALIASES_NEEDS_CALLOUT:
+		err.message = fmt.Sprintf("%s set but not %s", FLAG_CALLOUT_ALIASES, FLAG_CALLOUT)
+	case MAIN_ERR_KIND_HTML_COMMENT_CONFLICTS_WITH_CMMT:
+		err.message = fmt.Sprintf("%s and %s cannot be set at the same time", FLAG_HTML_COMMENT, FLAG_REMOVE_COMMENT)
+	case MAIN_ERR_KIND_HIGHLIGHT_WRAPPER_NEEDS_HIGHLIGHT:
+		err.message = fmt.Sprintf("%s set but not %s", FLAG_HIGHLIGHT_WRAPPER, FLAG_HIGHLIGHT)
+	case MAIN_ERR_KIND_INVALID_HIGHLIGHT_WRAPPER_FORMAT:
+		err.message = fmt.Sprintf("%s must contain {}", FLAG_HIGHLIGHT_WRAPPER)
+	case MAIN_ERR_KIND_REMOVE_INLINE_FIELDS_NEEDS_INLINE_FIELDS:
+		err.message = fmt.Sprintf("%s set but not %s", FLAG_REMOVE_INLINE_FIELDS, FLAG_INLINE_FIELDS)
+	case MAIN_ERR_KIND_INVALID_INLINE_FIELD_MERGE_POLICY:
+		err.message = fmt.Sprintf("%s is invalid. must choose from %s", FLAG_INLINE_FIELD_MERGE, strings.Join(INLINE_FIELD_MERGE_POLICIES, ", "))
+	case MAIN_ERR_KIND_INLINE_FIELD_MERGE_NEEDS_INLINE_FIELDS:
+		err.message = fmt.Sprintf("%s set but not %s", FLAG_INLINE_FIELD_MERGE, FLAG_INLINE_FIELDS)
+	case MAIN_ERR_KIND_INVALID_TASK_REPORT_FORMAT:
+		err.message = fmt.Sprintf("%s is invalid. must choose from %s", FLAG_TASK_REPORT, strings.Join(TASK_REPORT_FORMATS, ", "))
+	case MAIN_ERR_KIND_INVALID_MATH_STYLE:
+		err.message = fmt.Sprintf("%s is invalid. must choose from %s", FLAG_MATH, strings.Join(convert.MATH_STYLES, ", "))
+	case MAIN_ERR_KIND_LINK_TAGS_CONFLICTS_WITH_RMTAG:
+		err.message = fmt.Sprintf("%s and %s cannot be set at the same time", FLAG_LINK_TAGS, FLAG_REMOVE_TAGS)
+	case MAIN_ERR_KIND_TAG_LINK_TEMPLATE_NEEDS_LINK_TAGS:
+		err.message = fmt.Sprintf("%s set but not %s", FLAG_TAG_LINK_TEMPLATE, FLAG_LINK_TAGS)
+	case MAIN_ERR_KIND_INVALID_TAG_LINK_TEMPLATE_FORMAT:
+		err.message = fmt.Sprintf("%s must contain {}", FLAG_TAG_LINK_TEMPLATE)
+	case MAIN_ERR_KIND_FALLBACK_TITLE_NEEDS_TITLE:
+		err.message = fmt.Sprintf("%s set but neither %s nor %s", FLAG_FALLBACK_TITLE, FLAG_COPY_TITLE, FLAG_SYNC_TITLE_ALIASES)
+	case MAIN_ERR_KIND_TITLE_TEMPLATE_NEEDS_TITLE:
+		err.message = fmt.Sprintf("%s set but neither %s nor %s", FLAG_TITLE_TEMPLATE, FLAG_COPY_TITLE, FLAG_SYNC_TITLE_ALIASES)
+	case MAIN_ERR_KIND_TOC_LEVEL_NEEDS_TOC:
+		err.message = fmt.Sprintf("%s or %s set but neither %s nor %s", FLAG_TOC_MIN_LEVEL, FLAG_TOC_MAX_LEVEL, FLAG_TOC, FLAG_TOC_AFTER_H1)
+	case MAIN_ERR_KIND_INVALID_TOC_LEVEL:
+		err.message = fmt.Sprintf("%s and %s must be between 1 and 6 and %s <= %s", FLAG_TOC_MIN_LEVEL, FLAG_TOC_MAX_LEVEL, FLAG_TOC_MIN_LEVEL, FLAG_TOC_MAX_LEVEL)
+	case MAIN_ERR_KIND_SUMMARY_LENGTH_NEEDS_SUMMARY:
+		err.message = fmt.Sprintf("%s set but %s not set", FLAG_SUMMARY_LENGTH, FLAG_SUMMARY)
+	case MAIN_ERR_KIND_INVALID_SUMMARY_LENGTH:
+		err.message = fmt.Sprintf("%s must be positive", FLAG_SUMMARY_LENGTH)
+	case MAIN_ERR_KIND_DATE_KEY_NEEDS_SET_DATE:
+		err.message = fmt.Sprintf("%s set but %s not set", FLAG_DATE_KEY, FLAG_SET_DATE)
+	case MAIN_ERR_KIND_LASTMOD_KEY_NEEDS_SET_LASTMOD:
+		err.message = fmt.Sprintf("%s set but %s not set", FLAG_LASTMOD_KEY, FLAG_SET_LASTMOD)
+	case MAIN_ERR_KIND_DATE_OPTIONS_NEED_SET_DATES:
+		err.message = fmt.Sprintf("%s or %s set but neither %s nor %s", FLAG_DATE_FORMAT, FLAG_OVERWRITE_DATES, FLAG_SET_DATE, FLAG_SET_LASTMOD)
+	case MAIN_ERR_KIND_STRICT_SCHEMA_NEEDS_SCHEMA:
+		err.message = fmt.Sprintf("%s set but not %s", FLAG_STRICT_SCHEMA, FLAG_SCHEMA)
+	case MAIN_ERR_KIND_INVALID_FRONT_MATTER_FORMAT:
+		err.message = fmt.Sprintf("%s is invalid. must choose from %s", FLAG_FRONT_MATTER_FORMAT, strings.Join(process.FRONT_MATTER_FORMATS, ", "))
 	default:
 		err.kind = MAIN_ERR_UNEXPECTED
 		err.message = "unexpected error"
@@ -149,22 +331,68 @@ func initFlags(flagset *flag.FlagSet, config *configuration) {
 	flagset.StringVar(&config.dst, FLAG_DESTINATION, "", "destination directory")
 	flagset.StringVar(&config.tgt, FLAG_TARGET, "", "the path that will be processed. It can be a file or a directory. The default value of tgt = the directory specified by src flag. This option will be used when you want to process only a subset of a vault but resolve refs by the entire vault.")
 	flagset.BoolVar(&config.rmtag, FLAG_REMOVE_TAGS, false, "remove tag")
+	flagset.BoolVar(&config.linktag, FLAG_LINK_TAGS, false, fmt.Sprintf("replace tags in text with links to tag pages. Example: #k8s -> [#k8s](/tags/k8s/). URLs follow %s, %s and %s, and tags moved by %s are not linked. tags in display names of links are left as they are. cannot be used with %s", FLAG_TAG_MAP, FLAG_LOWER_TAGS, FLAG_SLUGIFY_TAGS, FLAG_ROUTE_TAG, FLAG_REMOVE_TAGS))
+	flagset.StringVar(&config.tagLinkTemplate, FLAG_TAG_LINK_TEMPLATE, "", fmt.Sprintf("URL of tag pages. {} is replaced with the tag. Example: -tagLinkTemplate=\"https://example.com/tags/{}\". default: %s. available only when %s is on", convert.DEFAULT_TAG_LINK_TEMPLATE, FLAG_LINK_TAGS))
 	flagset.BoolVar(&config.cptag, FLAG_COPY_TAGS, false, "copy tag to tags field of front matter")
 	flagset.BoolVar(&config.synctag, FLAG_SYNC_TAGS, false, "remove all tags in front matter and then copy tags from text")
+	flagset.BoolVar(&config.expandtag, FLAG_EXPAND_TAGS, false, "add ancestors of nested tags found in text to front matter. Example: #area/infra/k8s -> area, area/infra, area/infra/k8s")
+	flagset.BoolVar(&config.lowertag, FLAG_LOWER_TAGS, false, "lowercase tags found in text")
+	flagset.BoolVar(&config.slugtag, FLAG_SLUGIFY_TAGS, false, "lowercase tags found in text and replace characters other than letters, numbers, _ and / with -")
+	flagset.StringVar(&config.tagMap, FLAG_TAG_MAP, "", "path to a YAML file renaming tags found in text. format: \"old: new\" per line. several tags can be merged into one")
+	flagset.StringVar(&config.routeTag, FLAG_ROUTE_TAG, "", "move tags with specified prefixes found in text to other fields of front matter. Example (-routeTag=cat>categories|series>series): #cat/tech -> categories: [tech]")
+	flagset.BoolVar(&config.fmtagrule, FLAG_FRONT_MATTER_TAG_RULES, false, fmt.Sprintf("apply %s, %s, %s, %s and %s also to tags already in front matter", FLAG_EXPAND_TAGS, FLAG_LOWER_TAGS, FLAG_SLUGIFY_TAGS, FLAG_TAG_MAP, FLAG_ROUTE_TAG))
 	flagset.BoolVar(&config.title, FLAG_COPY_TITLE, false, "copy h1 content to title field of front matter")
+	flagset.BoolVar(&config.fallbackTitle, FLAG_FALLBACK_TITLE, false, fmt.Sprintf("if no h1 is found, use the title field of front matter, the first heading of any level or the file name without .md, in this order. available only when %s or %s is on", FLAG_COPY_TITLE, FLAG_SYNC_TITLE_ALIASES))
+	flagset.StringVar(&config.titleTemplate, FLAG_TITLE_TEMPLATE, "", fmt.Sprintf("Go template for title. Available fields: .Title, .H1, .Heading, .FileName, .Date (date prefix of the file name). trimDate removes a date prefix. Example: -titleTemplate=\"{{trimDate .FileName}}\" for 2026-10-17 Standup.md -> Standup. available only when %s or %s is on", FLAG_COPY_TITLE, FLAG_SYNC_TITLE_ALIASES))
 	flagset.BoolVar(&config.alias, FLAG_COPY_ALIASES, false, "copy add h1 content to aliases field of front matter")
 	flagset.BoolVar(&config.synctlal, FLAG_SYNC_TITLE_ALIASES, false, "remove an alias appearing also in title field and then copy h1 content to title and aliases fields")
 	flagset.BoolVar(&config.link, FLAG_CONVERT_LINKS, false, "convert obsidian internal and external links to external links in the usual format")
 	flagset.BoolVar(&config.cmmt, FLAG_REMOVE_COMMENT, false, "remove obsidian comment")
+	flagset.StringVar(&config.callout, FLAG_CALLOUT, "", fmt.Sprintf("convert obsidian callouts. Available styles: %s", strings.Join(convert.CALLOUT_STYLES, ", ")))
+	flagset.StringVar(&config.calloutAlias, FLAG_CALLOUT_ALIASES, "", fmt.Sprintf("map callout types to other types. Example (-calloutAlias=caution>warning|hint>tip): > [!caution] -> :::warning. available only when %s is set", FLAG_CALLOUT))
+	flagset.BoolVar(&config.highlight, FLAG_HIGHLIGHT, false, "convert obsidian highlights ==text== to <mark>text</mark>")
+	flagset.StringVar(&config.highlightWrapper, FLAG_HIGHLIGHT_WRAPPER, "", fmt.Sprintf("wrapper for highlighted text. {} is replaced with the text. Example: -highlightWrapper=\"<span class=hl>{}</span>\". default: %s. available only when %s is on", convert.DEFAULT_HIGHLIGHT_WRAPPER, FLAG_HIGHLIGHT))
+	flagset.BoolVar(&config.htmlcmmt, FLAG_HTML_COMMENT, false, fmt.Sprintf("convert obsidian comments %%%%text%%%% to html comments <!--text-->. cannot be used with %s", FLAG_REMOVE_COMMENT))
+	flagset.BoolVar(&config.dataview, FLAG_DATAVIEW, false, "render dataview code blocks (a subset of LIST and TABLE queries) as markdown lists and tables. unsupported queries are reported as warnings")
+	flagset.BoolVar(&config.inlineField, FLAG_INLINE_FIELDS, false, "copy dataview inline fields (key:: value, [key:: value], (key:: value)) to front matter. values are parsed as bool, number, date or comma-separated list")
+	flagset.BoolVar(&config.rmInlineField, FLAG_REMOVE_INLINE_FIELDS, false, fmt.Sprintf("remove inline fields from text. a line consisting of an inline field is removed entirely. available only when %s is on", FLAG_INLINE_FIELDS))
+	flagset.StringVar(&config.inlineFieldMerge, FLAG_INLINE_FIELD_MERGE, "", fmt.Sprintf("how to handle inline fields whose keys already exist in front matter. Available policies: %s. default: %s. available only when %s is on", strings.Join(INLINE_FIELD_MERGE_POLICIES, ", "), INLINE_FIELD_MERGE_KEEP, FLAG_INLINE_FIELDS))
+	flagset.StringVar(&config.math, FLAG_MATH, "", fmt.Sprintf("rewrite math $...$ and $$...$$ for renderers. Available styles: %s", strings.Join(convert.MATH_STYLES, ", ")))
+	flagset.BoolVar(&config.setmath, FLAG_SET_MATH, false, "add math: true to front matter of notes containing math unless math field already exists")
+	flagset.BoolVar(&config.taskCount, FLAG_TASK_COUNTS, false, "add the numbers of open and done tasks (- [ ] task) to tasks_open and tasks_done fields of front matter. notes without tasks are left as they are")
+	flagset.BoolVar(&config.summary, FLAG_SUMMARY, false, "add a plain-text summary of the converted text to description field of front matter unless it already exists. the summary is the text before <!--more--> or the first characters of the text")
+	flagset.IntVar(&config.summaryLength, FLAG_SUMMARY_LENGTH, 0, fmt.Sprintf("the maximum number of characters in summaries without <!--more-->. default: %d. available only when %s is on", convert.DEFAULT_SUMMARY_LEN, FLAG_SUMMARY))
+	flagset.BoolVar(&config.readingTime, FLAG_READING_TIME, false, "add wordcount and readingtime (minutes) fields to front matter. each CJK character is counted as a word")
+	flagset.BoolVar(&config.setdate, FLAG_SET_DATE, false, fmt.Sprintf("add date field to front matter from, in priority order, the field named by %s, a date at the beginning of the file name (2006-01-02), the first commit of the file in the local git repository and the modification time", FLAG_DATE_KEY))
+	flagset.BoolVar(&config.setlastmod, FLAG_SET_LASTMOD, false, fmt.Sprintf("add lastmod field to front matter from, in priority order, the field named by %s, the last commit of the file in the local git repository and the modification time", FLAG_LASTMOD_KEY))
+	flagset.StringVar(&config.dateKey, FLAG_DATE_KEY, "", fmt.Sprintf("the front matter field used as date. default: %s. available only when %s is on", DEFAULT_DATE_KEY, FLAG_SET_DATE))
+	flagset.StringVar(&config.lastmodKey, FLAG_LASTMOD_KEY, "", fmt.Sprintf("the front matter field used as lastmod. default: %s. available only when %s is on", DEFAULT_LASTMOD_KEY, FLAG_SET_LASTMOD))
+	flagset.StringVar(&config.dateFormat, FLAG_DATE_FORMAT, "", fmt.Sprintf("the format of date and lastmod in Go's time layout. Example: -dateFormat=2006-01-02. default: %s", DEFAULT_DATE_FORMAT))
+	flagset.BoolVar(&config.overwriteDate, FLAG_OVERWRITE_DATES, false, fmt.Sprintf("overwrite date and lastmod fields if they already exist. available only when %s or %s is on", FLAG_SET_DATE, FLAG_SET_LASTMOD))
+	flagset.StringVar(&config.taskReport, FLAG_TASK_REPORT, "", fmt.Sprintf("instead of converting, print all tasks in tgt with status, dates, priority, tags and source note/line. Available formats: %s. %s is not needed", strings.Join(TASK_REPORT_FORMATS, ", "), FLAG_DESTINATION))
+	flagset.BoolVar(&config.footnote, FLAG_CONVERT_FOOTNOTES, false, "convert obsidian inline footnotes ^[...] to numbered footnotes [^n] with definitions at the end")
+	flagset.BoolVar(&config.fixfm, FLAG_FIX_FRONT_MATTER, false, "rewrite tags and aliases fields of source notes in tgt into lists before converting. Example: tags: \"#a, b\" -> tags: [a, b], alias: x -> aliases: [x]")
 	flagset.BoolVar(&config.publishable, FLAG_PUBLISHABLE, false, "process only files with publish: true or draft: false. For files with publish: true, add draft: false.")
 	flagset.BoolVar(&config.rmH1, FLAG_REMOVE_H1, false, "remove H1")
+	flagset.BoolVar(&config.toc, FLAG_TOC, false, "replace [TOC] or %%toc%% lines with a nested list of links to headings. anchors follow formatAnchor")
+	flagset.BoolVar(&config.tocAfterH1, FLAG_TOC_AFTER_H1, false, fmt.Sprintf("same as %s, but insert a table of contents after H1 (or at the beginning) of notes without [TOC] or %%%%toc%%%%", FLAG_TOC))
+	flagset.IntVar(&config.tocMinLevel, FLAG_TOC_MIN_LEVEL, 0, fmt.Sprintf("the minimum heading level in tables of contents. default: %d", convert.DEFAULT_TOC_MIN_LEVEL))
+	flagset.IntVar(&config.tocMaxLevel, FLAG_TOC_MAX_LEVEL, 0, fmt.Sprintf("the maximum heading level in tables of contents. default: %d", convert.DEFAULT_TOC_MAX_LEVEL))
+	flagset.IntVar(&config.shiftHeadings, FLAG_SHIFT_HEADINGS, 0, fmt.Sprintf("shift heading levels by the given number. levels are kept between 1 and 6. Example (-shiftHeadings=-1 with %s): ## H2 -> # H2", FLAG_REMOVE_H1))
 	flagset.BoolVar(&config.strictref, FLAG_STRICT_REF, false, fmt.Sprintf("return error when ref target is not found. available only when %s is on", FLAG_CONVERT_LINKS))
+	flagset.BoolVar(&config.imprt, FLAG_IMPORT, false, fmt.Sprintf("convert links in the standard format to obsidian internal links and embeds. the inverse of %s", FLAG_CONVERT_LINKS))
+	flagset.BoolVar(&config.importtag, FLAG_IMPORT_TAGS, false, fmt.Sprintf("move tags in front matter to text. available only when %s is on", FLAG_IMPORT))
 	flagset.StringVar(&config.remapkey, FLAG_REMAP_META_KEYS, "", "remap keys in front matter. format: \"old1:new1,old2:new2\". If a new key is not specified (i.e., empty string), then the field will be removed.")
-	flagset.StringVar(&config.filter, FLAG_FILTER, "", "process only files with specified conditions. Example: -filter=\"(key1||!key2)&&key3\". Each field must be boolean and each key must match /[a-zA-Z-_]+/.")
+	flagset.StringVar(&config.fmrules, FLAG_FRONT_MATTER_RULES, "", fmt.Sprintf("path to a YAML file with an ordered list of front matter rules (default, set, copy, rename, coerce, map, delete). each rule can have a condition in the same format as %s. rules are applied before %s and %s", FLAG_FILTER, FLAG_PUBLISHABLE, FLAG_REMAP_META_KEYS))
+	flagset.StringVar(&config.schema, FLAG_SCHEMA, "", "path to a YAML file with the schema of front matter (required, type, values, pattern), scoped by path or condition. violations are reported as warnings")
+	flagset.BoolVar(&config.strictSchema, FLAG_STRICT_SCHEMA, false, fmt.Sprintf("fail the run if some front matter violates the schema. available only when %s is set", FLAG_SCHEMA))
+	flagset.StringVar(&config.filter, FLAG_FILTER, "", "process only files with specified conditions. Example: -filter=\"(key1||!key2)&&key3\", -filter='tags contains \"public\" && date > 2021-01-01 && path glob \"blog/**\"'. A bare key must be boolean. Comparisons (==, !=, <, >, <=, >=), in, contains, glob and exists(key) are also available.")
+	flagset.StringVar(&config.fmformat, FLAG_FRONT_MATTER_FORMAT, "", fmt.Sprintf("format of front matter in output files. Available formats: %s. YAML (---), TOML (+++) and JSON ({...}) front matter are all read regardless. default: same as input", strings.Join(process.FRONT_MATTER_FORMATS, ", ")))
 	// flagset.StringVar(&config.baseUrl, FLAG_BASE_URL, "", "prefix resolved internal links and format it. Example (-baseUrl=https://example.com/): sample -> https://example.com/sample")
 	flagset.StringVar(&config.remapPathPrefix, FLAG_REMAP_PATH_PREFIX, "", "remap prefixes in paths. Example (-remapPrefix=static/>images/|notes/>posts/): static/sample.png -> images/sample.png, notes/sample.md -> posts/sample.md")
 	flagset.BoolVar(&config.formatLink, FLAG_FORMAT_LINK, false, "trim suffix .md and complete link. Example: #section -> path/to/sample#section")
 	flagset.StringVar(&config.formatAnchor, FLAG_FORMAT_ANCHOR, convert.FORMAT_ANCHOR_HUGO, fmt.Sprintf("anchor formatting style. Available styles: %s", strings.Join(convert.ANCHOR_FORMATTING_STYLES, ", ")))
+	flagset.StringVar(&config.vaults, FLAG_VAULTS, "", "map vault names in obsidian URIs to their root directories or base URLs. Example (-vaults=work>../work|blog>https://example.com/): obsidian://open?vault=work&file=sample -> ../work/path/to/sample.md, obsidian://vault/blog/sample -> https://example.com/sample. local roots are relative to the current directory and links to them are relative to each output file")
 	flagset.BoolVar(&config.obs, FLAG_OBSIDIAN_USAGE, false, "alias of -cptag -title -alias")
 	flagset.BoolVar(&config.std, FLAG_STANDARD_USAGE, false, "alias of -cptag -rmtag -title -alias -link -cmmt -strictref")
 	flagset.BoolVar(&config.ver, FLAG_VERSION, false, "display the version currently installed")
@@ -210,6 +438,10 @@ func setConfig(flagset *flag.FlagSet, config *configuration) {
 	if _, ok := setflags[FLAG_COPY_ALIASES]; ok {
 		config.alias = orgFlag.alias
 	}
+	// -std -linktag のときはタグを削除せずにリンクにする
+	if config.linktag {
+		config.rmtag = false
+	}
 	if _, ok := setflags[FLAG_REMOVE_TAGS]; ok {
 		config.rmtag = orgFlag.rmtag
 	}
@@ -234,12 +466,21 @@ func verifyConfig(config *configuration) error {
 	if config.src == "" {
 		return newMainErr(MAIN_ERR_KIND_SOURCE_NOT_SET)
 	}
-	if config.dst == "" {
-		return newMainErr(MAIN_ERR_KIND_DESTINATION_NOT_SET)
-	}
 	if strings.HasPrefix(config.src, "-") {
 		return newMainErr(MAIN_ERR_KIND_INVALID_SOURCE_FORMAT)
 	}
+	// タスクの一覧を出力するだけなので, 変換のためのフラグは確認しない
+	if config.taskReport != "" {
+		for _, format := range TASK_REPORT_FORMATS {
+			if config.taskReport == format {
+				return nil
+			}
+		}
+		return newMainErr(MAIN_ERR_KIND_INVALID_TASK_REPORT_FORMAT)
+	}
+	if config.dst == "" {
+		return newMainErr(MAIN_ERR_KIND_DESTINATION_NOT_SET)
+	}
 	if strings.HasPrefix(config.dst, "-") {
 		return newMainErr(MAIN_ERR_KIND_INVALID_DESTINATION_FORMAT)
 	}
@@ -266,6 +507,120 @@ func verifyConfig(config *configuration) error {
 	if config.formatLink && !config.link {
 		return newMainErr(MAIN_ERR_KIND_FORMAT_LINK_NEEDS_LINK)
 	}
+	if config.vaults != "" && !config.link {
+		return newMainErr(MAIN_ERR_KIND_VAULTS_NEEDS_LINK)
+	}
+	if config.callout != "" {
+		validCalloutStyle := false
+		for _, style := range convert.CALLOUT_STYLES {
+			if config.callout == style {
+				validCalloutStyle = true
+				break
+			}
+		}
+		if !validCalloutStyle {
+			return newMainErr(MAIN_ERR_KIND_INVALID_CALLOUT_STYLE)
+		}
+	}
+	if config.math != "" {
+		validMathStyle := false
+		for _, style := range convert.MATH_STYLES {
+			if config.math == style {
+				validMathStyle = true
+				break
+			}
+		}
+		if !validMathStyle {
+			return newMainErr(MAIN_ERR_KIND_INVALID_MATH_STYLE)
+		}
+	}
+	if config.fmformat != "" {
+		validFrontMatterFormat := false
+		for _, format := range process.FRONT_MATTER_FORMATS {
+			if config.fmformat == format {
+				validFrontMatterFormat = true
+				break
+			}
+		}
+		if !validFrontMatterFormat {
+			return newMainErr(MAIN_ERR_KIND_INVALID_FRONT_MATTER_FORMAT)
+		}
+	}
+	if config.calloutAlias != "" && config.callout == "" {
+		return newMainErr(MAIN_ERR_KIND_CALLOUT_ALIASES_NEEDS_CALLOUT)
+	}
+	if config.htmlcmmt && config.cmmt {
+		return newMainErr(MAIN_ERR_KIND_HTML_COMMENT_CONFLICTS_WITH_CMMT)
+	}
+	if config.highlightWrapper != "" && !config.highlight {
+		return newMainErr(MAIN_ERR_KIND_HIGHLIGHT_WRAPPER_NEEDS_HIGHLIGHT)
+	}
+	if config.highlightWrapper != "" && !strings.Contains(config.highlightWrapper, "{}") {
+		return newMainErr(MAIN_ERR_KIND_INVALID_HIGHLIGHT_WRAPPER_FORMAT)
+	}
+	if config.fallbackTitle && !(config.title || config.synctlal) {
+		return newMainErr(MAIN_ERR_KIND_FALLBACK_TITLE_NEEDS_TITLE)
+	}
+	if config.titleTemplate != "" && !(config.title || config.synctlal) {
+		return newMainErr(MAIN_ERR_KIND_TITLE_TEMPLATE_NEEDS_TITLE)
+	}
+	if (config.tocMinLevel != 0 || config.tocMaxLevel != 0) && !(config.toc || config.tocAfterH1) {
+		return newMainErr(MAIN_ERR_KIND_TOC_LEVEL_NEEDS_TOC)
+	}
+	if minLevel, maxLevel := tocLevels(config); minLevel < 1 || maxLevel > 6 || minLevel > maxLevel {
+		return newMainErr(MAIN_ERR_KIND_INVALID_TOC_LEVEL)
+	}
+	if config.summaryLength != 0 && !config.summary {
+		return newMainErr(MAIN_ERR_KIND_SUMMARY_LENGTH_NEEDS_SUMMARY)
+	}
+	if config.summaryLength < 0 {
+		return newMainErr(MAIN_ERR_KIND_INVALID_SUMMARY_LENGTH)
+	}
+	if config.dateKey != "" && !config.setdate {
+		return newMainErr(MAIN_ERR_KIND_DATE_KEY_NEEDS_SET_DATE)
+	}
+	if config.lastmodKey != "" && !config.setlastmod {
+		return newMainErr(MAIN_ERR_KIND_LASTMOD_KEY_NEEDS_SET_LASTMOD)
+	}
+	if (config.dateFormat != "" || config.overwriteDate) && !(config.setdate || config.setlastmod) {
+		return newMainErr(MAIN_ERR_KIND_DATE_OPTIONS_NEED_SET_DATES)
+	}
+	if config.strictSchema && config.schema == "" {
+		return newMainErr(MAIN_ERR_KIND_STRICT_SCHEMA_NEEDS_SCHEMA)
+	}
+	if config.linktag && config.rmtag {
+		return newMainErr(MAIN_ERR_KIND_LINK_TAGS_CONFLICTS_WITH_RMTAG)
+	}
+	if config.tagLinkTemplate != "" && !config.linktag {
+		return newMainErr(MAIN_ERR_KIND_TAG_LINK_TEMPLATE_NEEDS_LINK_TAGS)
+	}
+	if config.tagLinkTemplate != "" && !strings.Contains(config.tagLinkTemplate, "{}") {
+		return newMainErr(MAIN_ERR_KIND_INVALID_TAG_LINK_TEMPLATE_FORMAT)
+	}
+	if config.rmInlineField && !config.inlineField {
+		return newMainErr(MAIN_ERR_KIND_REMOVE_INLINE_FIELDS_NEEDS_INLINE_FIELDS)
+	}
+	if config.inlineFieldMerge != "" {
+		if !config.inlineField {
+			return newMainErr(MAIN_ERR_KIND_INLINE_FIELD_MERGE_NEEDS_INLINE_FIELDS)
+		}
+		validInlineFieldMerge := false
+		for _, policy := range INLINE_FIELD_MERGE_POLICIES {
+			if config.inlineFieldMerge == policy {
+				validInlineFieldMerge = true
+				break
+			}
+		}
+		if !validInlineFieldMerge {
+			return newMainErr(MAIN_ERR_KIND_INVALID_INLINE_FIELD_MERGE_POLICY)
+		}
+	}
+	if config.imprt && config.link {
+		return newMainErr(MAIN_ERR_KIND_IMPORT_CONFLICTS_WITH_LINK)
+	}
+	if config.importtag && !config.imprt {
+		return newMainErr(MAIN_ERR_KIND_IMPORT_TAGS_NEEDS_IMPORT)
+	}
 	// check roughly if tgt and dst are the same type (regular file or directory)
 	if filepath.Ext(config.tgt) == ".md" && filepath.Ext(config.dst) != ".md" {
 		return newMainErrf(MAIN_ERR_KIND_TARGET_IS_MARKDOWN_FILE_BUT_DESTINATION_IS_NOT, "%s is a markdown file but %s is not", config.tgt, config.dst)
@@ -275,3 +630,51 @@ func verifyConfig(config *configuration) error {
 	}
 	return nil
 }
+
+// 0 は既定値
+func tocLevels(config *configuration) (minLevel int, maxLevel int) {
+	minLevel, maxLevel = config.tocMinLevel, config.tocMaxLevel
+	if minLevel == 0 {
+		minLevel = convert.DEFAULT_TOC_MIN_LEVEL
+	}
+	if maxLevel == 0 {
+		maxLevel = convert.DEFAULT_TOC_MAX_LEVEL
+	}
+	return minLevel, maxLevel
+}
+
+// -summary でなければ 0
+func summaryLength(config *configuration) int {
+	if !config.summary {
+		return 0
+	}
+	if config.summaryLength == 0 {
+		return convert.DEFAULT_SUMMARY_LEN
+	}
+	return config.summaryLength
+}
+
+// -setdate と -setlastmod がどちらもなければ nil
+func newDateOptions(config *configuration) *dateOptions {
+	if !config.setdate && !config.setlastmod {
+		return nil
+	}
+	o := &dateOptions{
+		date:       config.setdate,
+		lastmod:    config.setlastmod,
+		dateKey:    config.dateKey,
+		lastmodKey: config.lastmodKey,
+		format:     config.dateFormat,
+		overwrite:  config.overwriteDate,
+	}
+	if o.dateKey == "" {
+		o.dateKey = DEFAULT_DATE_KEY
+	}
+	if o.lastmodKey == "" {
+		o.lastmodKey = DEFAULT_LASTMOD_KEY
+	}
+	if o.format == "" {
+		o.format = DEFAULT_DATE_FORMAT
+	}
+	return o
+}
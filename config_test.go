@@ -79,6 +79,30 @@ func TestSetConfig(t *testing.T) {
 				formatAnchor: convert.FORMAT_ANCHOR_HUGO,
 			},
 		},
+		{
+			name: "standard usage with linktag",
+			cmdflags: map[string]string{
+				FLAG_SOURCE:         "src",
+				FLAG_DESTINATION:    "dst",
+				FLAG_LINK_TAGS:      "1",
+				FLAG_STANDARD_USAGE: "1",
+			},
+			wantConfig: configuration{
+				src:          "src",
+				dst:          "dst",
+				rmtag:        false,
+				linktag:      true,
+				cptag:        true,
+				title:        true,
+				alias:        true,
+				link:         true,
+				strictref:    true,
+				cmmt:         true,
+				std:          true,
+				tgt:          "src",
+				formatAnchor: convert.FORMAT_ANCHOR_HUGO,
+			},
+		},
 		{
 			name: "tgt overwrittedn",
 			cmdflags: map[string]string{
@@ -203,6 +227,284 @@ func TestVerifyConfig(t *testing.T) {
 			},
 			wantErr: newMainErr(MAIN_ERR_KIND_INVALID_ANCHOR_FORMATTING_STYLE),
 		},
+		{
+			name: fmt.Sprintf("%s set but not %s", FLAG_VAULTS, FLAG_CONVERT_LINKS),
+			config: configuration{
+				src:          "src",
+				dst:          "dst",
+				vaults:       "work>../work",
+				formatAnchor: convert.FORMAT_ANCHOR_HUGO,
+			},
+			wantErr: newMainErr(MAIN_ERR_KIND_VAULTS_NEEDS_LINK),
+		},
+		{
+			name: fmt.Sprintf("%s and %s set", FLAG_IMPORT, FLAG_CONVERT_LINKS),
+			config: configuration{
+				src:          "src",
+				dst:          "dst",
+				imprt:        true,
+				link:         true,
+				formatAnchor: convert.FORMAT_ANCHOR_HUGO,
+			},
+			wantErr: newMainErr(MAIN_ERR_KIND_IMPORT_CONFLICTS_WITH_LINK),
+		},
+		{
+			name: fmt.Sprintf("%s set but not %s", FLAG_IMPORT_TAGS, FLAG_IMPORT),
+			config: configuration{
+				src:          "src",
+				dst:          "dst",
+				importtag:    true,
+				formatAnchor: convert.FORMAT_ANCHOR_HUGO,
+			},
+			wantErr: newMainErr(MAIN_ERR_KIND_IMPORT_TAGS_NEEDS_IMPORT),
+		},
+		{
+			name: "invalid callout style",
+			config: configuration{
+				src:          "src",
+				dst:          "dst",
+				callout:      "x",
+				formatAnchor: convert.FORMAT_ANCHOR_HUGO,
+			},
+			wantErr: newMainErr(MAIN_ERR_KIND_INVALID_CALLOUT_STYLE),
+		},
+		{
+			name: fmt.Sprintf("%s set but not %s", FLAG_CALLOUT_ALIASES, FLAG_CALLOUT),
+			config: configuration{
+				src:          "src",
+				dst:          "dst",
+				calloutAlias: "caution>warning",
+				formatAnchor: convert.FORMAT_ANCHOR_HUGO,
+			},
+			wantErr: newMainErr(MAIN_ERR_KIND_CALLOUT_ALIASES_NEEDS_CALLOUT),
+		},
+		{
+			name: fmt.Sprintf("%s and %s set", FLAG_HTML_COMMENT, FLAG_REMOVE_COMMENT),
+			config: configuration{
+				src:          "src",
+				dst:          "dst",
+				cmmt:         true,
+				htmlcmmt:     true,
+				formatAnchor: convert.FORMAT_ANCHOR_HUGO,
+			},
+			wantErr: newMainErr(MAIN_ERR_KIND_HTML_COMMENT_CONFLICTS_WITH_CMMT),
+		},
+		{
+			name: fmt.Sprintf("%s without {}", FLAG_HIGHLIGHT_WRAPPER),
+			config: configuration{
+				src:              "src",
+				dst:              "dst",
+				highlight:        true,
+				highlightWrapper: "<mark>",
+				formatAnchor:     convert.FORMAT_ANCHOR_HUGO,
+			},
+			wantErr: newMainErr(MAIN_ERR_KIND_INVALID_HIGHLIGHT_WRAPPER_FORMAT),
+		},
+		{
+			name: fmt.Sprintf("%s set but not %s", FLAG_FALLBACK_TITLE, FLAG_COPY_TITLE),
+			config: configuration{
+				src:           "src",
+				dst:           "dst",
+				fallbackTitle: true,
+				formatAnchor:  convert.FORMAT_ANCHOR_HUGO,
+			},
+			wantErr: newMainErr(MAIN_ERR_KIND_FALLBACK_TITLE_NEEDS_TITLE),
+		},
+		{
+			name: fmt.Sprintf("%s set but not %s", FLAG_TITLE_TEMPLATE, FLAG_COPY_TITLE),
+			config: configuration{
+				src:           "src",
+				dst:           "dst",
+				alias:         true,
+				titleTemplate: "{{.FileName}}",
+				formatAnchor:  convert.FORMAT_ANCHOR_HUGO,
+			},
+			wantErr: newMainErr(MAIN_ERR_KIND_TITLE_TEMPLATE_NEEDS_TITLE),
+		},
+		{
+			name: fmt.Sprintf("%s set but not %s", FLAG_TOC_MAX_LEVEL, FLAG_TOC),
+			config: configuration{
+				src:          "src",
+				dst:          "dst",
+				tocMaxLevel:  3,
+				formatAnchor: convert.FORMAT_ANCHOR_HUGO,
+			},
+			wantErr: newMainErr(MAIN_ERR_KIND_TOC_LEVEL_NEEDS_TOC),
+		},
+		{
+			name: fmt.Sprintf("%s greater than %s", FLAG_TOC_MIN_LEVEL, FLAG_TOC_MAX_LEVEL),
+			config: configuration{
+				src:          "src",
+				dst:          "dst",
+				toc:          true,
+				tocMinLevel:  4,
+				tocMaxLevel:  3,
+				formatAnchor: convert.FORMAT_ANCHOR_HUGO,
+			},
+			wantErr: newMainErr(MAIN_ERR_KIND_INVALID_TOC_LEVEL),
+		},
+		{
+			name: fmt.Sprintf("%s out of range", FLAG_TOC_MAX_LEVEL),
+			config: configuration{
+				src:          "src",
+				dst:          "dst",
+				toc:          true,
+				tocMaxLevel:  7,
+				formatAnchor: convert.FORMAT_ANCHOR_HUGO,
+			},
+			wantErr: newMainErr(MAIN_ERR_KIND_INVALID_TOC_LEVEL),
+		},
+		{
+			name: fmt.Sprintf("%s set but not %s", FLAG_SUMMARY_LENGTH, FLAG_SUMMARY),
+			config: configuration{
+				src:           "src",
+				dst:           "dst",
+				summaryLength: 80,
+				formatAnchor:  convert.FORMAT_ANCHOR_HUGO,
+			},
+			wantErr: newMainErr(MAIN_ERR_KIND_SUMMARY_LENGTH_NEEDS_SUMMARY),
+		},
+		{
+			name: fmt.Sprintf("negative %s", FLAG_SUMMARY_LENGTH),
+			config: configuration{
+				src:           "src",
+				dst:           "dst",
+				summary:       true,
+				summaryLength: -1,
+				formatAnchor:  convert.FORMAT_ANCHOR_HUGO,
+			},
+			wantErr: newMainErr(MAIN_ERR_KIND_INVALID_SUMMARY_LENGTH),
+		},
+		{
+			name: fmt.Sprintf("%s set but not %s", FLAG_DATE_KEY, FLAG_SET_DATE),
+			config: configuration{
+				src:          "src",
+				dst:          "dst",
+				setlastmod:   true,
+				dateKey:      "created",
+				formatAnchor: convert.FORMAT_ANCHOR_HUGO,
+			},
+			wantErr: newMainErr(MAIN_ERR_KIND_DATE_KEY_NEEDS_SET_DATE),
+		},
+		{
+			name: fmt.Sprintf("%s set but not %s", FLAG_LASTMOD_KEY, FLAG_SET_LASTMOD),
+			config: configuration{
+				src:          "src",
+				dst:          "dst",
+				setdate:      true,
+				lastmodKey:   "updated",
+				formatAnchor: convert.FORMAT_ANCHOR_HUGO,
+			},
+			wantErr: newMainErr(MAIN_ERR_KIND_LASTMOD_KEY_NEEDS_SET_LASTMOD),
+		},
+		{
+			name: fmt.Sprintf("%s set but neither %s nor %s", FLAG_DATE_FORMAT, FLAG_SET_DATE, FLAG_SET_LASTMOD),
+			config: configuration{
+				src:          "src",
+				dst:          "dst",
+				dateFormat:   "2006-01-02",
+				formatAnchor: convert.FORMAT_ANCHOR_HUGO,
+			},
+			wantErr: newMainErr(MAIN_ERR_KIND_DATE_OPTIONS_NEED_SET_DATES),
+		},
+		{
+			name: fmt.Sprintf("%s set but not %s", FLAG_STRICT_SCHEMA, FLAG_SCHEMA),
+			config: configuration{
+				src:          "src",
+				dst:          "dst",
+				strictSchema: true,
+				formatAnchor: convert.FORMAT_ANCHOR_HUGO,
+			},
+			wantErr: newMainErr(MAIN_ERR_KIND_STRICT_SCHEMA_NEEDS_SCHEMA),
+		},
+		{
+			name: fmt.Sprintf("invalid %s", FLAG_FRONT_MATTER_FORMAT),
+			config: configuration{
+				src:          "src",
+				dst:          "dst",
+				fmformat:     "xml",
+				formatAnchor: convert.FORMAT_ANCHOR_HUGO,
+			},
+			wantErr: newMainErr(MAIN_ERR_KIND_INVALID_FRONT_MATTER_FORMAT),
+		},
+		{
+			name: fmt.Sprintf("%s and %s set", FLAG_LINK_TAGS, FLAG_REMOVE_TAGS),
+			config: configuration{
+				src:          "src",
+				dst:          "dst",
+				rmtag:        true,
+				linktag:      true,
+				formatAnchor: convert.FORMAT_ANCHOR_HUGO,
+			},
+			wantErr: newMainErr(MAIN_ERR_KIND_LINK_TAGS_CONFLICTS_WITH_RMTAG),
+		},
+		{
+			name: fmt.Sprintf("%s set but not %s", FLAG_TAG_LINK_TEMPLATE, FLAG_LINK_TAGS),
+			config: configuration{
+				src:             "src",
+				dst:             "dst",
+				tagLinkTemplate: "/tags/{}/",
+				formatAnchor:    convert.FORMAT_ANCHOR_HUGO,
+			},
+			wantErr: newMainErr(MAIN_ERR_KIND_TAG_LINK_TEMPLATE_NEEDS_LINK_TAGS),
+		},
+		{
+			name: fmt.Sprintf("%s without {}", FLAG_TAG_LINK_TEMPLATE),
+			config: configuration{
+				src:             "src",
+				dst:             "dst",
+				linktag:         true,
+				tagLinkTemplate: "/tags/",
+				formatAnchor:    convert.FORMAT_ANCHOR_HUGO,
+			},
+			wantErr: newMainErr(MAIN_ERR_KIND_INVALID_TAG_LINK_TEMPLATE_FORMAT),
+		},
+		{
+			name: fmt.Sprintf("%s set but not %s", FLAG_REMOVE_INLINE_FIELDS, FLAG_INLINE_FIELDS),
+			config: configuration{
+				src:           "src",
+				dst:           "dst",
+				rmInlineField: true,
+				formatAnchor:  convert.FORMAT_ANCHOR_HUGO,
+			},
+			wantErr: newMainErr(MAIN_ERR_KIND_REMOVE_INLINE_FIELDS_NEEDS_INLINE_FIELDS),
+		},
+		{
+			name: "invalid inline field merge policy",
+			config: configuration{
+				src:              "src",
+				dst:              "dst",
+				inlineField:      true,
+				inlineFieldMerge: "replace",
+				formatAnchor:     convert.FORMAT_ANCHOR_HUGO,
+			},
+			wantErr: newMainErr(MAIN_ERR_KIND_INVALID_INLINE_FIELD_MERGE_POLICY),
+		},
+		{
+			name: fmt.Sprintf("%s without %s", FLAG_TASK_REPORT, FLAG_DESTINATION),
+			config: configuration{
+				src:        "src",
+				taskReport: TASK_REPORT_JSON,
+			},
+		},
+		{
+			name: "invalid task report format",
+			config: configuration{
+				src:        "src",
+				taskReport: "csv",
+			},
+			wantErr: newMainErr(MAIN_ERR_KIND_INVALID_TASK_REPORT_FORMAT),
+		},
+		{
+			name: "invalid math style",
+			config: configuration{
+				src:          "src",
+				dst:          "dst",
+				math:         "mathjax",
+				formatAnchor: convert.FORMAT_ANCHOR_HUGO,
+			},
+			wantErr: newMainErr(MAIN_ERR_KIND_INVALID_MATH_STYLE),
+		},
 		{
 			name: "valid anchor formatting style",
 			config: configuration{
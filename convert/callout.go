@@ -0,0 +1,203 @@
+package convert
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/qawatake/obsdconv/scan"
+)
+
+const (
+	CALLOUT_STYLE_HTML       = "html"
+	CALLOUT_STYLE_HUGO       = "hugo"
+	CALLOUT_STYLE_MKDOCS     = "mkdocs"
+	CALLOUT_STYLE_DOCUSAURUS = "docusaurus"
+)
+
+var CALLOUT_STYLES = []string{CALLOUT_STYLE_HTML, CALLOUT_STYLE_HUGO, CALLOUT_STYLE_MKDOCS, CALLOUT_STYLE_DOCUSAURUS}
+
+type Callout struct {
+	Type  string
+	Fold  string // +, - または空文字列
+	Title string
+	// 入れ子の callout は変換済み
+	Content string
+}
+
+type CalloutRenderer interface {
+	RenderCallout(callout Callout) (rendered string, err error)
+}
+
+// aliases は callout の type の別名から出力に使う type への対応
+func TransformCalloutFunc(r CalloutRenderer, aliases map[string]string, inner *Converter) TransformerFunc {
+	return func(raw []rune, ptr int) (advance int, tobewritten []rune, err error) {
+		advance, calloutType, fold, title, content := scan.ScanCallout(raw, ptr)
+		if advance == 0 {
+			return 0, nil, nil
+		}
+
+		calloutType = strings.ToLower(calloutType)
+		if t, ok := aliases[calloutType]; ok {
+			calloutType = t
+		}
+		converted, err := inner.Convert([]rune(content))
+		if err != nil {
+			return 0, nil, errors.Wrap(err, "failed to convert nested callouts")
+		}
+		rendered, err := r.RenderCallout(Callout{Type: calloutType, Fold: fold, Title: title, Content: string(converted)})
+		if err != nil {
+			return 0, nil, errors.Wrap(err, "r.RenderCallout failed")
+		}
+		return advance, []rune(rendered), nil
+	}
+}
+
+func NewCalloutConverter(r CalloutRenderer, aliases map[string]string) *Converter {
+	c := new(Converter)
+
+	c.Set(MiddlewareAsIs(scan.ScanEscaped))
+	c.Set(MiddlewareAsIs(scan.ScanCodeBlock))
+	c.Set(MiddlewareAsIs(scan.ScanComment))
+	c.Set(MiddlewareAsIs(scan.ScanMathBlock))
+	c.Set(MiddlewareAsIs(scan.ScanNormalComment))
+	c.Set(TransformCalloutFunc(r, aliases, c))
+	c.Set(MiddlewareAsIs(scan.ScanInlineMath))
+	c.Set(MiddlewareAsIs(scan.ScanInlineCode))
+	c.Set(TransformNone)
+	return c
+}
+
+func NewCalloutRenderer(style string) (CalloutRenderer, error) {
+	switch style {
+	case CALLOUT_STYLE_HTML:
+		return &calloutRendererHTML{}, nil
+	case CALLOUT_STYLE_HUGO:
+		return &calloutRendererHugo{}, nil
+	case CALLOUT_STYLE_MKDOCS:
+		return &calloutRendererMkDocs{}, nil
+	case CALLOUT_STYLE_DOCUSAURUS:
+		return &calloutRendererDocusaurus{}, nil
+	}
+	return nil, newErrTransformf(ERR_KIND_UNEXPECTED, "unexpected callout style: %s", style)
+}
+
+// <div class="callout callout-warning">
+// 折りたたみできる場合は <details>
+type calloutRendererHTML struct{}
+
+func (r *calloutRendererHTML) RenderCallout(callout Callout) (rendered string, err error) {
+	title := callout.Title
+	if title == "" {
+		title = defaultCalloutTitle(callout.Type)
+	}
+	title = html.EscapeString(title)
+	b := new(strings.Builder)
+	if callout.Fold == "" {
+		fmt.Fprintf(b, "<div class=\"callout callout-%s\">\n", callout.Type)
+		fmt.Fprintf(b, "<div class=\"callout-title\">%s</div>\n", title)
+	} else {
+		open := ""
+		if callout.Fold == "+" {
+			open = " open"
+		}
+		fmt.Fprintf(b, "<details class=\"callout callout-%s\"%s>\n", callout.Type, open)
+		fmt.Fprintf(b, "<summary class=\"callout-title\">%s</summary>\n", title)
+	}
+	// markdown として解釈されるように空行で囲む
+	fmt.Fprintf(b, "<div class=\"callout-content\">\n\n%s\n\n</div>\n", strings.Trim(callout.Content, "\n"))
+	if callout.Fold == "" {
+		b.WriteString("</div>\n")
+	} else {
+		b.WriteString("</details>\n")
+	}
+	return b.String(), nil
+}
+
+// {{< callout type="warning" title="Title" >}}
+type calloutRendererHugo struct{}
+
+func (r *calloutRendererHugo) RenderCallout(callout Callout) (rendered string, err error) {
+	b := new(strings.Builder)
+	fmt.Fprintf(b, "{{< callout type=%s", quoteShortcodeParam(callout.Type))
+	if callout.Title != "" {
+		fmt.Fprintf(b, " title=%s", quoteShortcodeParam(callout.Title))
+	}
+	if callout.Fold != "" {
+		fmt.Fprintf(b, " fold=%s", quoteShortcodeParam(callout.Fold))
+	}
+	fmt.Fprintf(b, " >}}\n%s\n{{< /callout >}}\n", strings.Trim(callout.Content, "\n"))
+	return b.String(), nil
+}
+
+// Hugo の shortcode の引数として quote する.
+// Hugo は "..." の中の \" だけを " に戻すので, \ や " を含む場合は `...` を使う.
+func quoteShortcodeParam(s string) string {
+	if !strings.ContainsAny(s, `"\`) {
+		return `"` + s + `"`
+	}
+	if !strings.Contains(s, "`") {
+		return "`" + s + "`"
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+// !!! warning "Title"
+// 折りたたみできる場合は ??? (開いている場合は ???+)
+type calloutRendererMkDocs struct{}
+
+func (r *calloutRendererMkDocs) RenderCallout(callout Callout) (rendered string, err error) {
+	marker := "!!!"
+	if callout.Fold == "-" {
+		marker = "???"
+	} else if callout.Fold == "+" {
+		marker = "???+"
+	}
+	b := new(strings.Builder)
+	fmt.Fprintf(b, "%s %s", marker, callout.Type)
+	if callout.Title != "" {
+		// Python-Markdown は title をエスケープせずにそのまま使う
+		fmt.Fprintf(b, " \"%s\"", callout.Title)
+	}
+	b.WriteString("\n")
+	for _, line := range strings.Split(strings.Trim(callout.Content, "\n"), "\n") {
+		if line == "" {
+			b.WriteString("\n")
+			continue
+		}
+		fmt.Fprintf(b, "    %s\n", line)
+	}
+	return b.String(), nil
+}
+
+// :::warning[Title]
+// 入れ子の場合は外側の : を増やす. 折りたたみは無視する.
+type calloutRendererDocusaurus struct{}
+
+func (r *calloutRendererDocusaurus) RenderCallout(callout Callout) (rendered string, err error) {
+	content := strings.Trim(callout.Content, "\n")
+	colons := 3
+	for _, line := range strings.Split(content, "\n") {
+		n := len(line) - len(strings.TrimLeft(line, ":"))
+		if n >= colons {
+			colons = n + 1
+		}
+	}
+	fence := strings.Repeat(":", colons)
+	b := new(strings.Builder)
+	fmt.Fprintf(b, "%s%s", fence, callout.Type)
+	if callout.Title != "" {
+		fmt.Fprintf(b, "[%s]", callout.Title)
+	}
+	fmt.Fprintf(b, "\n%s\n%s\n", content, fence)
+	return b.String(), nil
+}
+
+// title がない場合は Obsidian と同じく type を表示する
+func defaultCalloutTitle(calloutType string) string {
+	if calloutType == "" {
+		return ""
+	}
+	return strings.ToUpper(calloutType[:1]) + calloutType[1:]
+}
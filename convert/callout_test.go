@@ -0,0 +1,95 @@
+package convert
+
+import "testing"
+
+func TestCalloutConverter(t *testing.T) {
+	cases := []struct {
+		name    string
+		style   string
+		aliases map[string]string
+		raw     []rune
+		want    []rune
+	}{
+		{
+			name:  "html",
+			style: CALLOUT_STYLE_HTML,
+			raw:   []rune("> [!Warning] Be careful\n> content\n\ntext\n"),
+			want:  []rune("<div class=\"callout callout-warning\">\n<div class=\"callout-title\">Be careful</div>\n<div class=\"callout-content\">\n\ncontent\n\n</div>\n</div>\n\ntext\n"),
+		},
+		{
+			name:  "html foldable without title",
+			style: CALLOUT_STYLE_HTML,
+			raw:   []rune("> [!tip]+\n> content\n"),
+			want:  []rune("<details class=\"callout callout-tip\" open>\n<summary class=\"callout-title\">Tip</summary>\n<div class=\"callout-content\">\n\ncontent\n\n</div>\n</details>\n"),
+		},
+		{
+			name:  "hugo",
+			style: CALLOUT_STYLE_HUGO,
+			raw:   []rune("> [!note]- Title\n> content\n"),
+			want:  []rune("{{< callout type=\"note\" title=\"Title\" fold=\"-\" >}}\ncontent\n{{< /callout >}}\n"),
+		},
+		{
+			name:  "html escaped title",
+			style: CALLOUT_STYLE_HTML,
+			raw:   []rune("> [!note] a <b> & \"c\"\n> content\n"),
+			want:  []rune("<div class=\"callout callout-note\">\n<div class=\"callout-title\">a &lt;b&gt; &amp; &#34;c&#34;</div>\n<div class=\"callout-content\">\n\ncontent\n\n</div>\n</div>\n"),
+		},
+		{
+			name:  "hugo title with quotes and backslashes",
+			style: CALLOUT_STYLE_HUGO,
+			raw:   []rune("> [!note] say \"C:\\dir\"\n> content\n"),
+			want:  []rune("{{< callout type=\"note\" title=`say \"C:\\dir\"` >}}\ncontent\n{{< /callout >}}\n"),
+		},
+		{
+			name:  "hugo title with quotes and backquotes",
+			style: CALLOUT_STYLE_HUGO,
+			raw:   []rune("> [!note] `a` \"b\"\n> content\n"),
+			want:  []rune("{{< callout type=\"note\" title=\"`a` \\\"b\\\"\" >}}\ncontent\n{{< /callout >}}\n"),
+		},
+		{
+			name:  "mkdocs title with backslash",
+			style: CALLOUT_STYLE_MKDOCS,
+			raw:   []rune("> [!note] C:\\dir\n> content\n"),
+			want:  []rune("!!! note \"C:\\dir\"\n    content\n"),
+		},
+		{
+			name:  "mkdocs nested",
+			style: CALLOUT_STYLE_MKDOCS,
+			raw:   []rune("> [!note] Outer\n> text\n>\n> > [!tip]-\n> > inner\n"),
+			want:  []rune("!!! note \"Outer\"\n    text\n\n    ??? tip\n        inner\n"),
+		},
+		{
+			name:  "docusaurus nested",
+			style: CALLOUT_STYLE_DOCUSAURUS,
+			raw:   []rune("> [!note] Outer\n> > [!tip]\n> > inner\n"),
+			want:  []rune("::::note[Outer]\n:::tip\ninner\n:::\n::::\n"),
+		},
+		{
+			name:    "aliases",
+			style:   CALLOUT_STYLE_DOCUSAURUS,
+			aliases: map[string]string{"caution": "warning"},
+			raw:     []rune("> [!CAUTION]\n> content\n"),
+			want:    []rune(":::warning\ncontent\n:::\n"),
+		},
+		{
+			name:  "in code block",
+			style: CALLOUT_STYLE_HTML,
+			raw:   []rune("```\n> [!note]\n> content\n```\n"),
+			want:  []rune("```\n> [!note]\n> content\n```\n"),
+		},
+	}
+
+	for _, tt := range cases {
+		r, err := NewCalloutRenderer(tt.style)
+		if err != nil {
+			t.Fatalf("[FATAL | %s] unexpected error ocurred: %v", tt.name, err)
+		}
+		got, err := NewCalloutConverter(r, tt.aliases).Convert(tt.raw)
+		if err != nil {
+			t.Fatalf("[FATAL | %s] unexpected error ocurred: %v", tt.name, err)
+		}
+		if string(got) != string(tt.want) {
+			t.Errorf("[ERROR | %s]\n\t got: %q\n\twant: %q", tt.name, string(got), string(tt.want))
+		}
+	}
+}
@@ -2,6 +2,9 @@ package convert
 
 import (
 	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
 
 	"github.com/pkg/errors"
 
@@ -56,6 +59,40 @@ func MiddlewareAsIs(scanner ScannerFunc) TransformerFunc {
 }
 
 func NewTagRemover() *Converter {
+	remove := func(tag string) []rune {
+		return nil
+	}
+	return newTagConverter(remove, remove)
+}
+
+const DEFAULT_TAG_LINK_TEMPLATE = "/tags/{}/"
+
+// #tag を tmpl の {} をタグで置き換えた URL へのリンク [#tag](/tags/tag/) に変換する.
+// name != nil のときは URL に name(tag) を使う. name(tag) が空ならリンクにしない.
+// リンクの表示名の中のタグはリンクにできないので, そのままにする.
+func NewTagLinker(tmpl string, name func(tag string) string) *Converter {
+	keep := func(tag string) []rune {
+		return []rune("#" + tag)
+	}
+	link := func(tag string) []rune {
+		page := tag
+		if name != nil {
+			page = name(tag)
+		}
+		if page == "" {
+			return keep(tag)
+		}
+		segments := strings.Split(page, "/")
+		for i, s := range segments {
+			segments[i] = url.PathEscape(s)
+		}
+		return []rune(fmt.Sprintf("[#%s](%s)", tag, strings.ReplaceAll(tmpl, "{}", strings.Join(segments, "/"))))
+	}
+	return newTagConverter(keep, link)
+}
+
+// inLinkHead はリンクの表示名の中のタグ, inText はそれ以外のタグをどう書き換えるか
+func newTagConverter(inLinkHead func(tag string) []rune, inText func(tag string) []rune) *Converter {
 	c := new(Converter)
 
 	c.Set(MiddlewareAsIs(scan.ScanEscaped))
@@ -72,7 +109,8 @@ func NewTagRemover() *Converter {
 		advHead, _ := scan.ScanExternalLinkHead(raw, ptr)
 		cur := ptr
 		for cur < ptr+advHead {
-			if adv, _ := scan.ScanTag(raw, cur); adv > 0 {
+			if adv, tag := scan.ScanTag(raw, cur); adv > 0 {
+				tobewritten = append(tobewritten, inLinkHead(tag)...)
 				cur += adv
 				continue
 			}
@@ -92,7 +130,8 @@ func NewTagRemover() *Converter {
 		advHead, _ := scan.ScanExternalLinkHead(raw, ptr)
 		cur := ptr
 		for cur < ptr+advHead {
-			if adv, _ := scan.ScanTag(raw, cur); adv > 0 {
+			if adv, tag := scan.ScanTag(raw, cur); adv > 0 {
+				tobewritten = append(tobewritten, inLinkHead(tag)...)
 				cur += adv
 				continue
 			}
@@ -118,8 +157,11 @@ func NewTagRemover() *Converter {
 	c.Set(MiddlewareAsIs(scan.ScanInlineMath))
 	c.Set(MiddlewareAsIs(scan.ScanInlineCode))
 	c.Set(func(raw []rune, ptr int) (advance int, tobewritten []rune, err error) {
-		advance, _ = scan.ScanTag(raw, ptr)
-		return advance, nil, nil
+		advance, tag := scan.ScanTag(raw, ptr)
+		if advance == 0 {
+			return 0, nil, nil
+		}
+		return advance, inText(tag), nil
 	})
 	c.Set(TransformNone)
 	return c
@@ -226,7 +268,49 @@ func NewTitleFinder(title *string) *Converter {
 	return c
 }
 
-func newLinkConverter(internal, embeds, external TransformerFunc) *Converter {
+type Heading struct {
+	Level int
+	Text  string
+}
+
+func NewHeadingFinder(headings *[]Heading) *Converter {
+	c := new(Converter)
+
+	c.Set(MiddlewareAsIs(scan.ScanEscaped))
+	c.Set(MiddlewareAsIs(scan.ScanCodeBlock))
+	c.Set(MiddlewareAsIs(scan.ScanComment))
+	c.Set(MiddlewareAsIs(scan.ScanMathBlock))
+	c.Set(MiddlewareAsIs(scan.ScanNormalComment))
+	c.Set(MiddlewareAsIs(func(raw []rune, ptr int) (advance int) {
+		advance, _, _, _ = scan.ScanExternalLink(raw, ptr)
+		return advance
+	}))
+	c.Set(MiddlewareAsIs(func(raw []rune, ptr int) (advance int) {
+		advance, _ = scan.ScanInternalLink(raw, ptr)
+		return advance
+	}))
+	c.Set(MiddlewareAsIs(func(raw []rune, ptr int) (advance int) {
+		advance, _ = scan.ScanEmbeds(raw, ptr)
+		return advance
+	}))
+	c.Set(MiddlewareAsIs(scan.ScanInlineMath))
+	c.Set(MiddlewareAsIs(scan.ScanInlineCode))
+	c.Set(func(raw []rune, ptr int) (advance int, tobewritten []rune, err error) {
+		advance, level, headertext := scan.ScanHeader(raw, ptr)
+		if advance > 0 {
+			*headings = append(*headings, Heading{Level: level, Text: headertext})
+		}
+		return advance, raw[ptr : ptr+advance], nil
+	})
+	c.Set(MiddlewareAsIs(func(raw []rune, ptr int) (advance int) {
+		advance, _ = scan.ScanTag(raw, ptr)
+		return advance
+	}))
+	c.Set(TransformNone)
+	return c
+}
+
+func newLinkConverter(internal, embeds, external, vardef TransformerFunc) *Converter {
 	c := new(Converter)
 
 	c.Set(MiddlewareAsIs(scan.ScanEscaped))
@@ -235,6 +319,7 @@ func newLinkConverter(internal, embeds, external TransformerFunc) *Converter {
 	c.Set(MiddlewareAsIs(scan.ScanMathBlock))
 	c.Set(MiddlewareAsIs(scan.ScanNormalComment))
 	c.Set(external)
+	c.Set(vardef)
 	c.Set(internal)
 	c.Set(embeds)
 	c.Set(MiddlewareAsIs(scan.ScanInlineMath))
@@ -247,11 +332,45 @@ func newLinkConverter(internal, embeds, external TransformerFunc) *Converter {
 	return c
 }
 
-func NewLinkConverter(db PathDB, anchorFormattingStyle string) *Converter {
+// vaults は Obsidian URI の vault 名から, その vault のファイルを探す PathDB への対応.
+// nil の場合は vault 名によらず db を使う.
+func NewLinkConverter(db PathDB, anchorFormattingStyle string, vaults map[string]PathDB) *Converter {
 	internal := defaultTransformInternalLinkFunc(db, anchorFormattingStyle)
 	embeds := defaultTransformEmbedsFunc(db)
-	external := defaultTransformExternalLinkFunc(db)
-	return newLinkConverter(internal, embeds, external)
+	external := defaultTransformExternalLinkFunc(db, anchorFormattingStyle, vaults)
+	vardef := defaultTransformExternalLinkVarDefGroupFunc(db, anchorFormattingStyle, vaults)
+	return newLinkConverter(internal, embeds, external, vardef)
+}
+
+// 標準形式のリンクを Obsidian の internal link と embeds に変換する (-link の逆変換).
+// selfPath は変換するファイルの vault からの相対パス.
+func NewLinkImporter(db FileIdDB, vault string, selfPath string, anchorFormattingStyle string) *Converter {
+	importer := newLinkImporterImpl(db, vault, selfPath, anchorFormattingStyle)
+
+	c := new(Converter)
+	c.Set(MiddlewareAsIs(scan.ScanEscaped))
+	c.Set(MiddlewareAsIs(scan.ScanCodeBlock))
+	c.Set(MiddlewareAsIs(scan.ScanComment))
+	c.Set(MiddlewareAsIs(scan.ScanMathBlock))
+	c.Set(MiddlewareAsIs(scan.ScanNormalComment))
+	c.Set(TransformImageLinkToEmbedsFunc(importer))
+	c.Set(TransformExternalLinkToInternalFunc(importer))
+	c.Set(MiddlewareAsIs(func(raw []rune, ptr int) (advance int) {
+		advance, _ = scan.ScanInternalLink(raw, ptr)
+		return advance
+	}))
+	c.Set(MiddlewareAsIs(func(raw []rune, ptr int) (advance int) {
+		advance, _ = scan.ScanEmbeds(raw, ptr)
+		return advance
+	}))
+	c.Set(MiddlewareAsIs(scan.ScanInlineMath))
+	c.Set(MiddlewareAsIs(scan.ScanInlineCode))
+	c.Set(MiddlewareAsIs(func(raw []rune, ptr int) (advance int) {
+		advance, _ = scan.ScanTag(raw, ptr)
+		return advance
+	}))
+	c.Set(TransformNone)
+	return c
 }
 
 func NewCommentEraser() *Converter {
@@ -290,6 +409,45 @@ func NewCommentEraser() *Converter {
 	return c
 }
 
+const DEFAULT_HIGHLIGHT_WRAPPER = "<mark>{}</mark>"
+
+// Obsidian だけの inline 記法を変換する.
+// highlightWrapper != "" のとき, ==highlight== を highlightWrapper の {} を中身で置き換えたものに変換する.
+// htmlComment のとき, %%comment%% を <!--comment--> に変換する.
+func NewInlineSyntaxConverter(highlightWrapper string, htmlComment bool) *Converter {
+	c := new(Converter)
+
+	c.Set(MiddlewareAsIs(scan.ScanEscaped))
+	c.Set(MiddlewareAsIs(scan.ScanCodeBlock))
+	c.Set(func(raw []rune, ptr int) (advance int, tobewritten []rune, err error) {
+		advance = scan.ScanComment(raw, ptr)
+		if advance == 0 {
+			return 0, nil, nil
+		}
+		if !htmlComment {
+			return advance, raw[ptr : ptr+advance], nil
+		}
+		length := len(raw[ptr:]) - len([]rune(strings.TrimLeft(string(raw[ptr:]), "%")))
+		content := strings.TrimSuffix(string(raw[ptr+length:ptr+advance]), strings.Repeat("%", length))
+		// -- は HTML のコメントの中では使えない
+		content = strings.ReplaceAll(content, "--", "- -")
+		return advance, []rune(fmt.Sprintf("<!--%s-->", content)), nil
+	})
+	c.Set(MiddlewareAsIs(scan.ScanMathBlock))
+	c.Set(MiddlewareAsIs(scan.ScanNormalComment))
+	c.Set(func(raw []rune, ptr int) (advance int, tobewritten []rune, err error) {
+		advance, content := scan.ScanHighlight(raw, ptr)
+		if advance == 0 || highlightWrapper == "" {
+			return 0, nil, nil
+		}
+		return advance, []rune(strings.Replace(highlightWrapper, "{}", content, 1)), nil
+	})
+	c.Set(MiddlewareAsIs(scan.ScanInlineMath))
+	c.Set(MiddlewareAsIs(scan.ScanInlineCode))
+	c.Set(TransformNone)
+	return c
+}
+
 func NewLinkPlainConverter() *Converter {
 	c := new(Converter)
 
@@ -350,3 +508,319 @@ func NewH1Remover() *Converter {
 	c.Set(TransformNone)
 	return c
 }
+
+// 見出しのレベルを shift だけずらす. 1 から 6 の範囲に収める.
+// アンカーは見出しの文字列から作られるので, レベルを変えても見出しへのリンクはそのまま使える.
+func NewHeadingShifter(shift int) *Converter {
+	c := new(Converter)
+
+	c.Set(MiddlewareAsIs(scan.ScanEscaped))
+	c.Set(MiddlewareAsIs(scan.ScanCodeBlock))
+	c.Set(MiddlewareAsIs(scan.ScanComment))
+	c.Set(MiddlewareAsIs(scan.ScanMathBlock))
+	c.Set(MiddlewareAsIs(scan.ScanNormalComment))
+	c.Set(MiddlewareAsIs(func(raw []rune, ptr int) (advance int) {
+		advance, _, _, _ = scan.ScanExternalLink(raw, ptr)
+		return advance
+	}))
+	c.Set(MiddlewareAsIs(func(raw []rune, ptr int) (advance int) {
+		advance, _ = scan.ScanInternalLink(raw, ptr)
+		return advance
+	}))
+	c.Set(MiddlewareAsIs(func(raw []rune, ptr int) (advance int) {
+		advance, _ = scan.ScanEmbeds(raw, ptr)
+		return advance
+	}))
+	c.Set(MiddlewareAsIs(scan.ScanInlineMath))
+	c.Set(MiddlewareAsIs(scan.ScanInlineCode))
+	c.Set(func(raw []rune, ptr int) (advance int, tobewritten []rune, err error) {
+		advance, level, _ := scan.ScanHeader(raw, ptr)
+		// ####### 以上は見出しではない
+		if advance == 0 || level > 6 {
+			return advance, raw[ptr : ptr+advance], nil
+		}
+		newLevel := level + shift
+		if newLevel < 1 {
+			newLevel = 1
+		} else if newLevel > 6 {
+			newLevel = 6
+		}
+		tobewritten = []rune(strings.Repeat("#", newLevel))
+		tobewritten = append(tobewritten, raw[ptr+level:ptr+advance]...)
+		return advance, tobewritten, nil
+	})
+	c.Set(MiddlewareAsIs(func(raw []rune, ptr int) (advance int) {
+		advance, _ = scan.ScanTag(raw, ptr)
+		return advance
+	}))
+	c.Set(TransformNone)
+	return c
+}
+
+type ReferenceLabel struct {
+	Label string
+	Line  int
+}
+
+// 参照形式のリンク [text][label] で使われているラベルと, [label]: ref で定義されているラベルを集める.
+// ラベルは大文字小文字を区別しないので, 小文字にそろえる.
+func NewReferenceLabelFinder(defined map[string]struct{}, used *[]ReferenceLabel) *Converter {
+	c := new(Converter)
+
+	c.Set(MiddlewareAsIs(scan.ScanEscaped))
+	c.Set(MiddlewareAsIs(scan.ScanCodeBlock))
+	c.Set(MiddlewareAsIs(scan.ScanComment))
+	c.Set(MiddlewareAsIs(scan.ScanMathBlock))
+	c.Set(MiddlewareAsIs(scan.ScanNormalComment))
+	c.Set(MiddlewareAsIs(func(raw []rune, ptr int) (advance int) {
+		advance, _, _, _ = scan.ScanExternalLink(raw, ptr)
+		return advance
+	}))
+	c.Set(MiddlewareAsIs(func(raw []rune, ptr int) (advance int) {
+		advance = scan.ScanExternalLinkVar(raw, ptr)
+		if advance == 0 {
+			return 0
+		}
+		advHead, displayName := scan.ScanExternalLinkHead(raw, ptr)
+		_, label := scan.ScanExternalLinkHead(raw, ptr+advHead)
+		// [label][] の場合
+		if label == "" {
+			label = displayName
+		}
+		*used = append(*used, ReferenceLabel{Label: normalizeReferenceLabel(label), Line: currentLine(raw, ptr)})
+		return advance
+	}))
+	c.Set(MiddlewareAsIs(func(raw []rune, ptr int) (advance int) {
+		advance, ptrs := scan.ScanExternalLinkVarDefGroup(raw, ptr)
+		for _, head := range ptrs {
+			_, label, _ := scan.ScanExternalLinkVarDef(raw, head)
+			defined[normalizeReferenceLabel(label)] = struct{}{}
+		}
+		return advance
+	}))
+	c.Set(MiddlewareAsIs(func(raw []rune, ptr int) (advance int) {
+		advance, _ = scan.ScanInternalLink(raw, ptr)
+		return advance
+	}))
+	c.Set(MiddlewareAsIs(func(raw []rune, ptr int) (advance int) {
+		advance, _ = scan.ScanEmbeds(raw, ptr)
+		return advance
+	}))
+	c.Set(MiddlewareAsIs(scan.ScanInlineMath))
+	c.Set(MiddlewareAsIs(scan.ScanInlineCode))
+	c.Set(MiddlewareAsIs(func(raw []rune, ptr int) (advance int) {
+		advance, _ = scan.ScanTag(raw, ptr)
+		return advance
+	}))
+	c.Set(TransformNone)
+	return c
+}
+
+// 使われているのに定義されていないラベルを, 行番号つきの ErrConvert として返す
+func FindUndefinedReferenceLabels(raw []rune) (undefined []ErrConvert, err error) {
+	defined := make(map[string]struct{})
+	used := make([]ReferenceLabel, 0)
+	if _, err := NewReferenceLabelFinder(defined, &used).Convert(raw); err != nil {
+		return nil, errors.Wrap(err, "ReferenceLabelFinder failed")
+	}
+	for _, l := range used {
+		if _, ok := defined[l.Label]; ok {
+			continue
+		}
+		e := newErrConvert(newErrTransformf(ERR_KIND_UNDEFINED_REFERENCE_LABEL, "reference label [%s] is used but not defined", l.Label))
+		e.SetLine(l.Line)
+		undefined = append(undefined, e)
+	}
+	return undefined, nil
+}
+
+func normalizeReferenceLabel(label string) string {
+	return strings.ToLower(strings.Join(strings.Fields(label), " "))
+}
+
+// 本文中の脚注のラベル [^label] を集める
+func NewFootnoteLabelFinder(labels map[string]struct{}) *Converter {
+	c := new(Converter)
+
+	c.Set(MiddlewareAsIs(scan.ScanEscaped))
+	c.Set(MiddlewareAsIs(scan.ScanCodeBlock))
+	c.Set(MiddlewareAsIs(scan.ScanComment))
+	c.Set(MiddlewareAsIs(scan.ScanMathBlock))
+	c.Set(MiddlewareAsIs(scan.ScanNormalComment))
+	c.Set(MiddlewareAsIs(func(raw []rune, ptr int) (advance int) {
+		advance, label := scan.ScanFootnoteRef(raw, ptr)
+		if advance > 0 {
+			labels[label] = struct{}{}
+		}
+		return advance
+	}))
+	c.Set(MiddlewareAsIs(scan.ScanInlineMath))
+	c.Set(MiddlewareAsIs(scan.ScanInlineCode))
+	c.Set(TransformNone)
+	return c
+}
+
+type Footnote struct {
+	Label   string
+	Content string
+}
+
+// ^[inline footnote] を [^n] に置き換えて, 中身を footnotes に追加する.
+// n は labels にないものを 1 から順に使う.
+func NewInlineFootnoteConverter(labels map[string]struct{}, footnotes *[]Footnote) *Converter {
+	c := new(Converter)
+
+	c.Set(MiddlewareAsIs(scan.ScanEscaped))
+	c.Set(MiddlewareAsIs(scan.ScanCodeBlock))
+	c.Set(MiddlewareAsIs(scan.ScanComment))
+	c.Set(MiddlewareAsIs(scan.ScanMathBlock))
+	c.Set(MiddlewareAsIs(scan.ScanNormalComment))
+	n := 0
+	c.Set(func(raw []rune, ptr int) (advance int, tobewritten []rune, err error) {
+		advance, content := scan.ScanInlineFootnote(raw, ptr)
+		if advance == 0 {
+			return 0, nil, nil
+		}
+		label := ""
+		for {
+			n++
+			label = strconv.Itoa(n)
+			if _, ok := labels[label]; !ok {
+				break
+			}
+		}
+		*footnotes = append(*footnotes, Footnote{Label: label, Content: content})
+		return advance, []rune(fmt.Sprintf("[^%s]", label)), nil
+	})
+	c.Set(MiddlewareAsIs(func(raw []rune, ptr int) (advance int) {
+		advance, _, _, _ = scan.ScanExternalLink(raw, ptr)
+		return advance
+	}))
+	c.Set(MiddlewareAsIs(func(raw []rune, ptr int) (advance int) {
+		advance, _ = scan.ScanInternalLink(raw, ptr)
+		return advance
+	}))
+	c.Set(MiddlewareAsIs(func(raw []rune, ptr int) (advance int) {
+		advance, _ = scan.ScanEmbeds(raw, ptr)
+		return advance
+	}))
+	c.Set(MiddlewareAsIs(scan.ScanInlineMath))
+	c.Set(MiddlewareAsIs(scan.ScanInlineCode))
+	c.Set(MiddlewareAsIs(func(raw []rune, ptr int) (advance int) {
+		advance, _ = scan.ScanTag(raw, ptr)
+		return advance
+	}))
+	c.Set(TransformNone)
+	return c
+}
+
+// ^[inline footnote] を番号つきの脚注 [^n] に変換して, 定義を本文の最後に追加する.
+// 番号は既存の脚注のラベルと重ならないようにする.
+func ConvertInlineFootnotes(raw []rune) (output []rune, err error) {
+	labels := make(map[string]struct{})
+	if _, err := NewFootnoteLabelFinder(labels).Convert(raw); err != nil {
+		return nil, errors.Wrap(err, "FootnoteLabelFinder failed")
+	}
+	footnotes := make([]Footnote, 0)
+	output, err = NewInlineFootnoteConverter(labels, &footnotes).Convert(raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "InlineFootnoteConverter failed")
+	}
+	if len(footnotes) == 0 {
+		return output, nil
+	}
+
+	if len(output) > 0 && output[len(output)-1] != '\n' {
+		output = append(output, '\n')
+	}
+	output = append(output, '\n')
+	for _, f := range footnotes {
+		// 2 行目以降はインデントして定義の続きにする
+		content := strings.ReplaceAll(f.Content, "\n", "\n    ")
+		output = append(output, []rune(fmt.Sprintf("[^%s]: %s\n", f.Label, content))...)
+	}
+	return output, nil
+}
+
+type InlineField struct {
+	Key   string
+	Value string
+}
+
+func countSpaces(raw []rune, ptr int) int {
+	n := 0
+	for ptr+n < len(raw) && (raw[ptr+n] == ' ' || raw[ptr+n] == '\t') {
+		n++
+	}
+	return n
+}
+
+// Dataview の inline field (key:: value, [key:: value], (key:: value)) を fields に集める.
+// remove のとき, inline field を本文から削除する. 行頭の形式の場合は行ごと削除する.
+func NewInlineFieldConverter(fields *[]InlineField, remove bool) *Converter {
+	c := new(Converter)
+
+	c.Set(MiddlewareAsIs(scan.ScanEscaped))
+	c.Set(MiddlewareAsIs(scan.ScanCodeBlock))
+	c.Set(MiddlewareAsIs(scan.ScanComment))
+	c.Set(MiddlewareAsIs(scan.ScanMathBlock))
+	c.Set(MiddlewareAsIs(scan.ScanNormalComment))
+	c.Set(func(raw []rune, ptr int) (advance int, tobewritten []rune, err error) {
+		advance, key, value := scan.ScanInlineFieldLine(raw, ptr)
+		if advance == 0 {
+			return 0, nil, nil
+		}
+		*fields = append(*fields, InlineField{Key: key, Value: value})
+		if !remove {
+			return advance, raw[ptr : ptr+advance], nil
+		}
+		if ptr+advance < len(raw) && raw[ptr+advance] == '\n' {
+			advance++
+		}
+		return advance, nil, nil
+	})
+	c.Set(func(raw []rune, ptr int) (advance int, tobewritten []rune, err error) {
+		advance, key, value := scan.ScanInlineField(raw, ptr)
+		if advance == 0 {
+			return 0, nil, nil
+		}
+		*fields = append(*fields, InlineField{Key: key, Value: value})
+		if !remove {
+			return advance, raw[ptr : ptr+advance], nil
+		}
+		// 行頭なら後ろの空白も削除する
+		if ptr == 0 || raw[ptr-1] == '\n' {
+			advance += countSpaces(raw, ptr+advance)
+		}
+		return advance, nil, nil
+	})
+	// 削除するときは前の空白もいっしょに削除して, 空白が 2 つ続かないようにする
+	c.Set(func(raw []rune, ptr int) (advance int, tobewritten []rune, err error) {
+		spaces := countSpaces(raw, ptr)
+		if !remove || spaces == 0 {
+			return 0, nil, nil
+		}
+		advance, key, value := scan.ScanInlineField(raw, ptr+spaces)
+		if advance == 0 {
+			return 0, nil, nil
+		}
+		*fields = append(*fields, InlineField{Key: key, Value: value})
+		return spaces + advance, nil, nil
+	})
+	c.Set(MiddlewareAsIs(func(raw []rune, ptr int) (advance int) {
+		advance, _, _, _ = scan.ScanExternalLink(raw, ptr)
+		return advance
+	}))
+	c.Set(MiddlewareAsIs(func(raw []rune, ptr int) (advance int) {
+		advance, _ = scan.ScanInternalLink(raw, ptr)
+		return advance
+	}))
+	c.Set(MiddlewareAsIs(func(raw []rune, ptr int) (advance int) {
+		advance, _ = scan.ScanEmbeds(raw, ptr)
+		return advance
+	}))
+	c.Set(MiddlewareAsIs(scan.ScanInlineMath))
+	c.Set(MiddlewareAsIs(scan.ScanInlineCode))
+	c.Set(TransformNone)
+	return c
+}
@@ -1,7 +1,9 @@
 package convert
 
 import (
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -56,6 +58,83 @@ func TestTagRemover(t *testing.T) {
 	}
 }
 
+func TestTagLinker(t *testing.T) {
+	cases := []struct {
+		name     string
+		tmpl     string
+		pageName func(tag string) string
+		raw      []rune
+		want     []rune
+	}{
+		{
+			name: "simple",
+			tmpl: "/tags/{}/",
+			raw:  []rune("# H1 #todo #obsidian\n## H2\n"),
+			want: []rune("# H1 [#todo](/tags/todo/) [#obsidian](/tags/obsidian/)\n## H2\n"),
+		},
+		{
+			name: "escaped",
+			tmpl: "/tags/{}/",
+			raw:  []rune("#todo \\#obsidian"),
+			want: []rune("[#todo](/tags/todo/) \\#obsidian"),
+		},
+		{
+			name: "nested tag",
+			tmpl: "https://example.com/tags/{}",
+			raw:  []rune("#area/infra"),
+			want: []rune("[#area/infra](https://example.com/tags/area/infra)"),
+		},
+		{
+			name: "non-ascii tag is escaped in url",
+			tmpl: "/tags/{}/",
+			raw:  []rune("#日本語"),
+			want: []rune("[#日本語](/tags/%E6%97%A5%E6%9C%AC%E8%AA%9E/)"),
+		},
+		{
+			name: "tag in display name in external link",
+			tmpl: "/tags/{}/",
+			raw:  []rune("[google #todo](https://google.com) #google"),
+			want: []rune("[google #todo](https://google.com) [#google](/tags/google/)"),
+		},
+		{
+			name: "tag in display name in var external link",
+			tmpl: "/tags/{}/",
+			raw:  []rune("[google #todo][google #not_tag]"),
+			want: []rune("[google #todo][google #not_tag]"),
+		},
+		{
+			name: "tag in inline code",
+			tmpl: "/tags/{}/",
+			raw:  []rune("`#todo`"),
+			want: []rune("`#todo`"),
+		},
+		{
+			name:     "renamed tag",
+			tmpl:     "/tags/{}/",
+			pageName: strings.ToLower,
+			raw:      []rune("#Area/K8s"),
+			want:     []rune("[#Area/K8s](/tags/area/k8s/)"),
+		},
+		{
+			name:     "tag without page",
+			tmpl:     "/tags/{}/",
+			pageName: func(tag string) string { return "" },
+			raw:      []rune("#cat/tech"),
+			want:     []rune("#cat/tech"),
+		},
+	}
+
+	for _, tt := range cases {
+		got, err := NewTagLinker(tt.tmpl, tt.pageName).Convert(tt.raw)
+		if err != nil {
+			t.Fatalf("[FATAL] | %v] unexpected error ocurred: %v", tt.name, err)
+		}
+		if string(got) != string(tt.want) {
+			t.Errorf("[ERROR | %v]\n\t got: %q\n\twant: %q", tt.name, string(got), string(tt.want))
+		}
+	}
+}
+
 func TestTagFinder(t *testing.T) {
 	cases := []struct {
 		name     string
@@ -290,6 +369,13 @@ func TestLinkConverter(t *testing.T) {
 			raw:                   []rune("![[ ]]"),
 			want:                  []rune(""),
 		},
+		{
+			name:                  "reference-style link definitions",
+			vault:                 "vardef",
+			anchorFormattingStyle: FORMAT_ANCHOR_HUGO,
+			raw:                   []rune("[see this][label] and [google][]\n\n[label]: test#section\n[google]:  https://google.com\n[missing]: missing  \n"),
+			want:                  []rune("[see this][label] and [google][]\n\n[label]: test.md#section\n[google]:  https://google.com\n[missing]: missing  \n"),
+		},
 		{
 			name:                  "fragments with non-ascii characters (hugo)",
 			vault:                 "internal",
@@ -308,7 +394,7 @@ func TestLinkConverter(t *testing.T) {
 
 	for _, tt := range cases {
 		db := NewPathDB(filepath.Join(testLinkConverterVaultDir, tt.vault))
-		c := NewLinkConverter(db, tt.anchorFormattingStyle)
+		c := NewLinkConverter(db, tt.anchorFormattingStyle, nil)
 		c.Convert(tt.raw)
 		got, err := c.Convert(tt.raw)
 		if err != nil {
@@ -320,6 +406,93 @@ func TestLinkConverter(t *testing.T) {
 	}
 }
 
+func TestFindUndefinedReferenceLabels(t *testing.T) {
+	cases := []struct {
+		name       string
+		raw        []rune
+		wantLabels []string
+		wantLines  []int
+	}{
+		{
+			name:       "all defined",
+			raw:        []rune("[see this][Label] and [google][]\n\n[label]: test\n[google]: https://google.com\n"),
+			wantLabels: nil,
+			wantLines:  nil,
+		},
+		{
+			name:       "undefined",
+			raw:        []rune("[see this][label]\n\n[other][missing]\n\n[label]: test\n"),
+			wantLabels: []string{"missing"},
+			wantLines:  []int{3},
+		},
+		{
+			name:       "in code",
+			raw:        []rune("`[see this][label]`\n```\n[other][missing]\n```\n"),
+			wantLabels: nil,
+			wantLines:  nil,
+		},
+	}
+
+	for _, tt := range cases {
+		undefined, err := FindUndefinedReferenceLabels(tt.raw)
+		if err != nil {
+			t.Fatalf("[FATAL | %s] unexpected error ocurred: %v", tt.name, err)
+		}
+		if len(undefined) != len(tt.wantLabels) {
+			t.Errorf("[ERROR | %s] got %d undefined labels, want %d: %v", tt.name, len(undefined), len(tt.wantLabels), undefined)
+			continue
+		}
+		for i, e := range undefined {
+			ee, ok := e.Source().(ErrTransform)
+			if !ok || ee.Kind() != ERR_KIND_UNDEFINED_REFERENCE_LABEL {
+				t.Errorf("[ERROR | %s] unexpected error: %v", tt.name, e)
+			}
+			if !strings.Contains(e.Error(), "["+tt.wantLabels[i]+"]") || e.Line() != tt.wantLines[i] {
+				t.Errorf("[ERROR | %s]\n\t got: %v (line %d)\n\twant: %s (line %d)", tt.name, e, e.Line(), tt.wantLabels[i], tt.wantLines[i])
+			}
+		}
+	}
+}
+
+func TestConvertInlineFootnotes(t *testing.T) {
+	cases := []struct {
+		name string
+		raw  []rune
+		want []rune
+	}{
+		{
+			name: "simple",
+			raw:  []rune("text^[first] and text^[second [[note]]]\n"),
+			want: []rune("text[^1] and text[^2]\n\n[^1]: first\n[^2]: second [[note]]\n"),
+		},
+		{
+			name: "existing footnotes",
+			raw:  []rune("text[^1] and text^[inline]\n\n[^1]: existing\n[^2]: unused"),
+			want: []rune("text[^1] and text[^3]\n\n[^1]: existing\n[^2]: unused\n\n[^3]: inline\n"),
+		},
+		{
+			name: "skip code, math and comments",
+			raw:  []rune("`^[code]` $^[math]$ %%^[comment]%%\n```\n^[block]\n```\n"),
+			want: []rune("`^[code]` $^[math]$ %%^[comment]%%\n```\n^[block]\n```\n"),
+		},
+		{
+			name: "multiline",
+			raw:  []rune("text^[first\nsecond]"),
+			want: []rune("text[^1]\n\n[^1]: first\n    second\n"),
+		},
+	}
+
+	for _, tt := range cases {
+		got, err := ConvertInlineFootnotes(tt.raw)
+		if err != nil {
+			t.Fatalf("[FATAL | %s] unexpected error ocurred: %v", tt.name, err)
+		}
+		if string(got) != string(tt.want) {
+			t.Errorf("[ERROR | %s]\n\t got: %q\n\twant: %q", tt.name, string(got), string(tt.want))
+		}
+	}
+}
+
 func TestCommentEraser(t *testing.T) {
 	cases := []struct {
 		name string
@@ -375,3 +548,323 @@ func TestCommentEraser(t *testing.T) {
 		}
 	}
 }
+
+func TestHeadingShifter(t *testing.T) {
+	cases := []struct {
+		name  string
+		shift int
+		raw   []rune
+		want  []rune
+	}{
+		{
+			name:  "demote",
+			shift: 1,
+			raw:   []rune("# H1\ntext\n## H2\n"),
+			want:  []rune("## H1\ntext\n### H2\n"),
+		},
+		{
+			name:  "promote",
+			shift: -1,
+			raw:   []rune("## H2\n### H3"),
+			want:  []rune("# H2\n## H3"),
+		},
+		{
+			name:  "clamped",
+			shift: -2,
+			raw:   []rune("## H2\n##### H5\n"),
+			want:  []rune("# H2\n### H5\n"),
+		},
+		{
+			name:  "clamped at 6",
+			shift: 3,
+			raw:   []rune("#### H4 ####\n"),
+			want:  []rune("###### H4 ####\n"),
+		},
+		{
+			name:  "not heading",
+			shift: 1,
+			raw:   []rune("#tag\n####### H7\n\\# escaped\n"),
+			want:  []rune("#tag\n####### H7\n\\# escaped\n"),
+		},
+		{
+			name:  "code block, math and comment",
+			shift: 1,
+			raw:   []rune("```\n# code\n```\n$$\n# math\n$$\n%%\n# comment\n%%\n<!--\n# html\n-->\n"),
+			want:  []rune("```\n# code\n```\n$$\n# math\n$$\n%%\n# comment\n%%\n<!--\n# html\n-->\n"),
+		},
+	}
+
+	for _, tt := range cases {
+		got, err := NewHeadingShifter(tt.shift).Convert(tt.raw)
+		if err != nil {
+			t.Fatalf("[FATAL] | %v] unexpected error ocurred: %v", tt.name, err)
+		}
+		if string(got) != string(tt.want) {
+			t.Errorf("[ERROR | %v]\n\tgot: %q\n\twant: %q", tt.name, string(got), string(tt.want))
+		}
+	}
+}
+
+func TestInlineSyntaxConverter(t *testing.T) {
+	cases := []struct {
+		name             string
+		highlightWrapper string
+		htmlComment      bool
+		raw              []rune
+		want             []rune
+	}{
+		{
+			name:             "highlight",
+			highlightWrapper: DEFAULT_HIGHLIGHT_WRAPPER,
+			raw:              []rune("This is ==important== and \\==not== highlighted"),
+			want:             []rune("This is <mark>important</mark> and \\==not== highlighted"),
+		},
+		{
+			name:             "custom wrapper",
+			highlightWrapper: "{{< hl >}}{}{{< /hl >}}",
+			raw:              []rune("==a=="),
+			want:             []rune("{{< hl >}}a{{< /hl >}}"),
+		},
+		{
+			name:             "skip code and math",
+			highlightWrapper: DEFAULT_HIGHLIGHT_WRAPPER,
+			htmlComment:      true,
+			raw:              []rune("`==a==` $a==b$\n```\n==a== %%b%%\n```\n$$\na==b==c\n$$\n\\==a=="),
+			want:             []rune("`==a==` $a==b$\n```\n==a== %%b%%\n```\n$$\na==b==c\n$$\n\\==a=="),
+		},
+		{
+			name:        "html comment",
+			htmlComment: true,
+			raw:         []rune("text %%inline -- comment%% ==kept==\n%%%\nblock\n%%%\n"),
+			want:        []rune("text <!--inline - - comment--> ==kept==\n<!--\nblock\n-->\n"),
+		},
+		{
+			name: "all off",
+			raw:  []rune("==a== %%b%%"),
+			want: []rune("==a== %%b%%"),
+		},
+	}
+
+	for _, tt := range cases {
+		got, err := NewInlineSyntaxConverter(tt.highlightWrapper, tt.htmlComment).Convert(tt.raw)
+		if err != nil {
+			t.Fatalf("[FATAL | %s] unexpected error ocurred: %v", tt.name, err)
+		}
+		if string(got) != string(tt.want) {
+			t.Errorf("[ERROR | %s]\n\t got: %q\n\twant: %q", tt.name, string(got), string(tt.want))
+		}
+	}
+}
+
+func TestInlineFieldConverter(t *testing.T) {
+	cases := []struct {
+		name       string
+		remove     bool
+		raw        []rune
+		want       []rune
+		wantFields []InlineField
+	}{
+		{
+			name: "keep",
+			raw:  []rune("status:: draft\n- [ ] task [due:: 2026-11-01] (priority:: 1)\n"),
+			want: []rune("status:: draft\n- [ ] task [due:: 2026-11-01] (priority:: 1)\n"),
+			wantFields: []InlineField{
+				{Key: "status", Value: "draft"},
+				{Key: "due", Value: "2026-11-01"},
+				{Key: "priority", Value: "1"},
+			},
+		},
+		{
+			name:   "remove",
+			remove: true,
+			raw:    []rune("# Title\nstatus:: draft\ntext [due:: 2026-11-01]\n"),
+			want:   []rune("# Title\ntext\n"),
+			wantFields: []InlineField{
+				{Key: "status", Value: "draft"},
+				{Key: "due", Value: "2026-11-01"},
+			},
+		},
+		{
+			name:   "remove in a sentence",
+			remove: true,
+			raw:    []rune("Read by [due:: 2026-11-01] and rate it (rating:: 4.5).\n[a:: 1] first\n"),
+			want:   []rune("Read by and rate it.\nfirst\n"),
+			wantFields: []InlineField{
+				{Key: "due", Value: "2026-11-01"},
+				{Key: "rating", Value: "4.5"},
+				{Key: "a", Value: "1"},
+			},
+		},
+		{
+			name:   "code, math and comments",
+			remove: true,
+			raw:    []rune("```\na:: b\n```\n`[c:: d]` $[e:: f]$ %%g:: h%%\n"),
+			want:   []rune("```\na:: b\n```\n`[c:: d]` $[e:: f]$ %%g:: h%%\n"),
+		},
+	}
+
+	for _, tt := range cases {
+		fields := make([]InlineField, 0)
+		got, err := NewInlineFieldConverter(&fields, tt.remove).Convert(tt.raw)
+		if err != nil {
+			t.Fatalf("[FATAL | %s] unexpected error ocurred: %v", tt.name, err)
+		}
+		if string(got) != string(tt.want) {
+			t.Errorf("[ERROR | %s]\n\t got: %q\n\twant: %q", tt.name, string(got), string(tt.want))
+		}
+		if len(fields) != len(tt.wantFields) {
+			t.Errorf("[ERROR | %s] got: %v, want: %v", tt.name, fields, tt.wantFields)
+			continue
+		}
+		for i, f := range fields {
+			if f != tt.wantFields[i] {
+				t.Errorf("[ERROR | %s] got: %v, want: %v", tt.name, fields, tt.wantFields)
+				break
+			}
+		}
+	}
+}
+
+func TestLinkImporter(t *testing.T) {
+	testLinkImporterVaultDir := filepath.Join("testdata", "linkimporter")
+	cases := []struct {
+		name                  string
+		selfPath              string
+		anchorFormattingStyle string
+		raw                   []rune
+		want                  []rune
+	}{
+		{
+			name:                  "relative link",
+			selfPath:              "notes/self.md",
+			anchorFormattingStyle: FORMAT_ANCHOR_HUGO,
+			raw:                   []rune("[text](target.md)"),
+			want:                  []rune("[[target|text]]"),
+		},
+		{
+			name:                  "same display name",
+			selfPath:              "notes/self.md",
+			anchorFormattingStyle: FORMAT_ANCHOR_HUGO,
+			raw:                   []rune("[target](target.md)"),
+			want:                  []rune("[[target]]"),
+		},
+		{
+			name:                  "suffix .md omitted",
+			selfPath:              "notes/self.md",
+			anchorFormattingStyle: FORMAT_ANCHOR_HUGO,
+			raw:                   []rune("[text](target)"),
+			want:                  []rune("[[target|text]]"),
+		},
+		{
+			name:                  "anchor (hugo)",
+			selfPath:              "notes/self.md",
+			anchorFormattingStyle: FORMAT_ANCHOR_HUGO,
+			raw:                   []rune("[text](target.md#my-heading)"),
+			want:                  []rune("[[target#My Heading|text]]"),
+		},
+		{
+			name:                  "anchor (markdown it)",
+			selfPath:              "notes/self.md",
+			anchorFormattingStyle: FORMAT_ANCHOR_MARKDOWN_IT,
+			raw:                   []rune("[text](target.md#my-heading)"),
+			want:                  []rune("[[target#My Heading|text]]"),
+		},
+		{
+			name:                  "heading in code block is ignored",
+			selfPath:              "notes/self.md",
+			anchorFormattingStyle: FORMAT_ANCHOR_HUGO,
+			raw:                   []rune("[text](target.md#not-heading)"),
+			want:                  []rune("[[target#not-heading|text]]"),
+		},
+		{
+			name:                  "only anchor",
+			selfPath:              "notes/self.md",
+			anchorFormattingStyle: FORMAT_ANCHOR_HUGO,
+			raw:                   []rune("[Section One](#section-one)"),
+			want:                  []rune("[[#Section One]]"),
+		},
+		{
+			name:                  "ambiguous file name",
+			selfPath:              "notes/self.md",
+			anchorFormattingStyle: FORMAT_ANCHOR_HUGO,
+			raw:                   []rune("[dup](sub/dup.md)"),
+			want:                  []rune("[[sub/dup|dup]]"),
+		},
+		{
+			name:                  "parent directory",
+			selfPath:              "notes/self.md",
+			anchorFormattingStyle: FORMAT_ANCHOR_HUGO,
+			raw:                   []rune("[other](../other/dup.md)"),
+			want:                  []rune("[[other/dup|other]]"),
+		},
+		{
+			name:                  "image",
+			selfPath:              "notes/self.md",
+			anchorFormattingStyle: FORMAT_ANCHOR_HUGO,
+			raw:                   []rune("![image.png](../static/image.png)"),
+			want:                  []rune("![[image.png]]"),
+		},
+		{
+			name:                  "image with alt",
+			selfPath:              "notes/self.md",
+			anchorFormattingStyle: FORMAT_ANCHOR_HUGO,
+			raw:                   []rune("![screenshot](/static/image.png)"),
+			want:                  []rune("![[image.png|screenshot]]"),
+		},
+		{
+			name:                  "url",
+			selfPath:              "notes/self.md",
+			anchorFormattingStyle: FORMAT_ANCHOR_HUGO,
+			raw:                   []rune("[google]( https://google.com )"),
+			want:                  []rune("[google]( https://google.com )"),
+		},
+		{
+			name:                  "not found",
+			selfPath:              "notes/self.md",
+			anchorFormattingStyle: FORMAT_ANCHOR_HUGO,
+			raw:                   []rune("[text](not_found.md)"),
+			want:                  []rune("[text](not_found.md)"),
+		},
+		{
+			name:                  "in code",
+			selfPath:              "notes/self.md",
+			anchorFormattingStyle: FORMAT_ANCHOR_HUGO,
+			raw:                   []rune("`[text](target.md)`"),
+			want:                  []rune("`[text](target.md)`"),
+		},
+	}
+
+	db := NewFileIdDB(testLinkImporterVaultDir)
+	for _, tt := range cases {
+		c := NewLinkImporter(db, testLinkImporterVaultDir, tt.selfPath, tt.anchorFormattingStyle)
+		got, err := c.Convert(tt.raw)
+		if err != nil {
+			t.Fatalf("[FATAL | %v] unexpected error ocurred: %v", tt.name, err)
+		}
+		if string(got) != string(tt.want) {
+			t.Errorf("[ERROR | %v]\n\t got: %q\n\twant: %q", tt.name, string(got), string(tt.want))
+		}
+	}
+}
+
+func TestLinkImporterCachesHeadings(t *testing.T) {
+	vault := t.TempDir()
+	if err := os.WriteFile(filepath.Join(vault, "target.md"), []byte("# Target\n\n## My Heading\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	importer := newLinkImporterImpl(NewFileIdDB(vault), vault, "self.md", FORMAT_ANCHOR_HUGO)
+	if _, err := importer.findHeading("target.md", "my-heading"); err != nil {
+		t.Fatalf("[FATAL] unexpected error ocurred: %v", err)
+	}
+	// 2 回目はファイルを読まない
+	if err := os.Remove(filepath.Join(vault, "target.md")); err != nil {
+		t.Fatal(err)
+	}
+	got, err := importer.findHeading("target.md", "my-heading")
+	if err != nil {
+		t.Fatalf("[FATAL] unexpected error ocurred: %v", err)
+	}
+	if got != "My Heading" {
+		t.Errorf("[ERROR] got: %q, want: %q", got, "My Heading")
+	}
+}
@@ -0,0 +1,853 @@
+package convert
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/qawatake/obsdconv/scan"
+)
+
+// Dataview のクエリのうち, LIST と TABLE の一部だけを静的に描画する.
+// 対応していないクエリは警告を出して, コードブロックをそのまま残す.
+
+// vault 内の markdown ファイル
+type Page struct {
+	Path        string // vault からの相対パス (/ 区切り)
+	FrontMatter map[string]interface{}
+	Tags        []string // front matter と本文のタグ (# なし)
+}
+
+const (
+	DATAVIEW_LIST  = "LIST"
+	DATAVIEW_TABLE = "TABLE"
+)
+
+type DataviewQuery struct {
+	Type      string
+	WithoutId bool
+	Fields    []DataviewField
+	From      string // #tag または folder. 空文字列なら vault 全体
+	Where     dataviewExpr
+	Sort      []DataviewSortKey
+	Limit     int // 0 なら制限なし
+}
+
+type DataviewField struct {
+	Name   string
+	Header string
+}
+
+type DataviewSortKey struct {
+	Field string
+	Desc  bool
+}
+
+func NewDataviewConverter(pages []Page, warnings *[]ErrConvert) *Converter {
+	c := new(Converter)
+
+	c.Set(MiddlewareAsIs(scan.ScanEscaped))
+	c.Set(TransformDataviewFunc(pages, warnings))
+	c.Set(MiddlewareAsIs(scan.ScanCodeBlock))
+	c.Set(MiddlewareAsIs(scan.ScanComment))
+	c.Set(MiddlewareAsIs(scan.ScanMathBlock))
+	c.Set(MiddlewareAsIs(scan.ScanNormalComment))
+	c.Set(MiddlewareAsIs(scan.ScanInlineMath))
+	c.Set(MiddlewareAsIs(scan.ScanInlineCode))
+	c.Set(TransformNone)
+	return c
+}
+
+// ```dataview のコードブロックを描画結果に置き換える.
+// 対応していないクエリは warnings に追加して, そのまま残す.
+func TransformDataviewFunc(pages []Page, warnings *[]ErrConvert) TransformerFunc {
+	return func(raw []rune, ptr int) (advance int, tobewritten []rune, err error) {
+		advance = scan.ScanCodeBlock(raw, ptr)
+		if advance == 0 {
+			return 0, nil, nil
+		}
+		lang, src, ok := splitCodeBlock(string(raw[ptr : ptr+advance]))
+		if !ok {
+			return 0, nil, nil
+		}
+
+		var rendered string
+		switch strings.ToLower(lang) {
+		case "dataview":
+			q, err := ParseDataviewQuery(src)
+			if err == nil {
+				rendered = q.Render(q.Evaluate(pages))
+			} else {
+				e := newErrConvert(err)
+				e.SetLine(currentLine(raw, ptr))
+				*warnings = append(*warnings, e)
+				return advance, raw[ptr : ptr+advance], nil
+			}
+		case "dataviewjs", "query":
+			e := newErrConvert(newErrTransformf(ERR_KIND_UNSUPPORTED_DATAVIEW_QUERY, "unsupported code block: %s", lang))
+			e.SetLine(currentLine(raw, ptr))
+			*warnings = append(*warnings, e)
+			return advance, raw[ptr : ptr+advance], nil
+		default:
+			return 0, nil, nil
+		}
+		return advance, []rune(rendered), nil
+	}
+}
+
+// ```lang
+// src
+// ```
+// を lang と src に分ける
+func splitCodeBlock(block string) (lang string, src string, ok bool) {
+	fence := len(block) - len(strings.TrimLeft(block, "`"))
+	if fence < 3 {
+		return "", "", false
+	}
+	nl := strings.Index(block, "\n")
+	if nl < 0 || len(block)-fence < nl+1 {
+		return "", "", false
+	}
+	info := strings.Fields(block[fence:nl])
+	if len(info) == 0 {
+		return "", "", false
+	}
+	return info[0], strings.TrimRight(block[nl+1:len(block)-fence], " \t\r\n"), true
+}
+
+func (q *DataviewQuery) Evaluate(pages []Page) []Page {
+	result := make([]Page, 0)
+	for _, p := range pages {
+		if !q.from(&p) {
+			continue
+		}
+		if q.Where != nil && !dataviewTruthy(q.Where.eval(&p)) {
+			continue
+		}
+		result = append(result, p)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+	sort.SliceStable(result, func(i, j int) bool {
+		for _, key := range q.Sort {
+			c := dataviewCompare(dataviewFieldValue(&result[i], key.Field), dataviewFieldValue(&result[j], key.Field))
+			if c == 0 {
+				continue
+			}
+			if key.Desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+
+	if q.Limit > 0 && len(result) > q.Limit {
+		result = result[:q.Limit]
+	}
+	return result
+}
+
+func (q *DataviewQuery) from(p *Page) bool {
+	if q.From == "" {
+		return true
+	}
+	if strings.HasPrefix(q.From, "#") {
+		tag := q.From[1:]
+		for _, t := range p.Tags {
+			if t == tag || strings.HasPrefix(t, tag+"/") {
+				return true
+			}
+		}
+		return false
+	}
+	folder := strings.Trim(q.From, "/")
+	return p.Path == folder || p.Path == folder+".md" || strings.HasPrefix(p.Path, folder+"/")
+}
+
+// 結果は Obsidian の internal link で書く.
+// -link が指定されていれば, 後で標準形式に変換される.
+func (q *DataviewQuery) Render(pages []Page) string {
+	b := new(strings.Builder)
+	switch q.Type {
+	case DATAVIEW_LIST:
+		for _, p := range pages {
+			items := make([]string, 0, 2)
+			if !q.WithoutId {
+				items = append(items, dataviewLink(&p))
+			}
+			for _, f := range q.Fields {
+				items = append(items, formatDataviewValue(dataviewFieldValue(&p, f.Name)))
+			}
+			fmt.Fprintf(b, "- %s\n", strings.Join(items, ": "))
+		}
+	case DATAVIEW_TABLE:
+		headers := make([]string, 0, len(q.Fields)+1)
+		if !q.WithoutId {
+			headers = append(headers, "File")
+		}
+		for _, f := range q.Fields {
+			headers = append(headers, f.Header)
+		}
+		fmt.Fprintf(b, "| %s |\n", strings.Join(headers, " | "))
+		fmt.Fprintf(b, "|%s\n", strings.Repeat(" --- |", len(headers)))
+		for _, p := range pages {
+			cells := make([]string, 0, len(headers))
+			if !q.WithoutId {
+				cells = append(cells, strings.ReplaceAll(dataviewLink(&p), "|", "\\|"))
+			}
+			for _, f := range q.Fields {
+				cell := formatDataviewValue(dataviewFieldValue(&p, f.Name))
+				cell = strings.ReplaceAll(cell, "|", "\\|")
+				cell = strings.ReplaceAll(cell, "\n", " ")
+				cells = append(cells, cell)
+			}
+			fmt.Fprintf(b, "| %s |\n", strings.Join(cells, " | "))
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func dataviewLink(p *Page) string {
+	fileId := strings.TrimSuffix(p.Path, ".md")
+	name := path.Base(fileId)
+	if name == fileId {
+		return fmt.Sprintf("[[%s]]", fileId)
+	}
+	return fmt.Sprintf("[[%s|%s]]", fileId, name)
+}
+
+func formatDataviewValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = formatDataviewValue(item)
+		}
+		return strings.Join(items, ", ")
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.Format("2006-01-02")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+var dataviewFileFields = map[string]struct{}{
+	"file.name":   {},
+	"file.path":   {},
+	"file.folder": {},
+	"file.tags":   {},
+}
+
+func dataviewFieldValue(p *Page, name string) interface{} {
+	switch name {
+	case "file.name":
+		return strings.TrimSuffix(path.Base(p.Path), ".md")
+	case "file.path":
+		return p.Path
+	case "file.folder":
+		if dir := path.Dir(p.Path); dir != "." {
+			return dir
+		}
+		return ""
+	case "file.tags":
+		tags := make([]interface{}, len(p.Tags))
+		for i, t := range p.Tags {
+			tags[i] = "#" + t
+		}
+		return tags
+	}
+	if v, ok := p.FrontMatter[name]; ok {
+		return v
+	}
+	// Dataview ではフィールド名の大文字小文字を区別しない
+	for k, v := range p.FrontMatter {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return nil
+}
+
+type dataviewExpr interface {
+	eval(p *Page) interface{}
+}
+
+type dataviewFieldExpr struct {
+	name string
+}
+
+func (e *dataviewFieldExpr) eval(p *Page) interface{} {
+	return dataviewFieldValue(p, e.name)
+}
+
+type dataviewLiteralExpr struct {
+	value interface{}
+}
+
+func (e *dataviewLiteralExpr) eval(p *Page) interface{} {
+	return e.value
+}
+
+type dataviewBinaryExpr struct {
+	op    string
+	left  dataviewExpr
+	right dataviewExpr
+}
+
+func (e *dataviewBinaryExpr) eval(p *Page) interface{} {
+	switch e.op {
+	case "and":
+		return dataviewTruthy(e.left.eval(p)) && dataviewTruthy(e.right.eval(p))
+	case "or":
+		return dataviewTruthy(e.left.eval(p)) || dataviewTruthy(e.right.eval(p))
+	}
+	l, r := e.left.eval(p), e.right.eval(p)
+	switch e.op {
+	case "=":
+		return dataviewEqual(l, r)
+	case "!=":
+		return !dataviewEqual(l, r)
+	}
+	// null との大小は比較しない
+	if l == nil || r == nil {
+		return false
+	}
+	c := dataviewCompare(l, r)
+	switch e.op {
+	case "<":
+		return c < 0
+	case ">":
+		return c > 0
+	case "<=":
+		return c <= 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+type dataviewNotExpr struct {
+	expr dataviewExpr
+}
+
+func (e *dataviewNotExpr) eval(p *Page) interface{} {
+	return !dataviewTruthy(e.expr.eval(p))
+}
+
+type dataviewContainsExpr struct {
+	haystack dataviewExpr
+	needle   dataviewExpr
+}
+
+func (e *dataviewContainsExpr) eval(p *Page) interface{} {
+	needle := e.needle.eval(p)
+	switch h := e.haystack.eval(p).(type) {
+	case []interface{}:
+		for _, item := range h {
+			if dataviewEqual(item, needle) {
+				return true
+			}
+		}
+		return false
+	case string:
+		return strings.Contains(h, formatDataviewValue(needle))
+	}
+	return false
+}
+
+func dataviewTruthy(v interface{}) bool {
+	switch v := normalizeDataviewValue(v).(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	case []interface{}:
+		return len(v) > 0
+	}
+	return true
+}
+
+func dataviewEqual(a, b interface{}) bool {
+	a, b = normalizeDataviewValue(a), normalizeDataviewValue(b)
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if _, ok := a.([]interface{}); ok {
+		return false
+	}
+	return dataviewCompare(a, b) == 0
+}
+
+// null は最小とする
+func dataviewCompare(a, b interface{}) int {
+	a, b = normalizeDataviewValue(a), normalizeDataviewValue(b)
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	switch a := a.(type) {
+	case float64:
+		if b, ok := b.(float64); ok {
+			switch {
+			case a < b:
+				return -1
+			case a > b:
+				return 1
+			}
+			return 0
+		}
+	case bool:
+		if b, ok := b.(bool); ok {
+			switch {
+			case a == b:
+				return 0
+			case !a:
+				return -1
+			}
+			return 1
+		}
+	}
+	return strings.Compare(formatDataviewValue(a), formatDataviewValue(b))
+}
+
+func normalizeDataviewValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case time.Time:
+		return v.Format("2006-01-02")
+	}
+	return v
+}
+
+const (
+	dataviewTokenIdent = iota
+	dataviewTokenString
+	dataviewTokenNumber
+	dataviewTokenTag
+	dataviewTokenSymbol
+)
+
+var dataviewComparisonOperators = map[string]struct{}{
+	"=": {}, "!=": {}, "<": {}, ">": {}, "<=": {}, ">=": {},
+}
+
+var dataviewSymbols = map[string]struct{}{
+	"=": {}, "!=": {}, "<": {}, ">": {}, "<=": {}, ">=": {}, "!": {}, ",": {}, "(": {}, ")": {},
+}
+
+type dataviewToken struct {
+	kind int
+	text string
+}
+
+func unsupportedDataviewQuery(format string, a ...interface{}) error {
+	return newErrTransformf(ERR_KIND_UNSUPPORTED_DATAVIEW_QUERY, "unsupported dataview query: "+format, a...)
+}
+
+func lexDataview(src string) (tokens []dataviewToken, err error) {
+	rs := []rune(src)
+	cur := 0
+	for cur < len(rs) {
+		r := rs[cur]
+		switch {
+		case unicode.IsSpace(r):
+			cur++
+		case r == '"':
+			end := cur + 1
+			for end < len(rs) && rs[end] != '"' {
+				if rs[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(rs) {
+				return nil, unsupportedDataviewQuery("unclosed string")
+			}
+			s, err := strconv.Unquote(string(rs[cur : end+1]))
+			if err != nil {
+				return nil, unsupportedDataviewQuery("invalid string %s", string(rs[cur:end+1]))
+			}
+			tokens = append(tokens, dataviewToken{kind: dataviewTokenString, text: s})
+			cur = end + 1
+		case r == '#':
+			adv, tag := scan.ScanTag(rs, cur)
+			if adv == 0 {
+				return nil, unsupportedDataviewQuery("invalid tag")
+			}
+			tokens = append(tokens, dataviewToken{kind: dataviewTokenTag, text: tag})
+			cur += adv
+		case unicode.IsDigit(r) || (r == '-' && cur+1 < len(rs) && unicode.IsDigit(rs[cur+1])):
+			end := cur + 1
+			for end < len(rs) && (unicode.IsDigit(rs[end]) || rs[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, dataviewToken{kind: dataviewTokenNumber, text: string(rs[cur:end])})
+			cur = end
+		case unicode.IsLetter(r) || r == '_':
+			end := cur + 1
+			for end < len(rs) && (unicode.IsLetter(rs[end]) || unicode.IsDigit(rs[end]) || rs[end] == '_' || rs[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, dataviewToken{kind: dataviewTokenIdent, text: string(rs[cur:end])})
+			cur = end
+		default:
+			symbol := string(r)
+			if cur+1 < len(rs) && rs[cur+1] == '=' && strings.ContainsRune("!<>", r) {
+				symbol += "="
+			}
+			if _, ok := dataviewSymbols[symbol]; !ok {
+				return nil, unsupportedDataviewQuery("unexpected character %q", r)
+			}
+			tokens = append(tokens, dataviewToken{kind: dataviewTokenSymbol, text: symbol})
+			cur += len([]rune(symbol))
+		}
+	}
+	return tokens, nil
+}
+
+type dataviewParser struct {
+	tokens []dataviewToken
+	pos    int
+}
+
+func (p *dataviewParser) peek() *dataviewToken {
+	if p.pos >= len(p.tokens) {
+		return nil
+	}
+	return &p.tokens[p.pos]
+}
+
+func (p *dataviewParser) next() *dataviewToken {
+	t := p.peek()
+	if t != nil {
+		p.pos++
+	}
+	return t
+}
+
+func (p *dataviewParser) keyword(words ...string) bool {
+	t := p.peek()
+	if t == nil || t.kind != dataviewTokenIdent {
+		return false
+	}
+	for _, w := range words {
+		if strings.EqualFold(t.text, w) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *dataviewParser) symbol(s string) bool {
+	t := p.peek()
+	return t != nil && t.kind == dataviewTokenSymbol && t.text == s
+}
+
+func (p *dataviewParser) clauseHead() bool {
+	return p.peek() == nil || p.keyword("FROM", "WHERE", "SORT", "LIMIT", "FLATTEN", "GROUP")
+}
+
+// LIST, TABLE と FROM, WHERE, SORT, LIMIT だけに対応する
+func ParseDataviewQuery(src string) (*DataviewQuery, error) {
+	tokens, err := lexDataview(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &dataviewParser{tokens: tokens}
+	q := new(DataviewQuery)
+
+	switch {
+	case p.keyword(DATAVIEW_LIST):
+		q.Type = DATAVIEW_LIST
+	case p.keyword(DATAVIEW_TABLE):
+		q.Type = DATAVIEW_TABLE
+	case p.peek() == nil:
+		return nil, unsupportedDataviewQuery("empty query")
+	default:
+		return nil, unsupportedDataviewQuery("query type %s", p.peek().text)
+	}
+	p.next()
+	if p.keyword("WITHOUT") {
+		p.next()
+		if !p.keyword("ID") {
+			return nil, unsupportedDataviewQuery("WITHOUT must be followed by ID")
+		}
+		p.next()
+		q.WithoutId = true
+	}
+	for !p.clauseHead() {
+		if len(q.Fields) > 0 {
+			if !p.symbol(",") {
+				return nil, unsupportedDataviewQuery("fields must be separated by commas")
+			}
+			p.next()
+		}
+		name, err := p.parseFieldName()
+		if err != nil {
+			return nil, err
+		}
+		f := DataviewField{Name: name, Header: name}
+		if p.keyword("AS") {
+			p.next()
+			t := p.next()
+			if t == nil || (t.kind != dataviewTokenString && t.kind != dataviewTokenIdent) {
+				return nil, unsupportedDataviewQuery("AS must be followed by a header")
+			}
+			f.Header = t.text
+		}
+		q.Fields = append(q.Fields, f)
+	}
+	if q.Type == DATAVIEW_LIST && len(q.Fields) > 1 {
+		return nil, unsupportedDataviewQuery("LIST takes at most one field")
+	}
+	if q.WithoutId && len(q.Fields) == 0 {
+		return nil, unsupportedDataviewQuery("WITHOUT ID needs fields")
+	}
+
+	for p.peek() != nil {
+		switch {
+		case p.keyword("FROM"):
+			p.next()
+			t := p.next()
+			if t == nil {
+				return nil, unsupportedDataviewQuery("FROM must be followed by a source")
+			}
+			switch t.kind {
+			case dataviewTokenTag:
+				q.From = "#" + t.text
+			case dataviewTokenString:
+				q.From = t.text
+			default:
+				return nil, unsupportedDataviewQuery("source %s", t.text)
+			}
+			if !p.clauseHead() {
+				return nil, unsupportedDataviewQuery("multiple sources")
+			}
+		case p.keyword("WHERE"):
+			p.next()
+			cond, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if q.Where == nil {
+				q.Where = cond
+			} else {
+				q.Where = &dataviewBinaryExpr{op: "and", left: q.Where, right: cond}
+			}
+		case p.keyword("SORT"):
+			p.next()
+			for {
+				name, err := p.parseFieldName()
+				if err != nil {
+					return nil, err
+				}
+				key := DataviewSortKey{Field: name}
+				if p.keyword("DESC", "DESCENDING") {
+					key.Desc = true
+					p.next()
+				} else if p.keyword("ASC", "ASCENDING") {
+					p.next()
+				}
+				q.Sort = append(q.Sort, key)
+				if !p.symbol(",") {
+					break
+				}
+				p.next()
+			}
+		case p.keyword("LIMIT"):
+			p.next()
+			t := p.next()
+			if t == nil || t.kind != dataviewTokenNumber {
+				return nil, unsupportedDataviewQuery("LIMIT must be followed by a number")
+			}
+			n, err := strconv.Atoi(t.text)
+			if err != nil || n <= 0 {
+				return nil, unsupportedDataviewQuery("LIMIT %s", t.text)
+			}
+			q.Limit = n
+		default:
+			return nil, unsupportedDataviewQuery("clause %s", p.peek().text)
+		}
+	}
+	return q, nil
+}
+
+func (p *dataviewParser) parseFieldName() (name string, err error) {
+	t := p.next()
+	if t == nil || t.kind != dataviewTokenIdent {
+		return "", unsupportedDataviewQuery("expected a field")
+	}
+	if strings.HasPrefix(t.text, "file.") {
+		if _, ok := dataviewFileFields[t.text]; !ok {
+			return "", unsupportedDataviewQuery("field %s", t.text)
+		}
+	}
+	if p.symbol("(") {
+		return "", unsupportedDataviewQuery("function %s", t.text)
+	}
+	return t.text, nil
+}
+
+func (p *dataviewParser) parseOr() (dataviewExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("OR") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &dataviewBinaryExpr{op: "or", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *dataviewParser) parseAnd() (dataviewExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("AND") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &dataviewBinaryExpr{op: "and", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *dataviewParser) parseUnary() (dataviewExpr, error) {
+	if p.symbol("!") || p.keyword("NOT") {
+		p.next()
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &dataviewNotExpr{expr: e}, nil
+	}
+	if p.symbol("(") {
+		p.next()
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.symbol(")") {
+			return nil, unsupportedDataviewQuery("unclosed parenthesis")
+		}
+		p.next()
+		return e, nil
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	if t == nil || t.kind != dataviewTokenSymbol {
+		return left, nil
+	}
+	if _, ok := dataviewComparisonOperators[t.text]; !ok {
+		return left, nil
+	}
+	p.next()
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	return &dataviewBinaryExpr{op: t.text, left: left, right: right}, nil
+}
+
+func (p *dataviewParser) parseOperand() (dataviewExpr, error) {
+	t := p.peek()
+	if t == nil {
+		return nil, unsupportedDataviewQuery("expected an operand")
+	}
+	switch t.kind {
+	case dataviewTokenString:
+		p.next()
+		return &dataviewLiteralExpr{value: t.text}, nil
+	case dataviewTokenNumber:
+		p.next()
+		v, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, unsupportedDataviewQuery("number %s", t.text)
+		}
+		return &dataviewLiteralExpr{value: v}, nil
+	case dataviewTokenTag:
+		p.next()
+		return &dataviewLiteralExpr{value: "#" + t.text}, nil
+	case dataviewTokenIdent:
+		switch strings.ToLower(t.text) {
+		case "true", "false":
+			p.next()
+			return &dataviewLiteralExpr{value: strings.ToLower(t.text) == "true"}, nil
+		case "null":
+			p.next()
+			return &dataviewLiteralExpr{value: nil}, nil
+		case "contains":
+			p.next()
+			return p.parseContains()
+		}
+		name, err := p.parseFieldName()
+		if err != nil {
+			return nil, err
+		}
+		return &dataviewFieldExpr{name: name}, nil
+	}
+	return nil, unsupportedDataviewQuery("unexpected %s", t.text)
+}
+
+// contains(field, value)
+func (p *dataviewParser) parseContains() (dataviewExpr, error) {
+	if !p.symbol("(") {
+		return nil, unsupportedDataviewQuery("contains must be called")
+	}
+	p.next()
+	haystack, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if !p.symbol(",") {
+		return nil, unsupportedDataviewQuery("contains takes two arguments")
+	}
+	p.next()
+	needle, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if !p.symbol(")") {
+		return nil, unsupportedDataviewQuery("contains takes two arguments")
+	}
+	p.next()
+	return &dataviewContainsExpr{haystack: haystack, needle: needle}, nil
+}
@@ -0,0 +1,77 @@
+package convert
+
+import "testing"
+
+func TestDataviewConverter(t *testing.T) {
+	pages := []Page{
+		{Path: "index.md", FrontMatter: map[string]interface{}{}},
+		{Path: "projects/b.md", FrontMatter: map[string]interface{}{"status": "active", "priority": 2, "tags": []interface{}{"project"}}, Tags: []string{"project"}},
+		{Path: "projects/a.md", FrontMatter: map[string]interface{}{"status": "active", "priority": 1}, Tags: []string{"project/sub"}},
+		{Path: "projects/c.md", FrontMatter: map[string]interface{}{"status": "done", "priority": 3}, Tags: []string{"project"}},
+		{Path: "notes/d.md", FrontMatter: map[string]interface{}{"status": "active", "Title": "a | b"}},
+	}
+
+	cases := []struct {
+		name         string
+		raw          []rune
+		want         []rune
+		wantWarnings int
+	}{
+		{
+			name: "list from tag",
+			raw:  []rune("# Index\n\n```dataview\nLIST FROM #project WHERE status = \"active\" SORT file.name\n```\n"),
+			want: []rune("# Index\n\n- [[projects/a|a]]\n- [[projects/b|b]]\n"),
+		},
+		{
+			name: "list with field from folder",
+			raw:  []rune("```dataview\nlist status\nfrom \"projects\"\nsort priority desc\nlimit 2\n```"),
+			want: []rune("- [[projects/c|c]]: done\n- [[projects/b|b]]: active"),
+		},
+		{
+			name: "table",
+			raw:  []rune("```dataview\nTABLE status AS \"Status\", title\nWHERE status != \"done\" AND (priority >= 2 OR !priority)\n```"),
+			want: []rune("| File | Status | title |\n| --- | --- | --- |\n| [[index]] |  |  |\n| [[notes/d\\|d]] | active | a \\| b |\n| [[projects/b\\|b]] | active |  |"),
+		},
+		{
+			name: "table without id and contains",
+			raw:  []rune("```dataview\nTABLE WITHOUT ID file.name, priority WHERE contains(tags, \"project\")\n```"),
+			want: []rune("| file.name | priority |\n| --- | --- |\n| b | 2 |"),
+		},
+		{
+			name:         "unsupported query",
+			raw:          []rune("```dataview\nTASK FROM #project\n```\n```dataview\nLIST WHERE length(file.tags) > 1\n```\n"),
+			want:         []rune("```dataview\nTASK FROM #project\n```\n```dataview\nLIST WHERE length(file.tags) > 1\n```\n"),
+			wantWarnings: 2,
+		},
+		{
+			name:         "dataviewjs",
+			raw:          []rune("```dataviewjs\ndv.list([])\n```"),
+			want:         []rune("```dataviewjs\ndv.list([])\n```"),
+			wantWarnings: 1,
+		},
+		{
+			name: "other code blocks",
+			raw:  []rune("```go\nLIST FROM #project\n```\n`LIST`"),
+			want: []rune("```go\nLIST FROM #project\n```\n`LIST`"),
+		},
+	}
+
+	for _, tt := range cases {
+		warnings := make([]ErrConvert, 0)
+		got, err := NewDataviewConverter(pages, &warnings).Convert(tt.raw)
+		if err != nil {
+			t.Fatalf("[FATAL | %s] unexpected error ocurred: %v", tt.name, err)
+		}
+		if string(got) != string(tt.want) {
+			t.Errorf("[ERROR | %s]\n\t got: %q\n\twant: %q", tt.name, string(got), string(tt.want))
+		}
+		if len(warnings) != tt.wantWarnings {
+			t.Errorf("[ERROR | %s] got %d warnings, want %d: %v", tt.name, len(warnings), tt.wantWarnings, warnings)
+		}
+		for _, w := range warnings {
+			if e, ok := w.Source().(ErrTransform); !ok || e.Kind() != ERR_KIND_UNSUPPORTED_DATAVIEW_QUERY {
+				t.Errorf("[ERROR | %s] unexpected warning: %v", tt.name, w)
+			}
+		}
+	}
+}
@@ -44,6 +44,8 @@ const (
 	ERR_KIND_UNEXPECTED_HREF
 	ERR_KIND_INVALID_SHORTHAND_OBSIDIAN_URL
 	ERR_KIND_PATH_NOT_FOUND
+	ERR_KIND_UNDEFINED_REFERENCE_LABEL
+	ERR_KIND_UNSUPPORTED_DATAVIEW_QUERY
 )
 
 type errTransformImpl struct {
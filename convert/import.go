@@ -0,0 +1,198 @@
+package convert
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/qawatake/obsdconv/scan"
+)
+
+func TransformExternalLinkToInternalFunc(t ExternalLinkImporter) TransformerFunc {
+	return func(raw []rune, ptr int) (advance int, tobewritten []rune, err error) {
+		advance, displayName, ref, title := scan.ScanExternalLink(raw, ptr)
+		if advance == 0 {
+			return 0, nil, nil
+		}
+
+		internalLink, imported, err := t.ImportExternalLink(displayName, ref, title)
+		if err != nil {
+			return 0, nil, errors.Wrap(err, "t.ImportExternalLink failed")
+		}
+		if !imported {
+			return advance, raw[ptr : ptr+advance], nil
+		}
+		return advance, []rune(internalLink), nil
+	}
+}
+
+func TransformImageLinkToEmbedsFunc(t ImageLinkImporter) TransformerFunc {
+	return func(raw []rune, ptr int) (advance int, tobewritten []rune, err error) {
+		advance, alt, ref, title := scan.ScanImageLink(raw, ptr)
+		if advance == 0 {
+			return 0, nil, nil
+		}
+
+		embeds, imported, err := t.ImportImageLink(alt, ref, title)
+		if err != nil {
+			return 0, nil, errors.Wrap(err, "t.ImportImageLink failed")
+		}
+		if !imported {
+			return advance, raw[ptr : ptr+advance], nil
+		}
+		return advance, []rune(embeds), nil
+	}
+}
+
+// vault 内のファイルを指さないリンク (通常の URL など) は imported = false を返し, そのまま残す
+type ExternalLinkImporter interface {
+	ImportExternalLink(displayName, ref string, title string) (internalLink string, imported bool, err error)
+}
+
+type ImageLinkImporter interface {
+	ImportImageLink(alt, ref string, title string) (embeds string, imported bool, err error)
+}
+
+type LinkImporterImpl struct {
+	FileIdDB
+	vault                 string
+	selfPath              string
+	anchorFormattingStyle string
+	headings              map[string][]Heading // 読み込んだファイルの見出し. 同じファイルへのリンクで読み直さない
+}
+
+func newLinkImporterImpl(db FileIdDB, vault string, selfPath string, anchorFormattingStyle string) *LinkImporterImpl {
+	return &LinkImporterImpl{
+		FileIdDB:              db,
+		vault:                 vault,
+		selfPath:              filepath.ToSlash(selfPath),
+		anchorFormattingStyle: anchorFormattingStyle,
+		headings:              make(map[string][]Heading),
+	}
+}
+
+func (t *LinkImporterImpl) ImportExternalLink(displayName, ref string, title string) (internalLink string, imported bool, err error) {
+	pth, fileId, anchor, ok, err := t.resolve(ref)
+	if err != nil {
+		return "", false, err
+	}
+	if !ok {
+		return "", false, nil
+	}
+
+	identifier := fileId
+	var fragments []string
+	if anchor != "" {
+		heading, err := t.findHeading(pth, anchor)
+		if err != nil {
+			return "", false, err
+		}
+		fragments = []string{heading}
+		identifier += "#" + heading
+	}
+	if pth == t.selfPath && anchor != "" {
+		// 自分自身へのリンクは [[#Heading]] とする
+		fileId = ""
+		identifier = "#" + fragments[0]
+	}
+
+	if displayName == "" || displayName == buildLinkText("", fileId, fragments) {
+		return fmt.Sprintf("[[%s]]", identifier), true, nil
+	}
+	return fmt.Sprintf("[[%s|%s]]", identifier, displayName), true, nil
+}
+
+func (t *LinkImporterImpl) ImportImageLink(alt, ref string, title string) (embeds string, imported bool, err error) {
+	pth, fileId, _, ok, err := t.resolve(ref)
+	if err != nil {
+		return "", false, err
+	}
+	if !ok {
+		return "", false, nil
+	}
+
+	if alt == "" || alt == fileId || alt == path.Base(pth) {
+		return fmt.Sprintf("![[%s]]", fileId), true, nil
+	}
+	return fmt.Sprintf("![[%s|%s]]", fileId, alt), true, nil
+}
+
+// 相対パスで書かれた ref を vault からのパスに直して, fileId を引く
+func (t *LinkImporterImpl) resolve(ref string) (pth string, fileId string, anchor string, ok bool, err error) {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return "", "", "", false, nil
+	}
+	if u.Scheme != "" || u.Host != "" {
+		return "", "", "", false, nil
+	}
+
+	if u.Path == "" {
+		if u.Fragment == "" {
+			return "", "", "", false, nil
+		}
+		pth = t.selfPath
+	} else if strings.HasPrefix(u.Path, "/") {
+		pth = path.Clean(strings.TrimLeft(u.Path, "/"))
+	} else {
+		pth = path.Join(path.Dir(t.selfPath), u.Path)
+	}
+	if pth == ".." || strings.HasPrefix(pth, "../") {
+		return "", "", "", false, nil
+	}
+
+	fileId, err = t.GetFileId(pth)
+	if err != nil {
+		return "", "", "", false, errors.Wrap(err, "FileIdDB.GetFileId failed")
+	}
+	// -formatLink で .md が省略されている場合
+	if fileId == "" && path.Ext(pth) == "" {
+		pth += ".md"
+		fileId, err = t.GetFileId(pth)
+		if err != nil {
+			return "", "", "", false, errors.Wrap(err, "FileIdDB.GetFileId failed")
+		}
+	}
+	if fileId == "" {
+		return "", "", "", false, nil
+	}
+	return pth, fileId, u.Fragment, true, nil
+}
+
+// anchor に変換される前の見出しを探す.
+// 見つからなければ anchor をそのまま返す.
+func (t *LinkImporterImpl) findHeading(pth string, anchor string) (heading string, err error) {
+	if path.Ext(pth) != ".md" {
+		return anchor, nil
+	}
+	headings, err := t.findHeadings(pth)
+	if err != nil {
+		return "", err
+	}
+	for _, h := range headings {
+		if h.Text == anchor || formatAnchorByStyle(h.Text, t.anchorFormattingStyle) == anchor {
+			return h.Text, nil
+		}
+	}
+	return anchor, nil
+}
+
+func (t *LinkImporterImpl) findHeadings(pth string) (headings []Heading, err error) {
+	if headings, ok := t.headings[pth]; ok {
+		return headings, nil
+	}
+	content, err := os.ReadFile(filepath.Join(t.vault, filepath.FromSlash(pth)))
+	if err != nil {
+		return nil, newErrTransformf(ERR_KIND_UNEXPECTED, "failed to read %s: %v", pth, err)
+	}
+	headings = make([]Heading, 0)
+	if _, err := NewHeadingFinder(&headings).Convert([]rune(string(content))); err != nil {
+		return nil, errors.Wrapf(err, "HeadingFinder failed in %s", pth)
+	}
+	t.headings[pth] = headings
+	return headings, nil
+}
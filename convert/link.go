@@ -20,6 +20,20 @@ type pathDbImpl struct {
 }
 
 func NewPathDB(vault string) PathDB {
+	return newPathDbImpl(vault)
+}
+
+// PathDB の逆引き.
+// vault 内のパスから, Obsidian と同様に vault 内で一意に定まる最短の fileId を返す.
+type FileIdDB interface {
+	GetFileId(path string) (fileId string, err error)
+}
+
+func NewFileIdDB(vault string) FileIdDB {
+	return newPathDbImpl(vault)
+}
+
+func newPathDbImpl(vault string) *pathDbImpl {
 	db := new(pathDbImpl)
 	db.vault = vault
 	db.vaultdict = make(map[string][]string)
@@ -77,6 +91,50 @@ func (f *pathDbImpl) Get(fileId string) (path string, err error) {
 	return filepath.ToSlash(path), nil
 }
 
+// path は vault からの相対パス.
+// vault 内に見つからなければ fileId = "" を返す.
+func (f *pathDbImpl) GetFileId(path string) (fileId string, err error) {
+	path = norm.NFC.String(filepath.ToSlash(filepath.Clean(path)))
+	base := filepath.Base(path)
+
+	found := false
+	others := make([][]string, 0)
+	for _, pth := range f.vaultdict[base] {
+		rel, err := filepath.Rel(f.vault, pth)
+		if err != nil {
+			return "", newErrTransformf(ERR_KIND_UNEXPECTED, "filepath.Rel failed: %v", err)
+		}
+		rel = norm.NFC.String(filepath.ToSlash(rel))
+		if rel == path {
+			found = true
+			continue
+		}
+		others = append(others, strings.Split(rel, "/"))
+	}
+	if !found {
+		return "", nil
+	}
+
+	// 他のファイルと区別できるまで, 末尾から segment を増やしていく
+	segments := strings.Split(path, "/")
+	fileId = path
+	for n := 1; n <= len(segments); n++ {
+		suffix := segments[len(segments)-n:]
+		ambiguous := false
+		for _, other := range others {
+			if len(other) >= n && strings.Join(other[len(other)-n:], "/") == strings.Join(suffix, "/") {
+				ambiguous = true
+				break
+			}
+		}
+		if !ambiguous {
+			fileId = strings.Join(suffix, "/")
+			break
+		}
+	}
+	return strings.TrimSuffix(fileId, ".md"), nil
+}
+
 func pathMatchScore(path string, filename string) int {
 	// 書記素クラスタに対応
 	path = norm.NFC.String(path)
@@ -201,6 +259,57 @@ func WrapForRemappingPathPrefix(pathPrefixRemap map[string]string, original Path
 	}
 }
 
+type pathDBWrapperImplPrefixingPath struct {
+	prefix   string
+	original PathDB
+}
+
+func (w *pathDBWrapperImplPrefixingPath) Get(fileId string) (path string, err error) {
+	if w.original == nil {
+		panic("original PathDB not set but used")
+	}
+	path, err = w.original.Get(fileId)
+	if err != nil {
+		return "", err
+	}
+	if path == "" {
+		return "", nil
+	}
+	return w.prefix + path, nil
+}
+
+// 見つかったパスの先頭に prefix を付ける.
+// 別の vault のファイルへのリンクに使う.
+func WrapForPrefixingPath(prefix string, original PathDB) PathDB {
+	return &pathDBWrapperImplPrefixingPath{
+		prefix:   prefix,
+		original: original,
+	}
+}
+
+type pathDbImplForRemoteVault struct {
+	baseUrl string
+}
+
+// ファイルを探さずに, baseUrl に fileId を繋げた URL を返す.
+// ローカルにない vault (公開済みのサイトなど) へのリンクに使う.
+func NewPathDBForRemoteVault(baseUrl string) PathDB {
+	return &pathDbImplForRemoteVault{
+		baseUrl: baseUrl,
+	}
+}
+
+func (db *pathDbImplForRemoteVault) Get(fileId string) (path string, err error) {
+	if fileId == "" {
+		return "", nil
+	}
+	segments := make([]string, 0)
+	for _, segment := range strings.Split(strings.TrimSuffix(filepath.ToSlash(fileId), ".md"), "/") {
+		segments = append(segments, url.PathEscape(segment))
+	}
+	return db.baseUrl + strings.Join(segments, "/"), nil
+}
+
 // type pathDBWrapperImplSettingBaseUrl struct {
 // 	baseUrl  string
 // 	original PathDB
@@ -132,6 +132,35 @@ func TestPathDBGet(t *testing.T) {
 	}
 }
 
+func TestFileIdDBGetFileId(t *testing.T) {
+	testGetFileIdRootDir := filepath.Join("testdata", "fileiddbgetfileid")
+	cases := []struct {
+		name string
+		root string
+		path string
+		want string
+	}{
+		{name: "unique", root: "unique", path: "a/test.md", want: "test"},
+		{name: "ambiguous in subdir", root: "ambiguous", path: "a/test.md", want: "a/test"},
+		{name: "ambiguous in cur dir", root: "ambiguous", path: "test.md", want: "test"},
+		{name: "ambiguous in nested dirs", root: "nested", path: "a/x/test.md", want: "a/x/test"},
+		{name: "image", root: "image", path: "test.png", want: "test.png"},
+		{name: "not found", root: "unique", path: "test.md", want: ""},
+	}
+
+	for _, tt := range cases {
+		db := NewFileIdDB(filepath.Join(testGetFileIdRootDir, tt.root))
+		got, err := db.GetFileId(tt.path)
+		if err != nil {
+			t.Errorf("[FAIL | %v] %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("[ERROR | %v] got: %v, want: %v", tt.name, got, tt.want)
+		}
+	}
+}
+
 func TestBuildLinkText(t *testing.T) {
 	cases := []struct {
 		displayName string
@@ -0,0 +1,130 @@
+package convert
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/qawatake/obsdconv/scan"
+)
+
+const (
+	MATH_STYLE_LATEX   = "latex"
+	MATH_STYLE_HUGO    = "hugo"
+	MATH_STYLE_HTML    = "html"
+	MATH_STYLE_ESCAPED = "escaped"
+)
+
+var MATH_STYLES = []string{MATH_STYLE_LATEX, MATH_STYLE_HUGO, MATH_STYLE_HTML, MATH_STYLE_ESCAPED}
+
+// $...$ と $$...$$ を style の形式に書き換える.
+// latex: \(...\), \[...\]
+// hugo: {{< math >}}$...${{< /math >}}
+// html: <span class="math">\(...\)</span>, <div class="math">\[...\]</div>
+// escaped: $...$ のまま, Markdown として解釈される記号をエスケープする
+func NewMathConverter(style string) *Converter {
+	c := new(Converter)
+
+	c.Set(MiddlewareAsIs(scan.ScanEscaped))
+	c.Set(MiddlewareAsIs(scan.ScanCodeBlock))
+	c.Set(MiddlewareAsIs(scan.ScanComment))
+	c.Set(func(raw []rune, ptr int) (advance int, tobewritten []rune, err error) {
+		advance = scan.ScanMathBlock(raw, ptr)
+		if advance == 0 {
+			return 0, nil, nil
+		}
+		block := string(raw[ptr : ptr+advance])
+		// 閉じていないものはそのまま
+		if advance < 4 || !strings.HasSuffix(block, "$$") {
+			return advance, raw[ptr : ptr+advance], nil
+		}
+		rendered, err := renderMath(style, block[2:len(block)-2], true)
+		if err != nil {
+			return 0, nil, err
+		}
+		return advance, []rune(rendered), nil
+	})
+	c.Set(MiddlewareAsIs(scan.ScanNormalComment))
+	c.Set(func(raw []rune, ptr int) (advance int, tobewritten []rune, err error) {
+		advance = scan.ScanInlineMath(raw, ptr)
+		if advance == 0 {
+			return 0, nil, nil
+		}
+		rendered, err := renderMath(style, string(raw[ptr+1:ptr+advance-1]), false)
+		if err != nil {
+			return 0, nil, err
+		}
+		return advance, []rune(rendered), nil
+	})
+	c.Set(MiddlewareAsIs(scan.ScanInlineCode))
+	c.Set(TransformNone)
+	return c
+}
+
+func renderMath(style string, content string, block bool) (rendered string, err error) {
+	switch style {
+	case MATH_STYLE_LATEX:
+		if block {
+			return fmt.Sprintf("\\[%s\\]", content), nil
+		}
+		return fmt.Sprintf("\\(%s\\)", content), nil
+	case MATH_STYLE_HUGO:
+		if block {
+			return fmt.Sprintf("{{< math >}}$$%s$${{< /math >}}", content), nil
+		}
+		return fmt.Sprintf("{{< math >}}$%s${{< /math >}}", content), nil
+	case MATH_STYLE_HTML:
+		// <div> で始まる HTML ブロックの中身は Markdown として解釈されない
+		if block {
+			return fmt.Sprintf("<div class=\"math\">\\[%s\\]</div>", html.EscapeString(content)), nil
+		}
+		// <span> の中身は Markdown として解釈されるのでエスケープする
+		return fmt.Sprintf("<span class=\"math\">%s</span>", escapeMarkdown("\\("+content+"\\)")), nil
+	case MATH_STYLE_ESCAPED:
+		if block {
+			return fmt.Sprintf("$$%s$$", escapeMarkdown(content)), nil
+		}
+		return fmt.Sprintf("$%s$", escapeMarkdown(content)), nil
+	}
+	return "", newErrTransformf(ERR_KIND_UNEXPECTED, "unexpected math style: %s", style)
+}
+
+// $ 以外の ASCII の記号をバックスラッシュでエスケープする.
+// CommonMark ではエスケープされた記号はそのまま出力される.
+func escapeMarkdown(s string) string {
+	b := new(strings.Builder)
+	for _, r := range s {
+		if r < 128 && r != '$' && strings.ContainsRune("!\"#%&'()*+,-./:;<=>?@[\\]^_`{|}~", r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// 本文に数式が含まれているかどうか
+func NewMathFinder(found *bool) *Converter {
+	c := new(Converter)
+
+	c.Set(MiddlewareAsIs(scan.ScanEscaped))
+	c.Set(MiddlewareAsIs(scan.ScanCodeBlock))
+	c.Set(MiddlewareAsIs(scan.ScanComment))
+	c.Set(MiddlewareAsIs(func(raw []rune, ptr int) (advance int) {
+		advance = scan.ScanMathBlock(raw, ptr)
+		if advance > 0 {
+			*found = true
+		}
+		return advance
+	}))
+	c.Set(MiddlewareAsIs(scan.ScanNormalComment))
+	c.Set(MiddlewareAsIs(func(raw []rune, ptr int) (advance int) {
+		advance = scan.ScanInlineMath(raw, ptr)
+		if advance > 0 {
+			*found = true
+		}
+		return advance
+	}))
+	c.Set(MiddlewareAsIs(scan.ScanInlineCode))
+	c.Set(TransformNone)
+	return c
+}
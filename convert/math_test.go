@@ -0,0 +1,76 @@
+package convert
+
+import "testing"
+
+func TestMathConverter(t *testing.T) {
+	cases := []struct {
+		name  string
+		style string
+		raw   []rune
+		want  []rune
+	}{
+		{
+			name:  "latex",
+			style: MATH_STYLE_LATEX,
+			raw:   []rune("inline $a_1 + b_1$ and\n$$\n\\sum_{i} x_i\n$$\n"),
+			want:  []rune("inline \\(a_1 + b_1\\) and\n\\[\n\\sum_{i} x_i\n\\]\n"),
+		},
+		{
+			name:  "hugo",
+			style: MATH_STYLE_HUGO,
+			raw:   []rune("$x_1$\n$$y$$"),
+			want:  []rune("{{< math >}}$x_1${{< /math >}}\n{{< math >}}$$y$${{< /math >}}"),
+		},
+		{
+			name:  "html",
+			style: MATH_STYLE_HTML,
+			raw:   []rune("$a<b_1$\n\n$$\na<b\n$$\n"),
+			want:  []rune("<span class=\"math\">\\\\\\(a\\<b\\_1\\\\\\)</span>\n\n<div class=\"math\">\\[\na&lt;b\n\\]</div>\n"),
+		},
+		{
+			name:  "escaped",
+			style: MATH_STYLE_ESCAPED,
+			raw:   []rune("$x_1 * y_{2}$"),
+			want:  []rune("$x\\_1 \\* y\\_\\{2\\}$"),
+		},
+		{
+			name:  "code and escaped dollars",
+			style: MATH_STYLE_LATEX,
+			raw:   []rune("`$x$` \\$5 and \\$6\n```\n$$y$$\n```\n"),
+			want:  []rune("`$x$` \\$5 and \\$6\n```\n$$y$$\n```\n"),
+		},
+	}
+
+	for _, tt := range cases {
+		got, err := NewMathConverter(tt.style).Convert(tt.raw)
+		if err != nil {
+			t.Fatalf("[FATAL | %s] unexpected error ocurred: %v", tt.name, err)
+		}
+		if string(got) != string(tt.want) {
+			t.Errorf("[ERROR | %s]\n\t got: %q\n\twant: %q", tt.name, string(got), string(tt.want))
+		}
+	}
+}
+
+func TestMathFinder(t *testing.T) {
+	cases := []struct {
+		name string
+		raw  []rune
+		want bool
+	}{
+		{name: "inline", raw: []rune("a $x$ b"), want: true},
+		{name: "block", raw: []rune("$$\nx\n$$\n"), want: true},
+		{name: "code", raw: []rune("`$x$`\n```\n$$x$$\n```\n"), want: false},
+		{name: "price", raw: []rune("costs $5 and $ 6"), want: false},
+	}
+
+	for _, tt := range cases {
+		got := false
+		if _, err := NewMathFinder(&got).Convert(tt.raw); err != nil {
+			t.Fatalf("[FATAL | %s] unexpected error ocurred: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("[ERROR | %s] got: %v, want: %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package convert
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/qawatake/obsdconv/scan"
+)
+
+const (
+	MORE_MARKER          = "<!--more-->"
+	DEFAULT_SUMMARY_LEN  = 120
+	WORDS_PER_MINUTE     = 200 // 英語など空白で区切る言語
+	CJK_CHARS_PER_MINUTE = 500 // 日本語など
+)
+
+// 要約や単語数のために本文を平文にする.
+// リンクは表示名にし, タグ (タグのページへのリンクを含む), コード, 数式, コメント, 画像, 脚注の参照は取り除く.
+func NewPlainTextConverter() *Converter {
+	c := new(Converter)
+
+	remove := func(scanner func(raw []rune, ptr int) (advance int)) TransformerFunc {
+		return func(raw []rune, ptr int) (advance int, tobewritten []rune, err error) {
+			return scanner(raw, ptr), nil, nil
+		}
+	}
+
+	c.Set(func(raw []rune, ptr int) (advance int, tobewritten []rune, err error) {
+		advance = scan.ScanEscaped(raw, ptr)
+		if advance == 0 {
+			return 0, nil, nil
+		}
+		return advance, raw[ptr+1 : ptr+advance], nil
+	})
+	c.Set(remove(scan.ScanCodeBlock))
+	c.Set(remove(scan.ScanComment))
+	c.Set(remove(scan.ScanMathBlock))
+	c.Set(remove(scan.ScanNormalComment))
+	c.Set(remove(func(raw []rune, ptr int) (advance int) {
+		advance, _, _, _ = scan.ScanImageLink(raw, ptr)
+		return advance
+	}))
+	c.Set(remove(func(raw []rune, ptr int) (advance int) {
+		advance, _ = scan.ScanEmbeds(raw, ptr)
+		return advance
+	}))
+	// -linktag で作ったタグのリンクもタグとして取り除く
+	c.Set(remove(func(raw []rune, ptr int) (advance int) {
+		advance, displayName, _, _ := scan.ScanExternalLink(raw, ptr)
+		if advance == 0 {
+			return 0
+		}
+		if n, _ := scan.ScanTag([]rune(displayName), 0); n == 0 || n != len([]rune(displayName)) {
+			return 0
+		}
+		return advance
+	}))
+	c.Set(TransformExternalLinkToPlain)
+	c.Set(TransformInternalLinkToPlain)
+	c.Set(remove(func(raw []rune, ptr int) (advance int) {
+		advance, _ = scan.ScanFootnoteRef(raw, ptr)
+		return advance
+	}))
+	c.Set(remove(scan.ScanInlineMath))
+	c.Set(remove(scan.ScanInlineCode))
+	// 見出しは先頭の # だけを除き, 残りは本文と同じように扱う
+	c.Set(func(raw []rune, ptr int) (advance int, tobewritten []rune, err error) {
+		if n, level, _ := scan.ScanHeader(raw, ptr); n == 0 {
+			return 0, nil, nil
+		} else {
+			advance = level
+		}
+		for ptr+advance < len(raw) && (raw[ptr+advance] == ' ' || raw[ptr+advance] == '\t') {
+			advance++
+		}
+		return advance, nil, nil
+	})
+	c.Set(remove(func(raw []rune, ptr int) (advance int) {
+		advance, _ = scan.ScanTag(raw, ptr)
+		return advance
+	}))
+	c.Set(TransformNone)
+	return c
+}
+
+var (
+	plainLinePrefix = regexp.MustCompile(`^((>|[-*+]|\d+[.)]|\[.\])[ \t]+)+`)
+	plainHTMLTag    = regexp.MustCompile(`</?[a-zA-Z][^>\n]*>`)
+	plainEmphasis   = strings.NewReplacer("**", "", "__", "", "~~", "", "==", "")
+)
+
+// 引用やリストの記号, HTML タグ, 強調の記号を除き, 空白を 1 つにまとめる
+func PlainText(raw []rune) (text string, err error) {
+	plain, err := NewPlainTextConverter().Convert(raw)
+	if err != nil {
+		return "", err
+	}
+	lines := strings.Split(string(plain), "\n")
+	words := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		line = plainLinePrefix.ReplaceAllString(line, "")
+		line = plainHTMLTag.ReplaceAllString(line, "")
+		line = plainEmphasis.Replace(line)
+		words = append(words, strings.Fields(line)...)
+	}
+	return strings.Join(words, " "), nil
+}
+
+// <!--more--> より前の平文. なければ先頭の length 文字. 切り詰めた場合は … を付ける.
+// H1 は title と重なるので, -rmh1 と同じように取り除いてから要約する.
+func Summarize(raw []rune, length int) (summary string, err error) {
+	raw, err = NewH1Remover().Convert(raw)
+	if err != nil {
+		return "", err
+	}
+	more := -1
+	c := new(Converter)
+	c.Set(MiddlewareAsIs(scan.ScanEscaped))
+	c.Set(MiddlewareAsIs(scan.ScanCodeBlock))
+	c.Set(MiddlewareAsIs(scan.ScanComment))
+	c.Set(MiddlewareAsIs(scan.ScanMathBlock))
+	c.Set(func(raw []rune, ptr int) (advance int, tobewritten []rune, err error) {
+		advance = scan.ScanNormalComment(raw, ptr)
+		if advance > 0 && more < 0 && strings.ToLower(strings.Join(strings.Fields(string(raw[ptr:ptr+advance])), "")) == MORE_MARKER {
+			more = ptr
+		}
+		return advance, nil, nil
+	})
+	c.Set(MiddlewareAsIs(scan.ScanInlineCode))
+	c.Set(TransformNone)
+	if _, err := c.Convert(raw); err != nil {
+		return "", err
+	}
+	if more >= 0 {
+		return PlainText(raw[:more])
+	}
+
+	text, err := PlainText(raw)
+	if err != nil {
+		return "", err
+	}
+	return truncateText(text, length), nil
+}
+
+// 単語の途中では切らない. ただし CJK の文字の間では切ってよい.
+func truncateText(text string, length int) string {
+	runes := []rune(text)
+	if len(runes) <= length {
+		return text
+	}
+	cut := length
+	if !isCJK(runes[cut-1]) && !unicode.IsSpace(runes[cut]) {
+		for back := cut - 1; back > 0; back-- {
+			if unicode.IsSpace(runes[back]) || isCJK(runes[back]) {
+				cut = back + 1
+				break
+			}
+		}
+	}
+	return strings.TrimRightFunc(string(runes[:cut]), func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	}) + "…"
+}
+
+// CJK の文字は 1 文字を 1 語として数える. それ以外は空白などで区切られた文字と数字の並びを 1 語とする.
+func CountWords(text string) (words int, cjk int) {
+	inWord := false
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			cjk++
+			inWord = false
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			if !inWord {
+				words++
+			}
+			inWord = true
+		case r == '\'' || r == '’' || r == '-':
+			// don't, well-known は 1 語
+		default:
+			inWord = false
+		}
+	}
+	return words, cjk
+}
+
+// 分単位. 空でなければ少なくとも 1 分.
+func ReadingTime(words int, cjk int) int {
+	if words == 0 && cjk == 0 {
+		return 0
+	}
+	minutes := float64(words)/WORDS_PER_MINUTE + float64(cjk)/CJK_CHARS_PER_MINUTE
+	return int(math.Max(1, math.Ceil(minutes)))
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
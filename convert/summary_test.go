@@ -0,0 +1,139 @@
+package convert
+
+import "testing"
+
+func TestPlainText(t *testing.T) {
+	cases := []struct {
+		name string
+		raw  []rune
+		want string
+	}{
+		{
+			name: "links, tags and emphasis",
+			raw:  []rune("# Title #tag\n\nSee [[note|the note]] and [Go](https://go.dev) **now**. [#linked](/tags/linked/)\n"),
+			want: "Title See the note and Go now.",
+		},
+		{
+			name: "code, math, comments and images",
+			raw:  []rune("text `code` $x$\n```\ncode block\n```\n$$\ny\n$$\n%%hidden%% <!-- html -->![img](a.png)![[embed.png]] end[^1]\n"),
+			want: "text end",
+		},
+		{
+			name: "lists and quotes",
+			raw:  []rune("> quote\n- [ ] task\n1. one\n<span>html</span>\n"),
+			want: "quote task one html",
+		},
+	}
+
+	for _, tt := range cases {
+		got, err := PlainText(tt.raw)
+		if err != nil {
+			t.Fatalf("[FATAL | %s] unexpected error occurred: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("[ERROR | %s]\n\t got: %q\n\twant: %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	cases := []struct {
+		name   string
+		raw    []rune
+		length int
+		want   string
+	}{
+		{
+			name:   "short",
+			raw:    []rune("Hello world."),
+			length: 20,
+			want:   "Hello world.",
+		},
+		{
+			name:   "truncated at word boundary",
+			raw:    []rune("The quick brown fox jumps."),
+			length: 12,
+			want:   "The quick…",
+		},
+		{
+			name:   "cjk",
+			raw:    []rune("今日は良い天気です。散歩に行きます。"),
+			length: 10,
+			want:   "今日は良い天気です…",
+		},
+		{
+			name:   "more marker",
+			raw:    []rune("# Title\nIntro [[a|link]].\n<!--more-->\nRest."),
+			length: 3,
+			want:   "Intro link.",
+		},
+		{
+			name:   "h1 skipped",
+			raw:    []rune("# Title\n\n## Section\nBody text."),
+			length: 30,
+			want:   "Section Body text.",
+		},
+		{
+			name:   "more marker in code block",
+			raw:    []rune("```\n<!--more-->\n```\nabc def"),
+			length: 5,
+			want:   "abc…",
+		},
+	}
+
+	for _, tt := range cases {
+		got, err := Summarize(tt.raw, tt.length)
+		if err != nil {
+			t.Fatalf("[FATAL | %s] unexpected error occurred: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("[ERROR | %s]\n\t got: %q\n\twant: %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCountWords(t *testing.T) {
+	cases := []struct {
+		name      string
+		text      string
+		wantWords int
+		wantCJK   int
+		wantMin   int
+	}{
+		{
+			name:      "english",
+			text:      "Don't stop: it's a well-known fact, 42 times.",
+			wantWords: 8,
+			wantMin:   1,
+		},
+		{
+			name:    "japanese",
+			text:    "日本語の文章、カタカナ。",
+			wantCJK: 10,
+			wantMin: 1,
+		},
+		{
+			name:      "mixed",
+			text:      "Go言語 is fun",
+			wantWords: 3,
+			wantCJK:   2,
+			wantMin:   1,
+		},
+		{
+			name: "empty",
+		},
+	}
+
+	for _, tt := range cases {
+		words, cjk := CountWords(tt.text)
+		if words != tt.wantWords || cjk != tt.wantCJK {
+			t.Errorf("[ERROR | %s] got: (%d, %d), want: (%d, %d)", tt.name, words, cjk, tt.wantWords, tt.wantCJK)
+		}
+		if got := ReadingTime(words, cjk); got != tt.wantMin {
+			t.Errorf("[ERROR | %s] got reading time: %d, want: %d", tt.name, got, tt.wantMin)
+		}
+	}
+	if got := ReadingTime(400, 500); got != 3 {
+		t.Errorf("[ERROR | reading time] got: %d, want: 3", got)
+	}
+}
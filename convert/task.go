@@ -0,0 +1,132 @@
+package convert
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/qawatake/obsdconv/scan"
+)
+
+const (
+	TASK_STATUS_OPEN        = "open"
+	TASK_STATUS_IN_PROGRESS = "in_progress"
+	TASK_STATUS_DONE        = "done"
+	TASK_STATUS_CANCELLED   = "cancelled"
+	TASK_STATUS_OTHER       = "other"
+)
+
+type Task struct {
+	Status string
+	Mark   rune // [ ] の中の文字
+	Text   string
+	Line   int
+	// Obsidian Tasks プラグインの絵文字で指定されたフィールド
+	Due        string
+	Scheduled  string
+	Start      string
+	Created    string
+	Done       string
+	Cancelled  string
+	Recurrence string
+	Priority   string
+	Tags       []string
+}
+
+// 未完了のタスクかどうか. キャンセルされたものは含まない.
+func (t Task) Open() bool {
+	return t.Status == TASK_STATUS_OPEN || t.Status == TASK_STATUS_IN_PROGRESS
+}
+
+// 本文中のタスク - [ ] task を集める
+func NewTaskFinder(tasks *[]Task) *Converter {
+	c := new(Converter)
+
+	c.Set(MiddlewareAsIs(scan.ScanEscaped))
+	c.Set(MiddlewareAsIs(scan.ScanCodeBlock))
+	c.Set(MiddlewareAsIs(scan.ScanComment))
+	c.Set(MiddlewareAsIs(scan.ScanMathBlock))
+	c.Set(MiddlewareAsIs(scan.ScanNormalComment))
+	c.Set(MiddlewareAsIs(func(raw []rune, ptr int) (advance int) {
+		advance, mark, text := scan.ScanTask(raw, ptr)
+		if advance > 0 {
+			*tasks = append(*tasks, NewTask(mark, text, currentLine(raw, ptr)))
+		}
+		return advance
+	}))
+	c.Set(TransformNone)
+	return c
+}
+
+var taskDateFields = []struct {
+	emoji string
+	set   func(t *Task, date string)
+}{
+	{"📅", func(t *Task, date string) { t.Due = date }},
+	{"⏳", func(t *Task, date string) { t.Scheduled = date }},
+	{"🛫", func(t *Task, date string) { t.Start = date }},
+	{"➕", func(t *Task, date string) { t.Created = date }},
+	{"✅", func(t *Task, date string) { t.Done = date }},
+	{"❌", func(t *Task, date string) { t.Cancelled = date }},
+}
+
+var taskPriorities = []struct {
+	emoji    string
+	priority string
+}{
+	{"🔺", "highest"},
+	{"⏫", "high"},
+	{"🔼", "medium"},
+	{"🔽", "low"},
+	{"⏬", "lowest"},
+}
+
+var taskDatePattern = regexp.MustCompile(`^\x{FE0F}?\s*([0-9]{4}-[0-9]{2}-[0-9]{2})`)
+
+// 🔁 の後ろは次の絵文字かタグまで
+var taskRecurrencePattern = regexp.MustCompile(`🔁\x{FE0F}?\s*([^📅⏳🛫➕✅❌🔺⏫🔼🔽⏬#]*)`)
+
+func NewTask(mark rune, text string, line int) Task {
+	t := Task{Mark: mark, Text: text, Line: line}
+	switch mark {
+	case ' ':
+		t.Status = TASK_STATUS_OPEN
+	case '/':
+		t.Status = TASK_STATUS_IN_PROGRESS
+	case 'x', 'X':
+		t.Status = TASK_STATUS_DONE
+	case '-':
+		t.Status = TASK_STATUS_CANCELLED
+	default:
+		t.Status = TASK_STATUS_OTHER
+	}
+
+	for _, f := range taskDateFields {
+		pos := strings.Index(text, f.emoji)
+		if pos < 0 {
+			continue
+		}
+		if m := taskDatePattern.FindStringSubmatch(text[pos+len(f.emoji):]); m != nil {
+			f.set(&t, m[1])
+		}
+	}
+	for _, p := range taskPriorities {
+		if strings.Contains(text, p.emoji) {
+			t.Priority = p.priority
+			break
+		}
+	}
+	if m := taskRecurrencePattern.FindStringSubmatch(text); m != nil {
+		t.Recurrence = strings.TrimSpace(m[1])
+	}
+
+	t.Tags = make([]string, 0)
+	rns := []rune(text)
+	for ptr := 0; ptr < len(rns); ptr++ {
+		advance, tag := scan.ScanTag(rns, ptr)
+		if advance > 0 {
+			t.Tags = append(t.Tags, tag)
+			ptr += advance - 1
+		}
+	}
+	return t
+}
@@ -0,0 +1,64 @@
+package convert
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTaskFinder(t *testing.T) {
+	cases := []struct {
+		name string
+		raw  []rune
+		want []Task
+	}{
+		{
+			name: "tasks plugin fields",
+			raw:  []rune("# Todo\n\n- [ ] write #project/a ⏫ 🔁 every week 📅 2026-11-01 ⏳ 2026-10-30\n- [x] read ✅ 2026-10-01\n"),
+			want: []Task{
+				{
+					Status:     TASK_STATUS_OPEN,
+					Mark:       ' ',
+					Text:       "write #project/a ⏫ 🔁 every week 📅 2026-11-01 ⏳ 2026-10-30",
+					Line:       3,
+					Due:        "2026-11-01",
+					Scheduled:  "2026-10-30",
+					Recurrence: "every week",
+					Priority:   "high",
+					Tags:       []string{"project/a"},
+				},
+				{
+					Status: TASK_STATUS_DONE,
+					Mark:   'x',
+					Text:   "read ✅ 2026-10-01",
+					Line:   4,
+					Done:   "2026-10-01",
+					Tags:   []string{},
+				},
+			},
+		},
+		{
+			name: "other statuses",
+			raw:  []rune("1. [-] dropped\n   - [/] doing\n   - [?] question"),
+			want: []Task{
+				{Status: TASK_STATUS_CANCELLED, Mark: '-', Text: "dropped", Line: 1, Tags: []string{}},
+				{Status: TASK_STATUS_IN_PROGRESS, Mark: '/', Text: "doing", Line: 2, Tags: []string{}},
+				{Status: TASK_STATUS_OTHER, Mark: '?', Text: "question", Line: 3, Tags: []string{}},
+			},
+		},
+		{
+			name: "code blocks and comments",
+			raw:  []rune("```\n- [ ] code\n```\n%%\n- [ ] comment\n%%\n<!--\n- [ ] html\n-->\n"),
+			want: []Task{},
+		},
+	}
+
+	for _, tt := range cases {
+		got := make([]Task, 0)
+		if _, err := NewTaskFinder(&got).Convert(tt.raw); err != nil {
+			t.Fatalf("[FATAL | %s] unexpected error ocurred: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("[ERROR | %s]\n\t got: %+v\n\twant: %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package convert
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/qawatake/obsdconv/scan"
+)
+
+const (
+	TOC_MARKER            = "[TOC]"
+	DEFAULT_TOC_MIN_LEVEL = 2
+	DEFAULT_TOC_MAX_LEVEL = 6
+)
+
+// [TOC] と %%toc%% の行を replacement に置き換える.
+// found != nil のとき, 置き換えたかどうかを found に書き込む.
+func NewTOCMarkerReplacer(replacement []rune, found *bool) *Converter {
+	c := new(Converter)
+
+	c.Set(MiddlewareAsIs(scan.ScanEscaped))
+	c.Set(MiddlewareAsIs(scan.ScanCodeBlock))
+	// %%toc%% はコメントでもあるので ScanComment より先に見る
+	c.Set(func(raw []rune, ptr int) (advance int, tobewritten []rune, err error) {
+		advance = scan.ScanTOCMarker(raw, ptr)
+		if advance == 0 {
+			return 0, nil, nil
+		}
+		if found != nil {
+			*found = true
+		}
+		return advance, replacement, nil
+	})
+	c.Set(MiddlewareAsIs(scan.ScanComment))
+	c.Set(MiddlewareAsIs(scan.ScanMathBlock))
+	c.Set(MiddlewareAsIs(scan.ScanNormalComment))
+	c.Set(MiddlewareAsIs(scan.ScanInlineMath))
+	c.Set(MiddlewareAsIs(scan.ScanInlineCode))
+	c.Set(TransformNone)
+	return c
+}
+
+type TOCEntry struct {
+	Level  int
+	Text   string
+	Anchor string
+}
+
+// 見出しの閉じの ### は見出しの文字列に含めない
+var headingClosingSequence = regexp.MustCompile(`(^|[ \t]+)#+$`)
+
+// 見出しとそのアンカーの一覧. リンクは表示名にしてからアンカーを作る.
+// 同じアンカーが続く場合は Hugo や markdown-it-anchor と同じく -1, -2 を付ける.
+func FindTOCEntries(raw []rune, anchorFormattingStyle string) (entries []TOCEntry, err error) {
+	plain, err := NewLinkPlainConverter().Convert(raw)
+	if err != nil {
+		return nil, err
+	}
+	headings := make([]Heading, 0)
+	if _, err := NewHeadingFinder(&headings).Convert(plain); err != nil {
+		return nil, err
+	}
+	entries = make([]TOCEntry, 0, len(headings))
+	used := make(map[string]int)
+	for _, h := range headings {
+		if h.Level > 6 {
+			continue
+		}
+		text := strings.TrimSpace(headingClosingSequence.ReplaceAllString(h.Text, ""))
+		anchor := formatAnchorByStyle(text, anchorFormattingStyle)
+		if n := used[anchor]; n > 0 {
+			used[anchor]++
+			anchor = fmt.Sprintf("%s-%d", anchor, n)
+		} else {
+			used[anchor] = 1
+		}
+		entries = append(entries, TOCEntry{Level: h.Level, Text: text, Anchor: anchor})
+	}
+	return entries, nil
+}
+
+// minLevel から maxLevel までの見出しを入れ子のリストにする
+func RenderTOC(entries []TOCEntry, minLevel int, maxLevel int) string {
+	top := 0
+	for _, e := range entries {
+		if e.Level >= minLevel && e.Level <= maxLevel && (top == 0 || e.Level < top) {
+			top = e.Level
+		}
+	}
+	lines := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.Level < minLevel || e.Level > maxLevel {
+			continue
+		}
+		text := strings.NewReplacer("[", "\\[", "]", "\\]").Replace(e.Text)
+		lines = append(lines, fmt.Sprintf("%s- [%s](#%s)", strings.Repeat("  ", e.Level-top), text, e.Anchor))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// [TOC] と %%toc%% の行を目次に置き換える.
+// afterH1 のとき, それらがなければ最初の H1 の後ろ (H1 がなければ先頭) に目次を入れる.
+// 目次に載せる見出しがなければマーカーを消すだけ.
+func InsertTOC(raw []rune, anchorFormattingStyle string, minLevel int, maxLevel int, afterH1 bool) (output []rune, err error) {
+	entries, err := FindTOCEntries(raw, anchorFormattingStyle)
+	if err != nil {
+		return nil, err
+	}
+	toc := RenderTOC(entries, minLevel, maxLevel)
+
+	found := false
+	output, err = NewTOCMarkerReplacer([]rune(toc), &found).Convert(raw)
+	if err != nil {
+		return nil, err
+	}
+	if found || !afterH1 || toc == "" {
+		return output, nil
+	}
+
+	inserted := false
+	c := new(Converter)
+	c.Set(MiddlewareAsIs(scan.ScanEscaped))
+	c.Set(MiddlewareAsIs(scan.ScanCodeBlock))
+	c.Set(MiddlewareAsIs(scan.ScanComment))
+	c.Set(MiddlewareAsIs(scan.ScanMathBlock))
+	c.Set(MiddlewareAsIs(scan.ScanNormalComment))
+	c.Set(MiddlewareAsIs(scan.ScanInlineMath))
+	c.Set(MiddlewareAsIs(scan.ScanInlineCode))
+	c.Set(func(raw []rune, ptr int) (advance int, tobewritten []rune, err error) {
+		advance, level, _ := scan.ScanHeader(raw, ptr)
+		if advance == 0 || level != 1 || inserted {
+			return advance, raw[ptr : ptr+advance], nil
+		}
+		inserted = true
+		tobewritten = append([]rune{}, raw[ptr:ptr+advance]...)
+		if raw[ptr+advance-1] != '\n' {
+			tobewritten = append(tobewritten, '\n')
+		}
+		tobewritten = append(tobewritten, []rune("\n"+toc+"\n")...)
+		return advance, tobewritten, nil
+	})
+	c.Set(MiddlewareAsIs(func(raw []rune, ptr int) (advance int) {
+		advance, _ = scan.ScanTag(raw, ptr)
+		return advance
+	}))
+	c.Set(TransformNone)
+	output, err = c.Convert(output)
+	if err != nil {
+		return nil, err
+	}
+	if !inserted {
+		output = append([]rune(toc+"\n\n"), output...)
+	}
+	return output, nil
+}
@@ -0,0 +1,76 @@
+package convert
+
+import "testing"
+
+func TestInsertTOC(t *testing.T) {
+	cases := []struct {
+		name     string
+		style    string
+		minLevel int
+		maxLevel int
+		afterH1  bool
+		raw      []rune
+		want     []rune
+	}{
+		{
+			name:     "marker",
+			style:    FORMAT_ANCHOR_HUGO,
+			minLevel: 2,
+			maxLevel: 6,
+			raw:      []rune("# Title\n\n[TOC]\n\n## Intro\n### Why [[Go]]?\n## Intro\n"),
+			want:     []rune("# Title\n\n- [Intro](#intro)\n  - [Why Go?](#why-go)\n- [Intro](#intro-1)\n\n## Intro\n### Why [[Go]]?\n## Intro\n"),
+		},
+		{
+			name:     "comment marker and max level",
+			style:    FORMAT_ANCHOR_MARKDOWN_IT,
+			minLevel: 1,
+			maxLevel: 2,
+			raw:      []rune("%%toc%%\n# A B\n## C\n### D\n"),
+			want:     []rune("- [A B](#a-b)\n  - [C](#c)\n# A B\n## C\n### D\n"),
+		},
+		{
+			name:     "after h1",
+			style:    FORMAT_ANCHOR_HUGO,
+			minLevel: 2,
+			maxLevel: 6,
+			afterH1:  true,
+			raw:      []rune("# Title\ntext\n### Deep ###\n"),
+			want:     []rune("# Title\n\n- [Deep](#deep)\ntext\n### Deep ###\n"),
+		},
+		{
+			name:     "after h1 without h1",
+			style:    FORMAT_ANCHOR_HUGO,
+			minLevel: 2,
+			maxLevel: 6,
+			afterH1:  true,
+			raw:      []rune("## A\n"),
+			want:     []rune("- [A](#a)\n\n## A\n"),
+		},
+		{
+			name:     "headings in code blocks, math and comments",
+			style:    FORMAT_ANCHOR_HUGO,
+			minLevel: 1,
+			maxLevel: 6,
+			raw:      []rune("[TOC]\n```\n# code\n```\n$$\n# math\n$$\n%%\n# comment\n%%\n## A\n"),
+			want:     []rune("- [A](#a)\n```\n# code\n```\n$$\n# math\n$$\n%%\n# comment\n%%\n## A\n"),
+		},
+		{
+			name:     "marker in code block",
+			style:    FORMAT_ANCHOR_HUGO,
+			minLevel: 1,
+			maxLevel: 6,
+			raw:      []rune("```\n[TOC]\n```\n# A\n"),
+			want:     []rune("```\n[TOC]\n```\n# A\n"),
+		},
+	}
+
+	for _, tt := range cases {
+		got, err := InsertTOC(tt.raw, tt.style, tt.minLevel, tt.maxLevel, tt.afterH1)
+		if err != nil {
+			t.Fatalf("[FATAL | %s] unexpected error occurred: %v", tt.name, err)
+		}
+		if string(got) != string(tt.want) {
+			t.Errorf("[ERROR | %s]\n\t got: %q\n\twant: %q", tt.name, string(got), string(tt.want))
+		}
+	}
+}
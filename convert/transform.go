@@ -72,8 +72,46 @@ func TransformExternalLinkFunc(t ExternalLinkTransformer) TransformerFunc {
 	}
 }
 
-func defaultTransformExternalLinkFunc(db PathDB) TransformerFunc {
-	return TransformExternalLinkFunc(newExternalLinkTransformerImpl(db))
+func defaultTransformExternalLinkFunc(db PathDB, anchorFormattingStyle string, vaults map[string]PathDB) TransformerFunc {
+	return TransformExternalLinkFunc(newExternalLinkTransformerImpl(db, anchorFormattingStyle, vaults))
+}
+
+// [label]: ref の形式のリンク定義のグループについて, ref の部分だけを変換する
+func TransformExternalLinkVarDefGroupFunc(t ExternalLinkRefTransformer) TransformerFunc {
+	return func(raw []rune, ptr int) (advance int, tobewritten []rune, err error) {
+		advance, ptrs := scan.ScanExternalLinkVarDefGroup(raw, ptr)
+		if advance == 0 {
+			return 0, nil, nil
+		}
+
+		tobewritten = make([]rune, 0, advance)
+		for _, head := range ptrs {
+			adv, _, ref := scan.ScanExternalLinkVarDef(raw, head)
+			advHead, _ := scan.ScanExternalLinkHead(raw, head)
+			cur := head + advHead + 1 // : の直後
+			for raw[cur] == ' ' || raw[cur] == '\t' {
+				cur++
+			}
+			refTail := cur + len([]rune(ref)) // ref の直後
+
+			newref, err := t.TransformRef(ref)
+			if err != nil {
+				return 0, nil, errors.Wrap(err, "t.TransformRef failed")
+			}
+			// 空の ref ではリンク定義にならないので, 元の ref を残す
+			if newref == "" {
+				newref = ref
+			}
+			tobewritten = append(tobewritten, raw[head:cur]...)
+			tobewritten = append(tobewritten, []rune(newref)...)
+			tobewritten = append(tobewritten, raw[refTail:head+adv]...)
+		}
+		return advance, tobewritten, nil
+	}
+}
+
+func defaultTransformExternalLinkVarDefGroupFunc(db PathDB, anchorFormattingStyle string, vaults map[string]PathDB) TransformerFunc {
+	return TransformExternalLinkVarDefGroupFunc(newExternalLinkTransformerImpl(db, anchorFormattingStyle, vaults))
 }
 
 func TransformInternalLinkToPlain(raw []rune, ptr int) (advance int, tobewritten []rune, err error) {
@@ -158,12 +196,7 @@ func (t *InternalLinkTransformerImpl) TransformInternalLink(content string) (ext
 	if fragments == nil {
 		ref = path
 	} else {
-		var anchor string
-		if t.anchorFormattingStyle == FORMAT_ANCHOR_HUGO {
-			anchor = formatAnchor(fragments[len(fragments)-1])
-		} else if t.anchorFormattingStyle == FORMAT_ANCHOR_MARKDOWN_IT {
-			anchor = formatAnchorByMarkdownItAnchorRule(fragments[len(fragments)-1])
-		}
+		anchor := formatAnchorByStyle(fragments[len(fragments)-1], t.anchorFormattingStyle)
 		ref = path + "#" + anchor
 	}
 
@@ -223,17 +256,37 @@ type ExternalLinkTransformer interface {
 	TransformExternalLink(displayName, ref string, title string) (externalLink string, err error)
 }
 
+type ExternalLinkRefTransformer interface {
+	TransformRef(ref string) (newref string, err error)
+}
+
 type ExternalLinkTransformerImpl struct {
 	PathDB
+	anchorFormattingStyle string
+	// Obsidian URI の vault 名ごとの PathDB.
+	// 登録されていない vault 名の場合は埋め込みの PathDB を使う.
+	vaults map[string]PathDB
 }
 
-func newExternalLinkTransformerImpl(db PathDB) *ExternalLinkTransformerImpl {
+func newExternalLinkTransformerImpl(db PathDB, anchorFormattingStyle string, vaults map[string]PathDB) *ExternalLinkTransformerImpl {
 	return &ExternalLinkTransformerImpl{
-		PathDB: db,
+		PathDB:                db,
+		anchorFormattingStyle: anchorFormattingStyle,
+		vaults:                vaults,
 	}
 }
 
 func (t *ExternalLinkTransformerImpl) TransformExternalLink(displayName, ref string, title string) (externalLink string, err error) {
+	newref, err := t.TransformRef(ref)
+	if err != nil {
+		return "", err
+	}
+	return buildExternalLink(displayName, newref, title), nil
+}
+
+// リンク先 (ref) だけを変換する.
+// [label]: ref の形式のリンク定義にも使う.
+func (t *ExternalLinkTransformerImpl) TransformRef(ref string) (newref string, err error) {
 	u, err := url.Parse(ref)
 	if err != nil {
 		return "", newErrTransformf(ERR_KIND_UNEXPECTED, "url.Parse failed: %v", err)
@@ -241,51 +294,66 @@ func (t *ExternalLinkTransformerImpl) TransformExternalLink(displayName, ref str
 
 	// ref = 通常のリンク
 	if (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" {
-		if title == "" {
-			return fmt.Sprintf("[%s](%s)", displayName, ref), nil
-		} else {
-			return fmt.Sprintf("[%s](%s \"%s\")", displayName, ref, title), nil
-		}
+		return ref, nil
 	}
 
 	// ref = obsidian URI (obsidian://open?...)
-	// ignore vault query (?vault=...) and path query (?path=...)
-	// resolve path by using file query (?file=...) and PathDB
+	// resolve path by using file query (?file=...) or path query (?path=...) and PathDB of the vault (?vault=...)
 	if u.Scheme == "obsidian" && u.Host == "open" {
 		q := u.Query()
-		fileId := q.Get("file")
-		if fileId == "" {
+		db := t.pathDBOf(q.Get("vault"))
+		var path string
+		if fileId := q.Get("file"); fileId != "" {
+			path, err = db.Get(fileId)
+		} else if p := q.Get("path"); p != "" {
+			path, err = getByPath(db, p)
+		} else {
 			return "", newErrTransformf(ERR_KIND_NO_REF_SPECIFIED_IN_OBSIDIAN_URL, "no ref file specified in obsidian url: %s", ref)
 		}
-		path, err := t.Get(fileId)
 		if err != nil {
 			return "", errors.Wrap(err, "PathDB.Get failed")
 		}
-		if title == "" {
-			return fmt.Sprintf("[%s](%s)", displayName, path), nil
-		} else {
-			return fmt.Sprintf("[%s](%s \"%s\")", displayName, path, title), nil
-		}
+		return path, nil
 	}
 
-	// ref = obsidian URI (obsidian://vault/my_vault/my_note)
-	// ignore vault parameter
-	// resolve path by using file parameter and PathDB
+	// ref = obsidian URI (obsidian://vault/my_vault/path/to/my_note)
+	// resolve path by using the path following the vault name and PathDB of the vault
 	if u.Scheme == "obsidian" && u.Host == "vault" {
-		segments := strings.Split(u.Path, "/")
-		if len(segments) != 3 {
+		segments := strings.Split(strings.TrimPrefix(u.Path, "/"), "/")
+		if len(segments) < 2 || segments[0] == "" || segments[len(segments)-1] == "" {
 			return "", newErrTransformf(ERR_KIND_INVALID_SHORTHAND_OBSIDIAN_URL, "invalid shorthand obsidian url: %s", ref)
 		}
-		fileId := segments[2]
-		path, err := t.Get(fileId)
+		path, err := t.pathDBOf(segments[0]).Get(strings.Join(segments[1:], "/"))
 		if err != nil {
 			return "", errors.Wrap(err, "PathDB.Get failed")
 		}
-		if title == "" {
-			return fmt.Sprintf("[%s](%s)", displayName, path), nil
+		return path, nil
+	}
+
+	// ref = obsidian URI of Advanced URI plugin (obsidian://advanced-uri?...)
+	// resolve path by using filepath query (?filepath=...) or filename query (?filename=...)
+	// and map heading query (?heading=...) and block query (?block=...) to anchors
+	if u.Scheme == "obsidian" && u.Host == "advanced-uri" {
+		q := u.Query()
+		db := t.pathDBOf(q.Get("vault"))
+		var path string
+		if p := q.Get("filepath"); p != "" {
+			path, err = getByPath(db, p)
+		} else if fileId := q.Get("filename"); fileId != "" {
+			path, err = db.Get(fileId)
 		} else {
-			return fmt.Sprintf("[%s](%s \"%s\")", displayName, path, title), nil
+			return "", newErrTransformf(ERR_KIND_NO_REF_SPECIFIED_IN_OBSIDIAN_URL, "no ref file specified in obsidian url: %s", ref)
+		}
+		if err != nil {
+			return "", errors.Wrap(err, "PathDB.Get failed")
 		}
+		if heading := q.Get("heading"); heading != "" {
+			path += "#" + formatAnchorByStyle(heading, t.anchorFormattingStyle)
+		} else if block := q.Get("block"); block != "" {
+			// block id は [a-zA-Z0-9-] なのでそのまま使う
+			path += "#" + block
+		}
+		return path, nil
 	}
 
 	// ref = fileId
@@ -298,22 +366,54 @@ func (t *ExternalLinkTransformerImpl) TransformExternalLink(displayName, ref str
 		if err != nil {
 			return "", errors.Wrap(err, "PathDB.Get failed")
 		}
-		var newref string
 		if fragments == nil {
-			newref = path
-		} else {
-			newref = path + "#" + strings.Join(fragments, "#")
-		}
-		if title == "" {
-			return fmt.Sprintf("[%s](%s)", displayName, newref), nil
-		} else {
-			return fmt.Sprintf("[%s](%s \"%s\")", displayName, newref, title), nil
+			return path, nil
 		}
+		return path + "#" + strings.Join(fragments, "#"), nil
 	}
 
 	return "", newErrTransformf(ERR_KIND_UNEXPECTED_HREF, "unexpected href: %s", ref)
 }
 
+func (t *ExternalLinkTransformerImpl) pathDBOf(vault string) PathDB {
+	if db, ok := t.vaults[vault]; ok {
+		return db
+	}
+	return t.PathDB
+}
+
+// path query には vault の外からの絶対パスも入りうるので, 先頭のディレクトリを順に取り除きながら探す
+func getByPath(db PathDB, p string) (path string, err error) {
+	segments := strings.Split(strings.Trim(filepath.ToSlash(p), "/"), "/")
+	for i := 0; i < len(segments); i++ {
+		path, err = db.Get(strings.Join(segments[i:], "/"))
+		if e, ok := errors.Cause(err).(ErrTransform); ok && e.Kind() == ERR_KIND_PATH_NOT_FOUND {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if path != "" {
+			return path, nil
+		}
+	}
+	return "", err
+}
+
+func buildExternalLink(displayName string, ref string, title string) (externalLink string) {
+	if title == "" {
+		return fmt.Sprintf("[%s](%s)", displayName, ref)
+	}
+	return fmt.Sprintf("[%s](%s \"%s\")", displayName, ref, title)
+}
+
+func formatAnchorByStyle(rawAnchor string, anchorFormattingStyle string) (anchor string) {
+	if anchorFormattingStyle == FORMAT_ANCHOR_MARKDOWN_IT {
+		return formatAnchorByMarkdownItAnchorRule(rawAnchor)
+	}
+	return formatAnchor(rawAnchor)
+}
+
 func formatAnchor(rawAnchor string) (anchor string) {
 	loweredAnchor := strings.ToLower(rawAnchor)
 	rawRunes := []rune(loweredAnchor)
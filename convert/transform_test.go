@@ -3,6 +3,8 @@ package convert
 import (
 	"path/filepath"
 	"testing"
+
+	"github.com/pkg/errors"
 )
 
 func TestTransformExternalLink(t *testing.T) {
@@ -13,6 +15,7 @@ func TestTransformExternalLink(t *testing.T) {
 		displayName      string
 		ref              string
 		title            string
+		vaults           map[string]PathDB
 		wantExternalLink string
 	}{
 		{
@@ -72,11 +75,62 @@ func TestTransformExternalLink(t *testing.T) {
 			ref:              "obsidian://vault/my_vault/test",
 			wantExternalLink: "[shorthand](test.md)",
 		},
+		{
+			name:             "obsidian url with path query",
+			root:             "obsidianurl_path",
+			displayName:      "path",
+			ref:              "obsidian://open?vault=my_vault&path=other%2Ftest.md",
+			wantExternalLink: "[path](other/test.md)",
+		},
+		{
+			name:             "obsidian url with absolute path query",
+			root:             "obsidianurl_path",
+			displayName:      "absolute path",
+			ref:              "obsidian://open?path=%2FUsers%2Fme%2Fmy_vault%2Fother%2Ftest.md",
+			wantExternalLink: "[absolute path](other/test.md)",
+		},
+		{
+			name:             "shorthand format obsidianurl with nested folders",
+			root:             "shorthand_nested",
+			displayName:      "nested",
+			ref:              "obsidian://vault/my_vault/other/test",
+			wantExternalLink: "[nested](other/test.md)",
+		},
+		{
+			name:             "advanced uri with heading",
+			root:             "advanced_uri",
+			displayName:      "heading",
+			ref:              "obsidian://advanced-uri?vault=my_vault&filepath=notes%2Ftest.md&heading=My%20Section",
+			wantExternalLink: "[heading](notes/test.md#my-section)",
+		},
+		{
+			name:             "advanced uri with block",
+			root:             "advanced_uri",
+			displayName:      "block",
+			ref:              "obsidian://advanced-uri?vault=my_vault&filepath=notes%2Ftest.md&block=abc123",
+			wantExternalLink: "[block](notes/test.md#abc123)",
+		},
+		{
+			name:             "vault mapped to another root",
+			root:             "advanced_uri",
+			displayName:      "other vault",
+			ref:              "obsidian://open?vault=other&file=other",
+			vaults:           map[string]PathDB{"other": WrapForPrefixingPath("../other_vault/", NewPathDB(filepath.Join(testTransformExternalLinkRootDir, "other_vault")))},
+			wantExternalLink: "[other vault](../other_vault/notes/other.md)",
+		},
+		{
+			name:             "vault mapped to base url",
+			root:             "advanced_uri",
+			displayName:      "remote vault",
+			ref:              "obsidian://vault/blog/posts/my%20post",
+			vaults:           map[string]PathDB{"blog": NewPathDBForRemoteVault("https://example.com/")},
+			wantExternalLink: "[remote vault](https://example.com/posts/my%20post)",
+		},
 	}
 
 	for _, tt := range cases {
 		db := NewPathDB(filepath.Join(testTransformExternalLinkRootDir, tt.root))
-		transformer := &ExternalLinkTransformerImpl{PathDB: db}
+		transformer := &ExternalLinkTransformerImpl{PathDB: db, vaults: tt.vaults}
 		got, err := transformer.TransformExternalLink(tt.displayName, tt.ref, tt.title)
 		if err != nil {
 			t.Fatalf("[FATAL] | %v] unexpected error ocurred: %v", tt.name, err)
@@ -87,6 +141,70 @@ func TestTransformExternalLink(t *testing.T) {
 	}
 }
 
+func TestTransformExternalLinkVarDefGroup(t *testing.T) {
+	testTransformExternalLinkRootDir := filepath.Join("testdata", "transformexternallink")
+	cases := []struct {
+		name        string
+		root        string
+		strictref   bool
+		raw         []rune
+		wantAdvance int
+		want        []rune
+		wantErr     bool
+	}{
+		{
+			name:        "fileId with fragments",
+			root:        "fragments",
+			raw:         []rune("[label]: test#section\n[google]: https://google.com\ntext"),
+			wantAdvance: 51,
+			want:        []rune("[label]: test.md#section\n[google]: https://google.com\n"),
+		},
+		{
+			name:        "obsidian url",
+			root:        "obsidianurl",
+			raw:         []rune("[label]:\tobsidian://open?vault=obsidian&file=test  \n"),
+			wantAdvance: 52,
+			want:        []rune("[label]:\ttest.md  \n"),
+		},
+		{
+			name:        "not found",
+			root:        "fileid",
+			raw:         []rune("[label]: missing"),
+			wantAdvance: 16,
+			want:        []rune("[label]: missing"),
+		},
+		{
+			name:      "not found with strictref",
+			root:      "fileid",
+			strictref: true,
+			raw:       []rune("[label]: missing"),
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range cases {
+		db := NewPathDB(filepath.Join(testTransformExternalLinkRootDir, tt.root))
+		if tt.strictref {
+			db = WrapForReturningNotFoundPathError(db)
+		}
+		transform := TransformExternalLinkVarDefGroupFunc(newExternalLinkTransformerImpl(db, FORMAT_ANCHOR_HUGO, nil))
+		gotAdvance, got, err := transform(tt.raw, 0)
+		if tt.wantErr {
+			e, ok := errors.Cause(err).(ErrTransform)
+			if !ok || e.Kind() != ERR_KIND_PATH_NOT_FOUND {
+				t.Errorf("[ERROR | %v] expected error did not occur: %v", tt.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("[FATAL] | %v] unexpected error ocurred: %v", tt.name, err)
+		}
+		if gotAdvance != tt.wantAdvance || string(got) != string(tt.want) {
+			t.Errorf("[ERROR | %v]\n\t got: %d, %q\n\twant: %d, %q", tt.name, gotAdvance, string(got), tt.wantAdvance, string(tt.want))
+		}
+	}
+}
+
 func TestCurrentLine(t *testing.T) {
 	cases := []struct {
 		raw  []rune
@@ -1,26 +1,66 @@
 package main
 
 import (
+	"fmt"
+	"net/url"
+	"path/filepath"
 	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/qawatake/obsdconv/convert"
 	"github.com/qawatake/obsdconv/process"
+	"github.com/qawatake/obsdconv/scan"
 )
 
 type bodyConvAuxOutImpl struct {
-	title string
-	tags  map[string]struct{}
+	title   string
+	tags    map[string]struct{}
+	fields  []convert.InlineField
+	tasks   []convert.Task
+	math    bool
+	sources *titleSources
+	summary string
+	stats   *textStats
+	dates   *dateSources
+	path    string
 }
 
-func newBodyConvAuxOutImpl(title string, tags map[string]struct{}) *bodyConvAuxOutImpl {
+func newBodyConvAuxOutImpl(title string, tags map[string]struct{}, fields []convert.InlineField, tasks []convert.Task, math bool, sources *titleSources, summary string, stats *textStats, dates *dateSources, path string) *bodyConvAuxOutImpl {
 	return &bodyConvAuxOutImpl{
-		title: title,
-		tags:  tags,
+		title:   title,
+		tags:    tags,
+		fields:  fields,
+		tasks:   tasks,
+		math:    math,
+		sources: sources,
+		summary: summary,
+		stats:   stats,
+		dates:   dates,
+		path:    path,
 	}
 }
 
 type bodyConverterImpl struct {
+	bodyConverterOptions
+}
+
+// idb != nil のとき, 標準形式のリンクを Obsidian の形式に変換する (-import).
+// targetPrefix は vault から tgt までの相対パス.
+// calloutRenderer != nil のとき, callout を変換する.
+// highlightWrapper != "" のとき, ==highlight== を変換する.
+// pages != nil のとき, pages を使って dataview のクエリを描画する.
+// inlineField のとき, inline field を集めて front matter に渡す. rmInlineField のときは本文から削除する.
+// taskCount のとき, タスクを集めて front matter に渡す.
+// mathStyle != "" のとき, 数式を mathStyle の形式に書き換える.
+// setMath のとき, 数式が含まれているかどうかを front matter に渡す.
+// tagLinkTemplate != "" のとき, 本文のタグをタグのページへのリンクに書き換える. リンク先には tagRules を適用したタグを使う.
+// titleSources のとき, H1 がない場合の title の候補を集めて front matter に渡す.
+// headingShift != 0 のとき, 見出しのレベルを headingShift だけずらす. H1 の削除の後に行う.
+// toc のとき, [TOC] と %%toc%% を目次に置き換える. tocAfterH1 のときはそれらがなくても H1 の後ろに入れる.
+// summaryLength > 0 のとき, 変換後の本文の要約を front matter に渡す.
+// readingTime のとき, 変換後の本文の単語数と読了時間を front matter に渡す.
+// dates != nil のとき, date と lastmod の候補を集めて front matter に渡す.
+type bodyConverterOptions struct {
 	db                    convert.PathDB
 	cptag                 bool
 	rmtag                 bool
@@ -31,27 +71,46 @@ type bodyConverterImpl struct {
 	formatLink            bool
 	anchorFormattingStyle string
 	pathPrefixRemap       map[string]string
+	vaults                *vaultPathDBs
+	idb                   convert.FileIdDB
+	importtag             bool
+	vault                 string
+	targetPrefix          string
+	footnote              bool
+	calloutRenderer       convert.CalloutRenderer
+	calloutAliases        map[string]string
+	highlightWrapper      string
+	htmlComment           bool
+	pages                 []convert.Page
+	inlineField           bool
+	rmInlineField         bool
+	taskCount             bool
+	mathStyle             string
+	setMath               bool
+	tagLinkTemplate       string
+	tagRules              *tagRules
+	titleSources          bool
+	headingShift          int
+	toc                   bool
+	tocAfterH1            bool
+	tocMinLevel           int
+	tocMaxLevel           int
+	summaryLength         int
+	readingTime           bool
+	dates                 *fileDateIndex
 }
 
-func newBodyConverterImpl(db convert.PathDB, cptag bool, rmtag bool, cmmt bool, title bool, link bool, rmH1 bool, formatLink bool, anchorFormattingStyle string, pathPrefixRemap map[string]string) *bodyConverterImpl {
-	c := new(bodyConverterImpl)
-	c.db = db
-	c.cptag = cptag
-	c.rmtag = rmtag
-	c.cmmt = cmmt
-	c.title = title
-	c.link = link
-	c.rmH1 = rmH1
-	c.formatLink = formatLink
-	c.anchorFormattingStyle = anchorFormattingStyle
-	c.pathPrefixRemap = pathPrefixRemap
+func newBodyConverterImpl(opts bodyConverterOptions) *bodyConverterImpl {
+	c := &bodyConverterImpl{bodyConverterOptions: opts}
+	c.toc = opts.toc || opts.tocAfterH1
 	return c
 }
 
-func (c *bodyConverterImpl) ConvertBody(raw []rune, selfRelativePath string) (output []rune, aux process.BodyConvAuxOut, err error) {
+func (c *bodyConverterImpl) ConvertBody(raw []rune, frontMatter *process.FrontMatter, selfRelativePath string) (output []rune, aux process.BodyConvAuxOut, err error) {
 	output = raw
 	title := ""
 	tags := make(map[string]struct{})
+	var sources *titleSources
 
 	if c.cptag {
 		_, err = convert.NewTagFinder(tags).Convert(output)
@@ -72,6 +131,12 @@ func (c *bodyConverterImpl) ConvertBody(raw []rune, selfRelativePath string) (ou
 		if err != nil {
 			return nil, nil, errors.Wrap(err, "TitleFinder failed")
 		}
+		if c.titleSources {
+			sources, err = newTitleSources(titleFoundFrom, frontMatter, selfRelativePath)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
 	}
 	if c.rmtag {
 		output, err = convert.NewTagRemover().Convert(output)
@@ -79,6 +144,13 @@ func (c *bodyConverterImpl) ConvertBody(raw []rune, selfRelativePath string) (ou
 			return nil, nil, errors.Wrap(err, "TagRemover failed")
 		}
 	}
+	// %%toc%% がコメントとして消されないように [TOC] にそろえておく
+	if c.toc {
+		output, err = convert.NewTOCMarkerReplacer([]rune(convert.TOC_MARKER), nil).Convert(output)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "TOCMarkerReplacer failed")
+		}
+	}
 	if c.cmmt {
 		output, err = convert.NewCommentEraser().Convert(output)
 		if err != nil {
@@ -86,7 +158,59 @@ func (c *bodyConverterImpl) ConvertBody(raw []rune, selfRelativePath string) (ou
 		}
 	}
 
+	if c.footnote {
+		output, err = convert.ConvertInlineFootnotes(output)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to convert inline footnotes")
+		}
+	}
+
+	var fields []convert.InlineField
+	if c.inlineField {
+		fields = make([]convert.InlineField, 0)
+		output, err = convert.NewInlineFieldConverter(&fields, c.rmInlineField).Convert(output)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "InlineFieldConverter failed")
+		}
+	}
+
+	var tasks []convert.Task
+	if c.taskCount {
+		tasks = make([]convert.Task, 0)
+		_, err = convert.NewTaskFinder(&tasks).Convert(output)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "TaskFinder failed")
+		}
+	}
+
+	math := false
+	if c.setMath {
+		_, err = convert.NewMathFinder(&math).Convert(output)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "MathFinder failed")
+		}
+	}
+
+	var warnings []error
+	if c.pages != nil {
+		unsupported := make([]convert.ErrConvert, 0)
+		output, err = convert.NewDataviewConverter(c.pages, &unsupported).Convert(output)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "DataviewConverter failed")
+		}
+		for _, e := range unsupported {
+			warnings = append(warnings, e)
+		}
+	}
 	if c.link {
+		undefined, err := convert.FindUndefinedReferenceLabels(output)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to find undefined reference labels")
+		}
+		for _, e := range undefined {
+			warnings = append(warnings, e)
+		}
+
 		db := c.db
 		if c.formatLink {
 			db = convert.WrapForUsingSelfForEmptyFileId(selfRelativePath, db)
@@ -98,7 +222,11 @@ func (c *bodyConverterImpl) ConvertBody(raw []rune, selfRelativePath string) (ou
 			db = convert.WrapForRemappingPathPrefix(c.pathPrefixRemap, db)
 		}
 
-		output, err = convert.NewLinkConverter(db, c.anchorFormattingStyle).Convert(output)
+		vaults, err := c.vaults.forNote(selfRelativePath)
+		if err != nil {
+			return nil, nil, err
+		}
+		output, err = convert.NewLinkConverter(db, c.anchorFormattingStyle, vaults).Convert(output)
 		if err != nil {
 			return nil, nil, errors.Wrap(err, "LinkConverter failed")
 		}
@@ -116,14 +244,97 @@ func (c *bodyConverterImpl) ConvertBody(raw []rune, selfRelativePath string) (ou
 		// 	}
 		// }
 	}
+	if c.idb != nil {
+		selfPath := filepath.Join(c.targetPrefix, selfRelativePath)
+		output, err = convert.NewLinkImporter(c.idb, c.vault, selfPath, c.anchorFormattingStyle).Convert(output)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "LinkImporter failed")
+		}
+	}
+	// 生成したリンクが LinkConverter で書き換えられないように, リンクの変換の後に行う
+	if c.tagLinkTemplate != "" {
+		var name func(tag string) string
+		if c.tagRules != nil {
+			name = c.tagRules.pageName
+		}
+		output, err = convert.NewTagLinker(c.tagLinkTemplate, name).Convert(output)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "TagLinker failed")
+		}
+	}
 	if c.rmH1 {
 		output, err = convert.NewH1Remover().Convert(output)
 		if err != nil {
 			return nil, nil, errors.Wrap(err, "H1Remover failed")
 		}
 	}
+	if c.headingShift != 0 {
+		output, err = convert.NewHeadingShifter(c.headingShift).Convert(output)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "HeadingShifter failed")
+		}
+	}
+	// 見出しのレベルとリンクが変換された後の本文から目次を作る
+	if c.toc {
+		output, err = convert.InsertTOC(output, c.anchorFormattingStyle, c.tocMinLevel, c.tocMaxLevel, c.tocAfterH1)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to insert table of contents")
+		}
+	}
+	if c.highlightWrapper != "" || c.htmlComment {
+		output, err = convert.NewInlineSyntaxConverter(c.highlightWrapper, c.htmlComment).Convert(output)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "InlineSyntaxConverter failed")
+		}
+	}
+	if c.calloutRenderer != nil {
+		output, err = convert.NewCalloutConverter(c.calloutRenderer, c.calloutAliases).Convert(output)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "CalloutConverter failed")
+		}
+	}
+	if c.mathStyle != "" {
+		output, err = convert.NewMathConverter(c.mathStyle).Convert(output)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "MathConverter failed")
+		}
+	}
+	summarizedFrom := output
+	if c.importtag {
+		output, err = appendTagsFromFrontMatter(output, frontMatter)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to move tags in front matter to text")
+		}
+	}
+
+	// 本文の末尾に移したタグは要約と単語数に含めないので, importtag の前の本文を使う
+	summary := ""
+	if c.summaryLength > 0 {
+		summary, err = convert.Summarize(summarizedFrom, c.summaryLength)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to summarize")
+		}
+	}
+	var stats *textStats
+	if c.readingTime {
+		stats, err = newTextStats(summarizedFrom)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to count words")
+		}
+	}
+
+	var dates *dateSources
+	if c.dates != nil {
+		dates, err = c.dates.sources(selfRelativePath)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
 
-	aux = newBodyConvAuxOutImpl(title, tags)
+	aux = newBodyConvAuxOutImpl(title, tags, fields, tasks, math, sources, summary, stats, dates, selfRelativePath)
+	if len(warnings) > 0 {
+		return output, aux, process.NewErrWarning(warnings...)
+	}
 	return output, aux, nil
 }
 
@@ -142,3 +353,133 @@ func parsePathPrefixRemap(input string) (remap map[string]string, err error) {
 	}
 	return remap, nil
 }
+
+// -calloutAlias の値をパースする. type は小文字にそろえる.
+func parseCalloutAliases(input string) (aliases map[string]string, err error) {
+	if input == "" {
+		return nil, nil
+	}
+	aliases = make(map[string]string)
+	entries := strings.Split(input, "|")
+	for _, entry := range entries {
+		pair := strings.Split(entry, ">")
+		if len(pair) != 2 || pair[0] == "" || pair[1] == "" {
+			return nil, newMainErrf(MAIN_ERR_KIND_INVALID_CALLOUT_ALIASES_FORMAT, "invalid format of %s: \"%s\"", FLAG_CALLOUT_ALIASES, input)
+		}
+		aliases[strings.ToLower(pair[0])] = strings.ToLower(pair[1])
+	}
+	return aliases, nil
+}
+
+// -vaults で指定された vault の PathDB.
+// ローカルの vault へのリンクは, ノートの出力先のディレクトリからの相対パスにする.
+type vaultPathDBs struct {
+	dst   string                    // 出力先の絶対パス
+	dbs   map[string]convert.PathDB // vault 名 => PathDB
+	roots map[string]string         // ローカルの vault 名 => ルートの絶対パス
+}
+
+// -vaults の値をパースして, vault 名ごとの PathDB を作る.
+// vault のルートが URL の場合はファイルを探さずに URL を組み立てる.
+// ローカルのルートはカレントディレクトリからのパスとして扱う.
+func parseVaults(input string, dst string, strictref bool, formatLink bool) (vaults *vaultPathDBs, err error) {
+	if input == "" {
+		return nil, nil
+	}
+	dst, err = filepath.Abs(dst)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get absolute path of %s", dst)
+	}
+	vaults = &vaultPathDBs{
+		dst:   dst,
+		dbs:   make(map[string]convert.PathDB),
+		roots: make(map[string]string),
+	}
+	entries := strings.Split(input, "|")
+	for _, entry := range entries {
+		pair := strings.Split(entry, ">")
+		if len(pair) != 2 || pair[0] == "" || pair[1] == "" {
+			return nil, newMainErrf(MAIN_ERR_KIND_INVALID_VAULTS_FORMAT, "invalid format of %s: \"%s\"", FLAG_VAULTS, input)
+		}
+		name, root := pair[0], pair[1]
+		if u, err := url.Parse(root); err == nil && u.Scheme != "" && u.Host != "" {
+			vaults.dbs[name] = convert.NewPathDBForRemoteVault(root)
+			continue
+		}
+		root, err = filepath.Abs(root)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get absolute path of %s", root)
+		}
+		db := convert.NewPathDB(root)
+		if strictref {
+			db = convert.WrapForReturningNotFoundPathError(db)
+		}
+		if formatLink {
+			db = convert.WrapForTrimmingSuffixMd(db)
+			db = convert.WrapForEncodingPaths(db)
+		}
+		vaults.dbs[name] = db
+		vaults.roots[name] = root
+	}
+	return vaults, nil
+}
+
+// relativePath のノートから使う PathDB を返す.
+// ローカルの vault のパスには, ノートの出力先から vault のルートへの相対パスを付ける.
+func (v *vaultPathDBs) forNote(relativePath string) (vaults map[string]convert.PathDB, err error) {
+	if v == nil {
+		return nil, nil
+	}
+	dir := filepath.Join(v.dst, filepath.Dir(relativePath))
+	vaults = make(map[string]convert.PathDB)
+	for name, db := range v.dbs {
+		root, ok := v.roots[name]
+		if !ok {
+			vaults[name] = db
+			continue
+		}
+		prefix, err := filepath.Rel(dir, root)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get path to vault %s from %s", name, dir)
+		}
+		vaults[name] = convert.WrapForPrefixingPath(filepath.ToSlash(prefix)+"/", db)
+	}
+	return vaults, nil
+}
+
+// front matter の tags を本文末尾に #tag として追加する
+func appendTagsFromFrontMatter(body []rune, frontMatter *process.FrontMatter) (output []rune, err error) {
+	v, ok := frontMatter.Get("tags")
+	if !ok {
+		return body, nil
+	}
+	vv, err := tagsInYaml(v)
+	if err != nil {
+		return nil, fmt.Errorf("tags field found but %w", err)
+	}
+
+	tags := make([]string, 0, len(vv))
+	for _, aa := range vv {
+		if !validBodyTag(aa) {
+			continue
+		}
+		tags = append(tags, "#"+aa)
+	}
+	if len(tags) == 0 {
+		return body, nil
+	}
+
+	output = body
+	if len(output) > 0 && output[len(output)-1] != '\n' {
+		output = append(output, '\n')
+	}
+	output = append(output, []rune("\n"+strings.Join(tags, " ")+"\n")...)
+	return output, nil
+}
+
+// 本文中に #tag と書いてタグとして認識されるかどうか
+func validBodyTag(tag string) bool {
+	rns := []rune("#" + tag)
+	advance, _ := scan.ScanTag(rns, 0)
+	return advance == len(rns)
+}
@@ -3,40 +3,79 @@ package main
 import (
 	"errors"
 	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/qawatake/obsdconv/convert"
 	"github.com/qawatake/obsdconv/process"
-	"gopkg.in/yaml.v2"
 )
 
+// inline field と同じ key が front matter にすでにある場合の扱い
+const (
+	INLINE_FIELD_MERGE_KEEP      = "keep"
+	INLINE_FIELD_MERGE_OVERWRITE = "overwrite"
+	INLINE_FIELD_MERGE_APPEND    = "append"
+)
+
+var INLINE_FIELD_MERGE_POLICIES = []string{INLINE_FIELD_MERGE_KEEP, INLINE_FIELD_MERGE_OVERWRITE, INLINE_FIELD_MERGE_APPEND}
+
 type yamlConvAuxInImpl struct {
 	title   string
 	alias   string
 	newtags []string
+	fields  []convert.InlineField
+	tasks   *taskCounts
+	math    bool
+	summary string
+	stats   *textStats
+	dates   *dateSources
+	path    string
 }
 
-func newYamlConvAuxInImpl(title string, alias string, newtags []string) *yamlConvAuxInImpl {
+// tasks == nil のとき, タスクの数を front matter に追加しない.
+// summary == "" のとき, description を追加しない. stats == nil のとき, 単語数と読了時間を追加しない.
+// dates == nil のとき, date と lastmod を追加しない. path は schema の path と照らし合わせる tgt からの相対パス.
+func newYamlConvAuxInImpl(title string, alias string, newtags []string, fields []convert.InlineField, tasks *taskCounts, math bool, summary string, stats *textStats, dates *dateSources, path string) *yamlConvAuxInImpl {
 	return &yamlConvAuxInImpl{
 		title:   title,
 		alias:   alias,
 		newtags: newtags,
+		fields:  fields,
+		tasks:   tasks,
+		math:    math,
+		summary: summary,
+		stats:   stats,
+		dates:   dates,
+		path:    path,
 	}
 }
 
 type yamlConverterImpl struct {
-	synctag     bool
-	synctlal    bool
-	publishable bool
-	remap       map[string]string
+	yamlConverterOptions
 }
 
-func newYamlConverterImpl(synctag bool, synctlal bool, publishable bool, remap map[string]string) *yamlConverterImpl {
-	return &yamlConverterImpl{
-		synctag:     synctag,
-		synctlal:    synctlal,
-		publishable: publishable,
-		remap:       remap,
-	}
+// tagRules != nil のとき, 本文から見つけたタグに規則を適用する.
+// dateOptions != nil のとき, date と lastmod を追加する.
+// rules は draft の追加と key の remap の前に上から順に適用する.
+// schema != nil のとき, 書き出す front matter を schema と照らし合わせ, 違反を warning として返す.
+type yamlConverterOptions struct {
+	synctag          bool
+	synctlal         bool
+	publishable      bool
+	remap            map[string]string
+	importtag        bool
+	inlineFieldMerge string
+	tagRules         *tagRules
+	dateOptions      *dateOptions
+	rules            []*frontMatterRule
+	schema           *frontMatterSchema
+}
+
+func newYamlConverterImpl(opts yamlConverterOptions) *yamlConverterImpl {
+	return &yamlConverterImpl{yamlConverterOptions: opts}
 }
 
 func parseRemap(input string) (remap map[string]string, err error) {
@@ -55,30 +94,44 @@ func parseRemap(input string) (remap map[string]string, err error) {
 	return remap, nil
 }
 
-func (c *yamlConverterImpl) ConvertYAML(raw []byte, aux process.YamlConvAuxIn) (output []byte, err error) {
+func (c *yamlConverterImpl) ConvertYAML(fm *process.FrontMatter, aux process.YamlConvAuxIn) (err error) {
 	title := ""
 	alias := ""
 	var newtags []string
+	var fields []convert.InlineField
+	var tasks *taskCounts
+	math := false
+	summary := ""
+	var stats *textStats
+	var dates *dateSources
+	path := ""
 
 	if v, ok := aux.(*yamlConvAuxInImpl); !ok {
-		return nil, errors.New("input (YamlConverterInput) cannot be converted to yamlConverterInputImpl")
+		return errors.New("input (YamlConverterInput) cannot be converted to yamlConverterInputImpl")
 	} else {
 		title = v.title
 		alias = v.alias
 		newtags = v.newtags
+		fields = v.fields
+		tasks = v.tasks
+		math = v.math
+		summary = v.summary
+		stats = v.stats
+		dates = v.dates
+		path = v.path
 	}
 
-	m := make(map[interface{}]interface{})
-	if err := yaml.Unmarshal(raw, m); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal front matter: %w", err)
+	// 変更しない key は元の書き方のまま残る. 新しい key は追加した順に最後に書かれる
+	if err := normalizeTagsAndAliases(fm); err != nil {
+		return err
 	}
 
 	// synctlal
 	existingTitle := ""
 	if c.synctlal {
-		if v, ok := m["title"]; ok {
+		if v, ok := fm.Get("title"); ok {
 			if vv, ok := v.(string); !ok {
-				return nil, fmt.Errorf("aliases field found but its field type is not string: %T", v)
+				return fmt.Errorf("aliases field found but its field type is not string: %T", v)
 			} else {
 				existingTitle = vv
 			}
@@ -87,25 +140,27 @@ func (c *yamlConverterImpl) ConvertYAML(raw []byte, aux process.YamlConvAuxIn) (
 
 	// title
 	if title != "" {
-		m["title"] = title
+		if err := fm.Set("title", title); err != nil {
+			return err
+		}
 	}
 
 	// alias
 	if alias != "" {
-		if v, ok := m["aliases"]; !ok {
-			if alias != "" {
-				m["aliases"] = []string{alias}
+		if v, ok := fm.Get("aliases"); !ok {
+			if err := fm.Set("aliases", []string{alias}); err != nil {
+				return err
 			}
 		} else {
 			if vv, ok := v.([]interface{}); !ok {
-				return nil, fmt.Errorf("aliases field found but its field type is not []interface{}: %T", v)
+				return fmt.Errorf("aliases field found but its field type is not []interface{}: %T", v)
 			} else {
 				exists := false
 				aliases := make([]string, 0, len(vv))
 				for _, a := range vv {
 					aa, ok := a.(string)
 					if !ok {
-						return nil, fmt.Errorf("aliases field found but its field type is not string: %T", a)
+						return fmt.Errorf("aliases field found but its field type is not string: %T", a)
 					}
 					if c.synctlal && aa == existingTitle {
 						continue
@@ -118,79 +173,376 @@ func (c *yamlConverterImpl) ConvertYAML(raw []byte, aux process.YamlConvAuxIn) (
 				if !exists {
 					aliases = append(aliases, alias)
 				}
-				m["aliases"] = aliases
+				if err := fm.Set("aliases", aliases); err != nil {
+					return err
+				}
 			}
 		}
 	}
 
 	// tags
-	if c.synctag {
-		delete(m, "tags")
+	// synctag では元のタグを捨てて本文のタグに置き換える. tags の位置は変えない
+	routed := make(map[string][]string)
+	if c.tagRules != nil {
+		newtags = c.tagRules.apply(newtags, routed)
+		if v, ok := fm.Get("tags"); ok && c.tagRules.frontMatter && !c.synctag {
+			existing, err := stringsInYaml(v)
+			if err != nil {
+				return fmt.Errorf("tags field found but %w", err)
+			}
+			kept := c.tagRules.apply(existing, routed)
+			if len(kept) == 0 {
+				fm.Delete("tags")
+			} else if err := fm.Set("tags", kept); err != nil {
+				return err
+			}
+		}
 	}
-	if v, ok := m["tags"]; !ok {
-		if len(newtags) > 0 {
+	if v, ok := fm.Get("tags"); !ok || c.synctag {
+		if len(newtags) == 0 {
+			fm.Delete("tags")
+		} else {
 			tags := make([]string, len(newtags))
 			copy(tags, newtags)
-			m["tags"] = tags
+			if err := fm.Set("tags", tags); err != nil {
+				return err
+			}
 		}
 	} else {
 		if vv, ok := v.([]interface{}); !ok {
-			return nil, fmt.Errorf("tags field found but its field type is not []interface{}: %T", v)
+			return fmt.Errorf("tags field found but its field type is not []interface{}: %T", v)
 		} else {
 			existingTag := make(map[string]bool)
 			for _, a := range vv {
 				aa, ok := a.(string)
 				if !ok {
-					return nil, fmt.Errorf("tags field found but its field type is not string: %T", a)
+					return fmt.Errorf("tags field found but its field type is not string: %T", a)
 				}
-				existingTag[aa] = true
+				existingTag[strings.ToLower(aa)] = true
 			}
 			for _, t := range newtags {
-				if !existingTag[t] {
+				if !existingTag[strings.ToLower(t)] {
 					vv = append(vv, t)
 				}
 			}
-			m["tags"] = vv
+			if err := fm.Set("tags", vv); err != nil {
+				return err
+			}
+		}
+	}
+
+	// prefix で振り分けたタグ
+	// 振り分け先の key は名前の順に追加する
+	routedKeys := make([]string, 0, len(routed))
+	for key := range routed {
+		routedKeys = append(routedKeys, key)
+	}
+	sort.Strings(routedKeys)
+	for _, key := range routedKeys {
+		tags := routed[key]
+		v, ok := fm.Get(key)
+		if !ok {
+			if err := fm.Set(key, tags); err != nil {
+				return err
+			}
+			continue
+		}
+		existing, err := stringsInYaml(v)
+		if err != nil {
+			return fmt.Errorf("%s field found but %w", key, err)
+		}
+		if err := fm.Set(key, appendUniqueTags(existing, tags...)); err != nil {
+			return err
+		}
+	}
+
+	// importtag
+	// 本文に移したタグを front matter から削除する
+	if c.importtag {
+		if v, ok := fm.Get("tags"); ok {
+			vv, ok := v.([]interface{})
+			if !ok {
+				return fmt.Errorf("tags field found but its field type is not []interface{}: %T", v)
+			}
+			remained := make([]interface{}, 0, len(vv))
+			for _, a := range vv {
+				if aa, ok := a.(string); ok && validBodyTag(aa) {
+					continue
+				}
+				remained = append(remained, a)
+			}
+			if len(remained) == 0 {
+				fm.Delete("tags")
+			} else if err := fm.Set("tags", remained); err != nil {
+				return err
+			}
+		}
+	}
+
+	// inline fields
+	if len(fields) > 0 {
+		if err := mergeInlineFields(fm, fields, c.inlineFieldMerge); err != nil {
+			return err
+		}
+	}
+
+	// task counts
+	// タスクがなくなったノートは数を消す
+	if tasks != nil && tasks.total == 0 {
+		fm.Delete("tasks_open")
+		fm.Delete("tasks_done")
+	} else if tasks != nil {
+		if err := fm.Set("tasks_open", tasks.open); err != nil {
+			return err
+		}
+		if err := fm.Set("tasks_done", tasks.done); err != nil {
+			return err
+		}
+	}
+
+	// math
+	// math field がすでにある場合はそのまま
+	if !fm.Has("math") && math {
+		if err := fm.Set("math", true); err != nil {
+			return err
+		}
+	}
+
+	// summary
+	// description field がすでにある場合はそのまま
+	if !fm.Has("description") && summary != "" {
+		if err := fm.Set("description", summary); err != nil {
+			return err
+		}
+	}
+
+	// word count and reading time
+	if stats != nil {
+		if err := fm.Set("wordcount", stats.words); err != nil {
+			return err
+		}
+		if err := fm.Set("readingtime", stats.readingTime); err != nil {
+			return err
+		}
+	}
+
+	// date and lastmod
+	if c.dateOptions != nil && dates != nil {
+		if err := c.dateOptions.apply(fm, dates); err != nil {
+			return err
+		}
+	}
+
+	// front matter rules
+	if len(c.rules) > 0 {
+		if err := applyFrontMatterRules(fm, c.rules); err != nil {
+			return err
 		}
 	}
 
 	// publishable -> draft
 	// if draft field already exists, then keep it as is.
-	_, ok := m["draft"]
-	if !ok && c.publishable {
-		if p, ok := m["publish"]; !ok {
-			m["draft"] = true
+	if !fm.Has("draft") && c.publishable {
+		if p, ok := fm.Get("publish"); !ok {
+			if err := fm.Set("draft", true); err != nil {
+				return err
+			}
 		} else {
 			if publishable, ok := p.(bool); !ok {
-				return nil, fmt.Errorf("publish field found but its field type is not bool: %T", p)
-			} else {
-				m["draft"] = !publishable
+				return fmt.Errorf("publish field found but its field type is not bool: %T", p)
+			} else if err := fm.Set("draft", !publishable); err != nil {
+				return err
 			}
 		}
 	}
 
 	// remap keys in front matter
+	// 新しい key は元の key の位置に書く
 	if len(c.remap) > 0 {
-		for oldKey, newKey := range c.remap {
-			v, ok := m[oldKey]
-			if !ok {
-				continue
-			}
-			delete(m, oldKey)
+		oldKeys := make([]string, 0, len(c.remap))
+		for oldKey := range c.remap {
+			oldKeys = append(oldKeys, oldKey)
+		}
+		sort.Strings(oldKeys)
+		for _, oldKey := range oldKeys {
+			newKey := c.remap[oldKey]
 			if newKey == "" {
+				fm.Delete(oldKey)
 				continue
 			}
-			m[newKey] = v
+			fm.Rename(oldKey, newKey)
 		}
 	}
 
-	// for empty front matters
-	if len(m) == 0 {
-		return nil, nil
+	// schema
+	// 違反があってもファイルは書き出す
+	if c.schema != nil {
+		if violations := c.schema.validate(fm.Map(), path); len(violations) > 0 {
+			return process.NewErrWarning(violations...)
+		}
+	}
+	return nil
+}
+
+// inline field を policy にしたがって front matter に追加する. policy が空の場合は keep.
+// 同じ key の inline field が複数ある場合はリストにまとめる.
+func mergeInlineFields(fm *process.FrontMatter, fields []convert.InlineField, policy string) error {
+	keys := make([]string, 0, len(fields))
+	values := make(map[string]interface{})
+	for _, f := range fields {
+		v := parseInlineFieldValue(f.Value)
+		if existing, ok := values[f.Key]; ok {
+			values[f.Key] = appendInlineFieldValue(existing, v)
+			continue
+		}
+		keys = append(keys, f.Key)
+		values[f.Key] = v
+	}
+
+	for _, key := range keys {
+		v := values[key]
+		existing, ok := fm.Get(key)
+		switch {
+		case !ok, policy == INLINE_FIELD_MERGE_OVERWRITE:
+		case policy == INLINE_FIELD_MERGE_APPEND:
+			v = appendInlineFieldValue(existing, v)
+		default:
+			continue
+		}
+		if err := fm.Set(key, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// existing と v をリストにまとめる. existing にすでにある値は追加しない.
+func appendInlineFieldValue(existing interface{}, v interface{}) interface{} {
+	list := toInlineFieldList(existing)
+	added := false
+	for _, a := range toInlineFieldList(v) {
+		exists := false
+		for _, b := range list {
+			if sameInlineFieldValue(a, b) {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			list = append(list, a)
+			added = true
+		}
+	}
+	if !added {
+		return existing
+	}
+	return list
+}
+
+// front matter から読んだ日付は文字列なので, LocalDateTime と同じ値として比べる
+func sameInlineFieldValue(a interface{}, b interface{}) bool {
+	if d, ok := a.(process.LocalDateTime); ok {
+		a = string(d)
+	}
+	if d, ok := b.(process.LocalDateTime); ok {
+		b = string(d)
+	}
+	return a == b
+}
+
+func toInlineFieldList(v interface{}) []interface{} {
+	switch vv := v.(type) {
+	case []interface{}:
+		list := make([]interface{}, len(vv))
+		copy(list, vv)
+		return list
+	case []string:
+		list := make([]interface{}, 0, len(vv))
+		for _, a := range vv {
+			list = append(list, a)
+		}
+		return list
 	}
-	output, err = yaml.Marshal(m)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal output yaml: %w", err)
+	return []interface{}{v}
+}
+
+var inlineFieldFloatPattern = regexp.MustCompile(`^-?[0-9]+\.[0-9]+$`)
+
+var inlineFieldDateLayouts = []string{"2006-01-02", "2006-01-02T15:04", "2006-01-02T15:04:05", time.RFC3339}
+
+// inline field の値を bool, 数値, 日付, リスト, 文字列のいずれかとして解釈する.
+// , で区切られた値はリストにする. "..." で囲まれた値は文字列のまま扱う.
+func parseInlineFieldValue(value string) interface{} {
+	if len(value) >= 2 && strings.HasPrefix(value, "\"") && strings.HasSuffix(value, "\"") {
+		return value[1 : len(value)-1]
+	}
+	if !strings.Contains(value, ",") {
+		return parseInlineFieldScalar(value)
+	}
+	list := make([]interface{}, 0)
+	for _, a := range strings.Split(value, ",") {
+		a = strings.TrimSpace(a)
+		if a == "" {
+			continue
+		}
+		list = append(list, parseInlineFieldScalar(a))
+	}
+	return list
+}
+
+func parseInlineFieldScalar(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	if strings.EqualFold(value, "true") {
+		return true
+	}
+	if strings.EqualFold(value, "false") {
+		return false
+	}
+	if n, err := strconv.Atoi(value); err == nil {
+		return n
+	}
+	if inlineFieldFloatPattern.MatchString(value) {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	for _, layout := range inlineFieldDateLayouts {
+		if d, err := time.Parse(layout, value); err == nil {
+			// 時差のない日付や日時は, 書かれていない時刻や時差を足さないように書かれたまま残す
+			if layout != time.RFC3339 {
+				return process.LocalDateTime(value)
+			}
+			return d
+		}
+	}
+	return value
+}
+
+// 文字列または文字列のリストを []string にする
+func stringsInYaml(v interface{}) ([]string, error) {
+	switch vv := v.(type) {
+	case string:
+		return []string{vv}, nil
+	case []interface{}:
+		ss := make([]string, 0, len(vv))
+		for _, a := range vv {
+			aa, ok := a.(string)
+			if !ok {
+				return nil, fmt.Errorf("its element type is not string: %T", a)
+			}
+			ss = append(ss, aa)
+		}
+		return ss, nil
+	}
+	return nil, fmt.Errorf("its field type is neither string nor []interface{}: %T", v)
+}
+
+func stringsToYaml(ss []string) []interface{} {
+	list := make([]interface{}, 0, len(ss))
+	for _, s := range ss {
+		list = append(list, s)
 	}
-	return output, nil
+	return list
 }
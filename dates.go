@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/qawatake/obsdconv/process"
+)
+
+const (
+	DEFAULT_DATE_KEY    = "created"
+	DEFAULT_LASTMOD_KEY = "updated"
+	DEFAULT_DATE_FORMAT = time.RFC3339
+)
+
+// front matter の date と lastmod の候補 (-setdate, -setlastmod).
+// 見つからなかったものはゼロ値.
+type dateSources struct {
+	fileName    time.Time // ファイル名の先頭の日付 (daily note)
+	gitCreated  time.Time // ファイルを含む最初のコミット
+	gitModified time.Time // ファイルを含む最後のコミット
+	mtime       time.Time
+}
+
+type gitDates struct {
+	created  time.Time
+	modified time.Time
+}
+
+// tgt 以下のファイルの日付の候補を探す
+type fileDateIndex struct {
+	root   string
+	gitDir string
+	git    map[string]gitDates // gitDir からの相対パス (/ 区切り)
+}
+
+// git の履歴はローカルのリポジトリから一度だけ読む.
+// git がない場合や tgt がリポジトリに含まれない場合, 出力を読めなかった場合は履歴を使わない.
+func newFileDateIndex(tgt string) *fileDateIndex {
+	idx := &fileDateIndex{root: tgt, gitDir: tgt}
+	if filepath.Ext(tgt) == ".md" {
+		idx.gitDir = filepath.Dir(tgt)
+	}
+	cmd := exec.Command("git", "-c", "core.quotePath=false", "-C", idx.gitDir, "log", "-M", "--relative", "--name-status", "--format=%x00%aI")
+	out, err := cmd.Output()
+	if err != nil {
+		return idx
+	}
+	dates, err := parseGitLog(out)
+	if err != nil {
+		return idx
+	}
+	idx.git = dates
+	return idx
+}
+
+// git log -M --name-status --format=%x00%aI の出力を読む.
+// 新しいコミットから順に並んでいるので, 最初に現れた日付が最後のコミット.
+// rename されたファイルは rename 前のコミットも今のパスの履歴として扱う (git log --follow と同じ).
+func parseGitLog(out []byte) (map[string]gitDates, error) {
+	dates := make(map[string]gitDates)
+	renamed := make(map[string]string) // rename 前のパス => 今のパス
+	closed := make(map[string]bool)    // これより古いコミットは別のファイルのもの
+	var current time.Time
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.HasPrefix(line, "\x00") {
+			t, err := time.Parse(time.RFC3339, strings.TrimPrefix(line, "\x00"))
+			if err != nil {
+				current = time.Time{}
+				continue
+			}
+			current = t
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 || current.IsZero() {
+			continue
+		}
+		status, path := fields[0], fields[len(fields)-1]
+		if closed[path] {
+			continue
+		}
+		if p, ok := renamed[path]; ok {
+			path = p
+		}
+		d, ok := dates[path]
+		if !ok {
+			d.modified = current
+		}
+		d.created = current
+		dates[path] = d
+
+		switch status[0] {
+		case 'A', 'C':
+			closed[fields[len(fields)-1]] = true
+		case 'R':
+			closed[fields[len(fields)-1]] = true
+			renamed[fields[1]] = path
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read git log")
+	}
+	return dates, nil
+}
+
+// relativePath は tgt からの相対パス
+func (idx *fileDateIndex) sources(relativePath string) (*dateSources, error) {
+	path := filepath.Join(idx.root, relativePath)
+	sources := new(dateSources)
+	if m := titleDatePrefix.FindStringSubmatch(filepath.Base(path)); m != nil {
+		if t, err := time.ParseInLocation("2006-01-02", m[1], time.Local); err == nil {
+			sources.fileName = t
+		}
+	}
+	if rel, err := filepath.Rel(idx.gitDir, path); err == nil {
+		if d, ok := idx.git[filepath.ToSlash(rel)]; ok {
+			sources.gitCreated = d.created
+			sources.gitModified = d.modified
+		}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to stat %s", path)
+	}
+	sources.mtime = info.ModTime()
+	return sources, nil
+}
+
+// date と lastmod の書き込み方
+type dateOptions struct {
+	date       bool
+	lastmod    bool
+	dateKey    string // date の代わりに使う既存の key
+	lastmodKey string
+	format     string // Go の time のレイアウト
+	overwrite  bool   // date と lastmod がすでにあっても書き換える
+}
+
+// date は dateKey, ファイル名, 最初のコミット, mtime の順に探す.
+// lastmod は lastmodKey, 最後のコミット, mtime の順. ファイル名の日付は作成日なので使わない.
+func (o *dateOptions) apply(fm *process.FrontMatter, sources *dateSources) error {
+	if o.date {
+		if err := o.set(fm, "date", o.dateKey, sources.fileName, sources.gitCreated, sources.mtime); err != nil {
+			return err
+		}
+	}
+	if o.lastmod {
+		if err := o.set(fm, "lastmod", o.lastmodKey, sources.gitModified, sources.mtime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *dateOptions) set(fm *process.FrontMatter, field string, key string, candidates ...time.Time) error {
+	if fm.Has(field) && !o.overwrite {
+		return nil
+	}
+	v, _ := fm.Get(key)
+	if t, ok := dateInYaml(v); ok {
+		return fm.Set(field, t.Format(o.format))
+	}
+	for _, t := range candidates {
+		if !t.IsZero() {
+			return fm.Set(field, t.Format(o.format))
+		}
+	}
+	return nil
+}
+
+var yamlDateLayouts = append([]string{"2006-01-02 15:04:05", "2006-01-02 15:04"}, inlineFieldDateLayouts...)
+
+// 日付として読めない値は無視する
+func dateInYaml(v interface{}) (time.Time, bool) {
+	switch vv := v.(type) {
+	case time.Time:
+		return vv, true
+	case string:
+		for _, layout := range yamlDateLayouts {
+			if t, err := time.ParseInLocation(layout, strings.TrimSpace(vv), time.Local); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
@@ -2,29 +2,34 @@ package main
 
 import (
 	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/pkg/errors"
-	"gopkg.in/yaml.v2"
+	"github.com/qawatake/obsdconv/convert"
+	"github.com/qawatake/obsdconv/process"
 )
 
 type yamlExaminatorImpl struct {
-	filter      string
+	filter      *nodeImpl
 	publishable bool
 }
 
-func newYamlExaminatorImpl(filter string, publishable bool) *yamlExaminatorImpl {
+// filter == nil のとき, front matter で絞り込まない
+func newYamlExaminatorImpl(filter *nodeImpl, publishable bool) *yamlExaminatorImpl {
 	return &yamlExaminatorImpl{
 		filter:      filter,
 		publishable: publishable,
 	}
 }
 
-func (examinator *yamlExaminatorImpl) ExamineYaml(yml []byte) (beProcessed bool, err error) {
-	fm := make(map[interface{}]interface{})
-	if err := yaml.Unmarshal(yml, fm); err != nil {
-		return false, errors.Wrap(err, "failed to unmarshal front matter")
-	}
+// relativePath と body は filter の path と bodytags に使う
+func (examinator *yamlExaminatorImpl) ExamineYaml(frontMatter *process.FrontMatter, body []rune, relativePath string) (beProcessed bool, err error) {
+	fm := frontMatter.Map()
 
 	if examinator.publishable {
 		if ok, err := checkPublishable(fm); err != nil {
@@ -34,8 +39,8 @@ func (examinator *yamlExaminatorImpl) ExamineYaml(yml []byte) (beProcessed bool,
 		}
 	}
 
-	if examinator.filter != "" {
-		if ok, err := checkFilter(fm, examinator.filter); err != nil {
+	if examinator.filter != nil {
+		if ok, err := evaluateNodeIn(examinator.filter, newFilterEnv(fm, relativePath, body)); err != nil {
 			return false, errors.Wrap(err, "failed to check filter field in front matter")
 		} else if !ok {
 			return false, nil
@@ -59,45 +64,80 @@ func checkPublishable(fm map[interface{}]interface{}) (ok bool, err error) {
 }
 
 func checkFilter(fm map[interface{}]interface{}, filter string) (value bool, err error) {
-	token, err := tokenizeFilter(filter)
+	nd, err := compileFilter(filter)
 	if err != nil {
 		return false, err
 	}
+	return evaluateNode(nd, fm)
+}
 
-	nd, _, err := parseTokens(token)
-	if err != nil {
-		return false, newMainErr(MAIN_ERR_KIND_INVALID_FILTER_FORMAT)
-	}
+// filter の中の名前 path と bodytags は front matter の key より優先する
+const (
+	FILTER_NAME_PATH      = "path"
+	FILTER_NAME_BODY_TAGS = "bodytags"
+)
 
-	return evaluateNode(nd, fm)
+// filter を評価するときに参照する値
+type filterEnv struct {
+	fm       map[interface{}]interface{}
+	path     string // tgt からの相対パス
+	body     []rune
+	bodyTags []interface{} // bodytags を使うときに body から集める
+}
+
+func newFilterEnv(fm map[interface{}]interface{}, path string, body []rune) *filterEnv {
+	return &filterEnv{
+		fm:   fm,
+		path: filepath.ToSlash(path),
+		body: body,
+	}
 }
 
 func evaluateNode(nd *nodeImpl, fm map[interface{}]interface{}) (value bool, err error) {
+	return evaluateNodeIn(nd, newFilterEnv(fm, "", nil))
+}
+
+func evaluateNodeIn(nd *nodeImpl, env *filterEnv) (value bool, err error) {
 	if nd.kind == NODE_IDENT {
-		name := nd.name
-		if v, ok := fm[name]; ok {
-			if value, ok := v.(bool); ok {
-				return value, nil
-			}
-		} else {
+		v, err := env.lookup(nd.name)
+		if err != nil {
+			return false, err
+		}
+		if v == nil {
 			return false, nil
 		}
-		return false, newMainErr(MAIN_ERR_KIND_INVALID_FILTER_FORMAT)
+		if value, ok := v.(bool); ok {
+			return value, nil
+		}
+		return false, newFilterErrf(FILTER_ERROR_EVALUATE, "%s is not bool but %T. use exists(%s) or a comparison", nd.name, v, nd.name)
 	} else if nd.kind == NODE_TRUE {
 		return true, nil
 	} else if nd.kind == NODE_NOT {
-		if v, err := evaluateNode(nd.left, fm); err != nil {
+		if v, err := evaluateNodeIn(nd.left, env); err != nil {
 			return false, err
 		} else {
 			return !v, nil
 		}
+	} else if nd.kind == NODE_EXISTS {
+		v, err := env.lookup(nd.name)
+		return v != nil, err
+	} else if nd.kind == NODE_COMPARE {
+		left, err := operandValue(nd.left, env)
+		if err != nil {
+			return false, err
+		}
+		right, err := operandValue(nd.right, env)
+		if err != nil {
+			return false, err
+		}
+		return compareFilterValues(nd.name, left, right, nd.pattern), nil
 	}
 
-	left, err := evaluateNode(nd.left, fm)
+	left, err := evaluateNodeIn(nd.left, env)
 	if err != nil {
 		return false, err
 	}
-	right, err := evaluateNode(nd.right, fm)
+	right, err := evaluateNodeIn(nd.right, env)
 	if err != nil {
 		return false, err
 	}
@@ -110,6 +150,156 @@ func evaluateNode(nd *nodeImpl, fm map[interface{}]interface{}) (value bool, err
 
 }
 
+// key がない場合は nil. meta.status のように . で入れ子の key をたどる
+func (env *filterEnv) lookup(name string) (interface{}, error) {
+	switch name {
+	case FILTER_NAME_PATH:
+		return env.path, nil
+	case FILTER_NAME_BODY_TAGS:
+		if env.bodyTags == nil {
+			tags := make(map[string]struct{})
+			if _, err := convert.NewTagFinder(tags).Convert(env.body); err != nil {
+				return nil, errors.Wrap(err, "failed to find tags in body")
+			}
+			names := make([]string, 0, len(tags))
+			for t := range tags {
+				names = append(names, t)
+			}
+			sort.Strings(names)
+			env.bodyTags = stringsToYaml(names)
+		}
+		return env.bodyTags, nil
+	}
+	if v, ok := env.fm[name]; ok {
+		return v, nil
+	}
+	var cur interface{} = env.fm
+	for _, key := range strings.Split(name, ".") {
+		m, ok := cur.(map[interface{}]interface{})
+		if !ok {
+			return nil, nil
+		}
+		if cur, ok = m[key]; !ok {
+			return nil, nil
+		}
+	}
+	return cur, nil
+}
+
+func operandValue(nd *nodeImpl, env *filterEnv) (interface{}, error) {
+	switch nd.kind {
+	case NODE_STRING:
+		return nd.name, nil
+	case NODE_NUMBER:
+		return nd.number, nil
+	}
+	return env.lookup(nd.name)
+}
+
+// 比較できない組み合わせ (key がない場合を含む) は != 以外 false
+func compareFilterValues(op string, left interface{}, right interface{}, pattern *regexp.Regexp) bool {
+	switch op {
+	case "==":
+		return equalFilterValues(left, right)
+	case "!=":
+		return !equalFilterValues(left, right)
+	case "in":
+		return containsFilterValue(right, left)
+	case "contains":
+		return containsFilterValue(left, right)
+	case "glob":
+		s, ok := left.(string)
+		return ok && pattern.MatchString(s)
+	}
+	c, ok := orderFilterValues(left, right)
+	if !ok {
+		return false
+	}
+	switch op {
+	case "<":
+		return c < 0
+	case ">":
+		return c > 0
+	case "<=":
+		return c <= 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+func equalFilterValues(left interface{}, right interface{}) bool {
+	if left == nil || right == nil {
+		return left == nil && right == nil
+	}
+	if c, ok := orderFilterValues(left, right); ok {
+		return c == 0
+	}
+	return fmt.Sprint(left) == fmt.Sprint(right)
+}
+
+// 数値どうし, 日付どうし, 文字列どうしの順に比べる
+func orderFilterValues(left interface{}, right interface{}) (int, bool) {
+	if l, ok := numberInFilter(left); ok {
+		if r, ok := numberInFilter(right); ok {
+			switch {
+			case l < r:
+				return -1, true
+			case l > r:
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+	if l, ok := dateInYaml(left); ok {
+		if r, ok := dateInYaml(right); ok {
+			switch {
+			case l.Before(r):
+				return -1, true
+			case l.After(r):
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+	l, lok := left.(string)
+	r, rok := right.(string)
+	if lok && rok {
+		return strings.Compare(l, r), true
+	}
+	return 0, false
+}
+
+func numberInFilter(v interface{}) (float64, bool) {
+	switch vv := v.(type) {
+	case int:
+		return float64(vv), true
+	case int64:
+		return float64(vv), true
+	case uint64:
+		return float64(vv), true
+	case float64:
+		return vv, true
+	}
+	return 0, false
+}
+
+// list がリストなら要素のどれかが v と等しいか, 文字列なら v を含むか
+func containsFilterValue(list interface{}, v interface{}) bool {
+	switch vv := list.(type) {
+	case []interface{}:
+		for _, a := range vv {
+			if equalFilterValues(a, v) {
+				return true
+			}
+		}
+	case string:
+		s, ok := v.(string)
+		return ok && strings.Contains(vv, s)
+	}
+	return false
+}
+
 type tokenKind = uint
 
 const (
@@ -119,12 +309,23 @@ const (
 	TOKEN_NOT
 	TOKEN_RESERVED
 	TOKEN_EOS
+	TOKEN_STRING
+	TOKEN_NUMBER
+	TOKEN_OP
 )
 
+// 比較の演算子. in, contains, glob は TOKEN_IDENT として読み, 演算子の位置にあるときだけ演算子として扱う
+var FILTER_OPERATORS = []string{"==", "!=", "<=", ">=", "<", ">"}
+var FILTER_KEYWORD_OPERATORS = []string{"in", "contains", "glob"}
+
+const FILTER_FUNC_EXISTS = "exists"
+
 type tokenImpl struct {
-	kind tokenKind
-	name string
-	next *tokenImpl
+	kind   tokenKind
+	name   string
+	number float64
+	pos    int // filter の何文字目から始まるか
+	next   *tokenImpl
 }
 
 type nodeKind uint
@@ -135,13 +336,41 @@ const (
 	NODE_OR
 	NODE_NOT
 	NODE_TRUE
+	NODE_STRING
+	NODE_NUMBER
+	NODE_COMPARE // name は演算子
+	NODE_EXISTS
 )
 
 type nodeImpl struct {
-	kind  nodeKind
-	name  string
-	left  *nodeImpl
-	right *nodeImpl
+	kind    nodeKind
+	name    string
+	number  float64
+	pattern *regexp.Regexp // glob の右辺
+	left    *nodeImpl
+	right   *nodeImpl
+}
+
+// filter を一度だけ parse する. 空の filter は常に true
+func compileFilter(filter string) (nd *nodeImpl, err error) {
+	token, err := tokenizeFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	if token.kind == TOKEN_EOS {
+		return newParentNode(NODE_TRUE, nil, nil), nil
+	}
+	nd, next, err := parseTokens(token)
+	if err == nil && next.kind != TOKEN_EOS {
+		err = newParseErr(next, "expected '&&', '||' or the end of the filter")
+	}
+	if err != nil {
+		if e, ok := err.(*FilterErr); ok {
+			e.filter = filter
+		}
+		return nil, err
+	}
+	return nd, nil
 }
 
 func parseTokens(cur *tokenImpl) (nd *nodeImpl, next *tokenImpl, err error) {
@@ -149,7 +378,7 @@ func parseTokens(cur *tokenImpl) (nd *nodeImpl, next *tokenImpl, err error) {
 	if err != nil {
 		return nil, nil, err
 	}
-	if cur != nil && cur.kind == TOKEN_OR {
+	if cur.kind == TOKEN_OR {
 		right, next, err := parseTokens(cur.next)
 		if err != nil {
 			return nil, nil, err
@@ -164,7 +393,7 @@ func andNode(cur *tokenImpl) (nd *nodeImpl, next *tokenImpl, err error) {
 	if err != nil {
 		return nil, nil, err
 	}
-	if cur != nil && cur.kind == TOKEN_AND {
+	if cur.kind == TOKEN_AND {
 		right, next, err := andNode(cur.next)
 		if err != nil {
 			return nil, nil, err
@@ -175,9 +404,6 @@ func andNode(cur *tokenImpl) (nd *nodeImpl, next *tokenImpl, err error) {
 }
 
 func unaryNode(cur *tokenImpl) (nd *nodeImpl, next *tokenImpl, err error) {
-	if cur == nil {
-		return nil, nil, newMainErr(MAIN_ERR_KIND_INVALID_FILTER_FORMAT)
-	}
 	if cur.kind == TOKEN_NOT {
 		left, next, err := unaryNode(cur.next)
 		if err != nil {
@@ -189,28 +415,52 @@ func unaryNode(cur *tokenImpl) (nd *nodeImpl, next *tokenImpl, err error) {
 	return primaryNode(cur)
 }
 
+// tokenizeFilter の結果は TOKEN_EOS で終わるので, TOKEN_EOS 以外の token の next は nil にならない
 func primaryNode(cur *tokenImpl) (nd *nodeImpl, next *tokenImpl, err error) {
-	if cur == nil {
-		return nil, nil, newMainErr(MAIN_ERR_KIND_INVALID_FILTER_FORMAT)
+	if cur.kind == TOKEN_IDENT && cur.name == FILTER_FUNC_EXISTS && isReservedToken(cur.next, "(") {
+		arg := cur.next.next
+		if arg.kind != TOKEN_IDENT {
+			return nil, nil, newParseErr(arg, "expected a key")
+		}
+		if !isReservedToken(arg.next, ")") {
+			return nil, nil, newParseErr(arg.next, "expected ')'")
+		}
+		return &nodeImpl{kind: NODE_EXISTS, name: arg.name}, arg.next.next, nil
 	}
-	if cur.kind == TOKEN_IDENT {
+	if left := operandNode(cur); left != nil {
 		next = cur.next
-		return newIdentNode(cur.name), next, nil
+		op := operatorToken(next)
+		if op == nil {
+			if left.kind != NODE_IDENT {
+				return nil, nil, newParseErr(next, "expected an operator")
+			}
+			return left, next, nil
+		}
+		right := operandNode(op.next)
+		if right == nil {
+			return nil, nil, newParseErr(op.next, "expected a key or a value after %s", op.name)
+		}
+		nd = newParentNode(NODE_COMPARE, left, right)
+		nd.name = op.name
+		if op.name == "glob" {
+			if right.kind != NODE_STRING {
+				return nil, nil, newParseErr(op.next, "expected a quoted pattern after glob")
+			}
+			nd.pattern = globToRegexp(right.name)
+		}
+		return nd, op.next.next, nil
 	}
 	if cur.kind == TOKEN_RESERVED && cur.name == "(" {
 		nd, cur, err = parseTokens(cur.next)
 		if err != nil {
 			return nil, nil, err
 		}
-		if cur.kind == TOKEN_RESERVED && cur.name == ")" {
-			next = cur.next
-			return nd, next, nil
+		if !isReservedToken(cur, ")") {
+			return nil, nil, newParseErr(cur, "expected ')'")
 		}
+		return nd, cur.next, nil
 	}
-	if cur.kind == TOKEN_EOS {
-		return newParentNode(NODE_TRUE, nil, nil), nil, nil
-	}
-	return nil, nil, newMainErr(MAIN_ERR_KIND_INVALID_FILTER_FORMAT)
+	return nil, nil, newParseErr(cur, "expected a key or a value")
 }
 
 func newParentNode(kind nodeKind, left *nodeImpl, right *nodeImpl) *nodeImpl {
@@ -228,10 +478,43 @@ func newIdentNode(name string) *nodeImpl {
 	}
 }
 
-func newTokenImpl(kind tokenKind, name string, pre *tokenImpl) *tokenImpl {
+func isReservedToken(cur *tokenImpl, name string) bool {
+	return cur != nil && cur.kind == TOKEN_RESERVED && cur.name == name
+}
+
+// key, 文字列, 数値でなければ nil
+func operandNode(cur *tokenImpl) *nodeImpl {
+	switch cur.kind {
+	case TOKEN_IDENT:
+		return newIdentNode(cur.name)
+	case TOKEN_STRING:
+		return &nodeImpl{kind: NODE_STRING, name: cur.name}
+	case TOKEN_NUMBER:
+		return &nodeImpl{kind: NODE_NUMBER, name: cur.name, number: cur.number}
+	}
+	return nil
+}
+
+// 演算子でなければ nil
+func operatorToken(cur *tokenImpl) *tokenImpl {
+	if cur.kind == TOKEN_OP {
+		return cur
+	}
+	if cur.kind == TOKEN_IDENT {
+		for _, op := range FILTER_KEYWORD_OPERATORS {
+			if cur.name == op {
+				return cur
+			}
+		}
+	}
+	return nil
+}
+
+func newTokenImpl(kind tokenKind, name string, pos int, pre *tokenImpl) *tokenImpl {
 	child := &tokenImpl{
 		kind: kind,
 		name: name,
+		pos:  pos,
 	}
 	pre.next = child
 	return child
@@ -247,15 +530,26 @@ func tokenizeFilter(input string) (token *tokenImpl, err error) {
 			break
 		}
 
+		if unicode.IsSpace(runes[p]) {
+			p++
+			continue
+		}
+
+		if op := consumeOperator(runes[p:]); op != "" {
+			cur = newTokenImpl(TOKEN_OP, op, p, cur)
+			p += len([]rune(op))
+			continue
+		}
+
 		if p < len(runes)-1 {
 			if string(runes[p:p+2]) == "&&" {
-				cur = newTokenImpl(TOKEN_AND, "", cur)
+				cur = newTokenImpl(TOKEN_AND, "", p, cur)
 				p += 2
 				continue
 			}
 
 			if string(runes[p:p+2]) == "||" {
-				cur = newTokenImpl(TOKEN_OR, "", cur)
+				cur = newTokenImpl(TOKEN_OR, "", p, cur)
 				p += 2
 				continue
 			}
@@ -263,30 +557,61 @@ func tokenizeFilter(input string) (token *tokenImpl, err error) {
 		}
 
 		if string(runes[p:p+1]) == "!" {
-			cur = newTokenImpl(TOKEN_NOT, "", cur)
+			cur = newTokenImpl(TOKEN_NOT, "", p, cur)
 			p++
 			continue
 		}
 
 		if runes[p] == '(' || runes[p] == ')' {
-			cur = newTokenImpl(TOKEN_RESERVED, string(runes[p:p+1]), cur)
+			cur = newTokenImpl(TOKEN_RESERVED, string(runes[p:p+1]), p, cur)
 			p++
 			continue
 		}
 
+		if runes[p] == '"' || runes[p] == '\'' {
+			value, length := consumeString(runes[p:])
+			if length == 0 {
+				return nil, newTokinizeErr(string(runes), p, "unterminated string")
+			}
+			cur = newTokenImpl(TOKEN_STRING, value, p, cur)
+			p += length
+			continue
+		}
+
 		if length := consumeIdent(runes[p:]); length > 0 {
-			cur = newTokenImpl(TOKEN_IDENT, string(runes[p:p+length]), cur)
+			name := string(runes[p : p+length])
+			// 数字で始まる場合は key ではなく値. 2021-06-01 のような日付は文字列として扱う
+			if unicode.IsDigit(runes[p]) || (runes[p] == '-' && length > 1 && unicode.IsDigit(runes[p+1])) {
+				if n, err := strconv.ParseFloat(name, 64); err == nil {
+					cur = newTokenImpl(TOKEN_NUMBER, name, p, cur)
+					cur.number = n
+				} else {
+					cur = newTokenImpl(TOKEN_STRING, name, p, cur)
+				}
+			} else {
+				cur = newTokenImpl(TOKEN_IDENT, name, p, cur)
+			}
 			p += length
 			continue
 		}
 
-		return nil, newTokinizeErr(string(runes), p)
+		return nil, newTokinizeErr(string(runes), p, fmt.Sprintf("unexpected character %q", runes[p]))
 
 	}
-	cur = newTokenImpl(TOKEN_EOS, "", cur)
+	cur = newTokenImpl(TOKEN_EOS, "", len(runes), cur)
 	return head.next, nil
 }
 
+// != は ! より先に読む
+func consumeOperator(runes []rune) string {
+	for _, op := range FILTER_OPERATORS {
+		if strings.HasPrefix(string(runes), op) {
+			return op
+		}
+	}
+	return ""
+}
+
 func consumeIdent(runes []rune) (length int) {
 	for i := 0; i < len(runes); i++ {
 		char := runes[i]
@@ -299,7 +624,7 @@ func consumeIdent(runes []rune) (length int) {
 		if '0' <= char && char <= '9' {
 			continue
 		}
-		if char == '-' || char == '_' {
+		if char == '-' || char == '_' || char == '.' {
 			continue
 		}
 		return i
@@ -307,6 +632,26 @@ func consumeIdent(runes []rune) (length int) {
 	return len(runes)
 }
 
+// runes[0] の引用符で囲まれた文字列. \ の次の文字はそのまま読む. 閉じていなければ length = 0
+func consumeString(runes []rune) (value string, length int) {
+	quote := runes[0]
+	b := new(strings.Builder)
+	for i := 1; i < len(runes); i++ {
+		switch runes[i] {
+		case '\\':
+			if i+1 < len(runes) {
+				i++
+				b.WriteRune(runes[i])
+			}
+		case quote:
+			return b.String(), i + 1
+		default:
+			b.WriteRune(runes[i])
+		}
+	}
+	return "", 0
+}
+
 type FilterErrorKind uint
 
 const (
@@ -315,9 +660,12 @@ const (
 	FILTER_ERROR_EVALUATE
 )
 
+// FILTER_ERROR_TOKENIZE と FILTER_ERROR_PARSE では, filter の pos 文字目に ↑ を付けて表示する
 type FilterErr struct {
 	kind    FilterErrorKind
 	message string
+	filter  string
+	pos     int
 }
 
 func newFilterErrf(kind FilterErrorKind, format string, a ...interface{}) *FilterErr {
@@ -328,10 +676,23 @@ func newFilterErrf(kind FilterErrorKind, format string, a ...interface{}) *Filte
 }
 
 func (err *FilterErr) Error() string {
-	return err.message
+	if err.kind == FILTER_ERROR_EVALUATE {
+		return err.message
+	}
+	errHere := strings.Repeat(" ", err.pos) + "↑"
+	return fmt.Sprintf("%s at column %d\n\t%s\n\t%s", err.message, err.pos+1, err.filter, errHere)
 }
 
-func newTokinizeErr(filter string, pos int) *FilterErr {
-	errHere := strings.Repeat(" ", pos) + "↑"
-	return newFilterErrf(FILTER_ERROR_TOKENIZE, "-filter=%s\n        %s", filter, errHere)
+func newTokinizeErr(filter string, pos int, message string) *FilterErr {
+	err := newFilterErrf(FILTER_ERROR_TOKENIZE, "%s", message)
+	err.filter = filter
+	err.pos = pos
+	return err
+}
+
+// filter は compileFilter で埋める
+func newParseErr(cur *tokenImpl, format string, a ...interface{}) *FilterErr {
+	err := newFilterErrf(FILTER_ERROR_PARSE, format, a...)
+	err.pos = cur.pos
+	return err
 }
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/qawatake/obsdconv/process"
+	"gopkg.in/yaml.v2"
+)
+
+// -fmrules で読み込む front matter の規則. 上から順に適用する.
+//
+//	# rules.yml
+//	- default: {draft: true}             # key がなければ追加する
+//	- set: {layout: post}                # 常に上書きする
+//	- copy: {from: title, to: linkTitle}
+//	- rename: {from: created, to: date}
+//	- coerce: {publish: bool}            # bool, int, float, string, list
+//	- map: {key: status, to: draft, values: {wip: true, done: false}}
+//	- delete: [cssclass, "kanban-*"]     # path.Match のパターン
+//	  if: "!keep"                        # -filter と同じ形式の条件
+type frontMatterRule struct {
+	If      string               `yaml:"if"`
+	Default yaml.MapSlice        `yaml:"default"` // 追加する key は書かれた順に並べる
+	Set     yaml.MapSlice        `yaml:"set"`
+	Copy    *frontMatterRuleMove `yaml:"copy"`
+	Rename  *frontMatterRuleMove `yaml:"rename"`
+	Coerce  map[string]string    `yaml:"coerce"`
+	Map     *frontMatterRuleMap  `yaml:"map"`
+	Delete  stringList           `yaml:"delete"`
+	cond    *nodeImpl
+}
+
+type frontMatterRuleMove struct {
+	From string `yaml:"from"`
+	To   string `yaml:"to"`
+}
+
+// values にない値のとき, default があれば default を書き込む. なければ何もしない.
+type frontMatterRuleMap struct {
+	Key     string                 `yaml:"key"`
+	To      string                 `yaml:"to"` // 空なら key を書き換える
+	Values  map[string]interface{} `yaml:"values"`
+	Default interface{}            `yaml:"default"`
+}
+
+// 文字列 1 つでも文字列のリストでもよい
+type stringList []string
+
+func (l *stringList) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var list []string
+	if err := unmarshal(&list); err == nil {
+		*l = list
+		return nil
+	}
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	*l = []string{s}
+	return nil
+}
+
+const (
+	COERCE_BOOL   = "bool"
+	COERCE_INT    = "int"
+	COERCE_FLOAT  = "float"
+	COERCE_STRING = "string"
+	COERCE_LIST   = "list"
+)
+
+var COERCE_TYPES = []string{COERCE_BOOL, COERCE_INT, COERCE_FLOAT, COERCE_STRING, COERCE_LIST}
+
+func readFrontMatterRules(path string) (rules []*frontMatterRule, err error) {
+	if path == "" {
+		return nil, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", path)
+	}
+	return parseFrontMatterRules(content)
+}
+
+func parseFrontMatterRules(content []byte) (rules []*frontMatterRule, err error) {
+	if err := yaml.UnmarshalStrict(content, &rules); err != nil {
+		return nil, newMainErrf(MAIN_ERR_KIND_INVALID_FRONT_MATTER_RULES_FORMAT, "invalid format of %s: %v", FLAG_FRONT_MATTER_RULES, err)
+	}
+	for i, r := range rules {
+		if r == nil {
+			return nil, newMainErrf(MAIN_ERR_KIND_INVALID_FRONT_MATTER_RULES_FORMAT, "invalid format of %s: rule %d is empty", FLAG_FRONT_MATTER_RULES, i+1)
+		}
+		if err := r.validate(); err != nil {
+			return nil, newMainErrf(MAIN_ERR_KIND_INVALID_FRONT_MATTER_RULES_FORMAT, "invalid format of %s: rule %d: %v", FLAG_FRONT_MATTER_RULES, i+1, err)
+		}
+		if r.If == "" {
+			continue
+		}
+		r.cond, err = compileFilter(r.If)
+		if err != nil {
+			return nil, newMainErrf(MAIN_ERR_KIND_INVALID_FRONT_MATTER_RULES_FORMAT, "invalid format of %s: rule %d: invalid condition: %v", FLAG_FRONT_MATTER_RULES, i+1, err)
+		}
+	}
+	return rules, nil
+}
+
+// 1 つの規則に書ける操作は 1 つだけ
+func (r *frontMatterRule) validate() error {
+	actions := 0
+	for _, set := range []bool{r.Default != nil, r.Set != nil, r.Copy != nil, r.Rename != nil, r.Coerce != nil, r.Map != nil, r.Delete != nil} {
+		if set {
+			actions++
+		}
+	}
+	if actions != 1 {
+		return fmt.Errorf("exactly one of default, set, copy, rename, coerce, map and delete is needed")
+	}
+	for _, move := range []*frontMatterRuleMove{r.Copy, r.Rename} {
+		if move != nil && (move.From == "" || move.To == "") {
+			return fmt.Errorf("from and to are needed")
+		}
+	}
+	for key, typ := range r.Coerce {
+		valid := false
+		for _, t := range COERCE_TYPES {
+			if typ == t {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("unknown type for %s: %s. Available types: %s", key, typ, strings.Join(COERCE_TYPES, ", "))
+		}
+	}
+	if r.Map != nil && r.Map.Key == "" {
+		return fmt.Errorf("key is needed in map")
+	}
+	for _, pattern := range r.Delete {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern in delete: %s", pattern)
+		}
+	}
+	return nil
+}
+
+func applyFrontMatterRules(fm *process.FrontMatter, rules []*frontMatterRule) error {
+	for _, r := range rules {
+		if r.cond != nil {
+			ok, err := evaluateNode(r.cond, fm.Map())
+			if err != nil {
+				return fmt.Errorf("failed to evaluate condition of %s: %s: %w", FLAG_FRONT_MATTER_RULES, r.If, err)
+			}
+			if !ok {
+				continue
+			}
+		}
+		if err := r.apply(fm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rename した key は元の key の位置に残る
+func (r *frontMatterRule) apply(fm *process.FrontMatter) error {
+	for _, item := range r.Default {
+		key := fmt.Sprint(item.Key)
+		if fm.Has(key) {
+			continue
+		}
+		if err := fm.Set(key, item.Value); err != nil {
+			return err
+		}
+	}
+	for _, item := range r.Set {
+		if err := fm.Set(fmt.Sprint(item.Key), item.Value); err != nil {
+			return err
+		}
+	}
+	if r.Copy != nil {
+		if v, ok := fm.Get(r.Copy.From); ok {
+			if err := fm.Set(r.Copy.To, v); err != nil {
+				return err
+			}
+		}
+	}
+	if r.Rename != nil {
+		fm.Rename(r.Rename.From, r.Rename.To)
+	}
+	coerced := make([]string, 0, len(r.Coerce))
+	for key := range r.Coerce {
+		coerced = append(coerced, key)
+	}
+	sort.Strings(coerced)
+	for _, key := range coerced {
+		v, ok := fm.Get(key)
+		if !ok {
+			continue
+		}
+		vv, err := coerceYamlValue(v, r.Coerce[key])
+		if err != nil {
+			return fmt.Errorf("%s field found but %w", key, err)
+		}
+		if err := fm.Set(key, vv); err != nil {
+			return err
+		}
+	}
+	if r.Map != nil {
+		if v, ok := fm.Get(r.Map.Key); ok {
+			to := r.Map.To
+			if to == "" {
+				to = r.Map.Key
+			}
+			if mapped, ok := r.Map.Values[fmt.Sprint(v)]; ok {
+				if err := fm.Set(to, mapped); err != nil {
+					return err
+				}
+			} else if r.Map.Default != nil {
+				if err := fm.Set(to, r.Map.Default); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for _, key := range fm.Keys() {
+		for _, pattern := range r.Delete {
+			if matched, _ := path.Match(pattern, key); matched {
+				fm.Delete(key)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func coerceYamlValue(v interface{}, typ string) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if typ == COERCE_LIST {
+		if _, ok := v.([]interface{}); ok {
+			return v, nil
+		}
+		return []interface{}{v}, nil
+	}
+	if _, ok := v.([]interface{}); ok {
+		return nil, fmt.Errorf("its field type is []interface{} and cannot be converted to %s", typ)
+	}
+	if _, ok := v.(map[interface{}]interface{}); ok {
+		return nil, fmt.Errorf("its field type is map[interface{}]interface{} and cannot be converted to %s", typ)
+	}
+	s := strings.TrimSpace(fmt.Sprint(v))
+	switch typ {
+	case COERCE_BOOL:
+		switch strings.ToLower(s) {
+		case "true", "yes", "on", "1":
+			return true, nil
+		case "false", "no", "off", "0":
+			return false, nil
+		}
+	case COERCE_INT:
+		if n, err := strconv.Atoi(s); err == nil {
+			return n, nil
+		}
+	case COERCE_FLOAT:
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f, nil
+		}
+	case COERCE_STRING:
+		return s, nil
+	}
+	return nil, fmt.Errorf("its value cannot be converted to %s: %v", typ, v)
+}
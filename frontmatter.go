@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+	"github.com/qawatake/obsdconv/process"
+	"gopkg.in/yaml.v2"
+)
+
+// Obsidian は tags と aliases の書き方をいくつも受け付ける.
+// 	tags: a, b / tags: "#a #b" / tags: [a, "#b"] / tag: a
+// 	aliases: a / aliases: [a, b] / alias: a
+// これらをリストにそろえてから変換する.
+
+// tags の値を読む. リストではなく文字列の場合はカンマと空白で区切る.
+// 先頭の # を除き, 大文字小文字を区別せずに重複を除く.
+func tagsInYaml(v interface{}) ([]string, error) {
+	values, err := scalarsInYaml(v)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := v.([]interface{}); !ok && len(values) == 1 {
+		values = strings.FieldsFunc(values[0], func(r rune) bool { return r == ',' || unicode.IsSpace(r) })
+	}
+	tags := make([]string, 0, len(values))
+	for _, value := range values {
+		if t := strings.TrimLeft(strings.TrimSpace(value), "#"); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return uniqueFold(tags), nil
+}
+
+// aliases の値を読む. 文字列は 1 つの別名として扱う.
+func aliasesInYaml(v interface{}) ([]string, error) {
+	values, err := scalarsInYaml(v)
+	if err != nil {
+		return nil, err
+	}
+	aliases := make([]string, 0, len(values))
+	for _, value := range values {
+		if a := strings.TrimSpace(value); a != "" {
+			aliases = append(aliases, a)
+		}
+	}
+	return uniqueFold(aliases), nil
+}
+
+// スカラーかスカラーのリスト. 数値や真偽値も文字列として読む.
+func scalarsInYaml(v interface{}) ([]string, error) {
+	switch vv := v.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		ss := make([]string, 0, len(vv))
+		for _, a := range vv {
+			switch a.(type) {
+			case nil:
+				continue
+			case []interface{}, map[interface{}]interface{}, yaml.MapSlice:
+				return nil, fmt.Errorf("its element type is not string: %T", a)
+			}
+			ss = append(ss, fmt.Sprint(a))
+		}
+		return ss, nil
+	case map[interface{}]interface{}, yaml.MapSlice:
+		return nil, fmt.Errorf("its field type is neither string nor []interface{}: %T", v)
+	}
+	return []string{fmt.Sprint(v)}, nil
+}
+
+// 大文字小文字を区別せずに重複を除く. 最初に現れたものを残す.
+func uniqueFold(ss []string) []string {
+	unique := make([]string, 0, len(ss))
+	seen := make(map[string]bool)
+	for _, s := range ss {
+		folded := strings.ToLower(s)
+		if seen[folded] {
+			continue
+		}
+		seen[folded] = true
+		unique = append(unique, s)
+	}
+	return unique
+}
+
+// tags (tag) と aliases (alias) をリストにそろえる. key がどちらもなければ何もしない.
+// tag と tags が両方ある場合は先に現れた位置に置く.
+func normalizeTagsAndAliases(fm *process.FrontMatter) error {
+	for _, field := range []struct {
+		key    string
+		legacy string
+		read   func(v interface{}) ([]string, error)
+	}{
+		{"tags", "tag", tagsInYaml},
+		{"aliases", "alias", aliasesInYaml},
+	} {
+		values := make([]string, 0)
+		found := false
+		for _, key := range []string{field.key, field.legacy} {
+			v, ok := fm.Get(key)
+			if !ok {
+				continue
+			}
+			found = true
+			vv, err := field.read(v)
+			if err != nil {
+				return fmt.Errorf("%s field found but %w", key, err)
+			}
+			values = append(values, vv...)
+		}
+		if !found {
+			continue
+		}
+		fm.Rename(field.legacy, field.key)
+		if err := fm.Set(field.key, uniqueFold(values)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// front matter の tags と aliases をリストの形式に書き換える.
+// それ以外の field はそのまま残す. すでにリストの形式であれば changed = false.
+func canonicalizeFrontMatter(fm *process.FrontMatter) (changed bool, err error) {
+	if err := normalizeTagsAndAliases(fm); err != nil {
+		return false, err
+	}
+	return fm.Changed(), nil
+}
+
+// 変換の前に, 元のノートの tags と aliases をリストの形式に書き換える (-fixfm)
+type frontMatterFixer struct {
+	sub process.Processor
+}
+
+func newFrontMatterFixer(sub process.Processor) *frontMatterFixer {
+	return &frontMatterFixer{sub: sub}
+}
+
+func (p *frontMatterFixer) Process(relativePath, orgpath, newpath string) error {
+	if filepath.Ext(orgpath) == ".md" {
+		if err := fixFrontMatter(orgpath); err != nil {
+			return err
+		}
+	}
+	return p.sub.Process(relativePath, orgpath, newpath)
+}
+
+// 区切りの行と本文はバイト列のまま残す
+func fixFrontMatter(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read %s", path)
+	}
+	doc := process.SplitFrontMatter(content)
+	if doc.Format != process.FRONT_MATTER_YAML {
+		return nil
+	}
+	fm, err := process.ParseFrontMatter(doc.FrontMatter, doc.Format)
+	if err != nil {
+		return errors.Wrapf(err, "failed to fix front matter of %s", path)
+	}
+	changed, err := canonicalizeFrontMatter(fm)
+	if err != nil {
+		return errors.Wrapf(err, "failed to fix front matter of %s", path)
+	}
+	if !changed {
+		return nil
+	}
+	frontMatter, err := doc.FormatFrontMatter(fm, process.FRONT_MATTER_YAML)
+	if err != nil {
+		return errors.Wrapf(err, "failed to fix front matter of %s", path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to stat %s", path)
+	}
+	if err := os.WriteFile(path, doc.Join(frontMatter, doc.Body()), info.Mode()); err != nil {
+		return errors.Wrapf(err, "failed to write %s", path)
+	}
+	return nil
+}
@@ -6,4 +6,5 @@ require (
 	github.com/pkg/errors v0.9.1
 	golang.org/x/text v0.3.7
 	gopkg.in/yaml.v2 v2.4.0
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -26,20 +26,21 @@ func main() {
 	setConfig(flag.CommandLine, config)
 
 	// main 部分
-	versionText, bufferredErrs, err := run(Version, config)
+	// 変換や -strictschema で失敗した場合も, それまでの警告やエラーを先に出す
+	output, bufferredErrs, err := run(Version, config)
+	for _, err := range bufferredErrs {
+		fmt.Fprintln(os.Stderr, err)
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
-	if versionText != "" {
-		fmt.Println(versionText)
-		return
-	}
-	for _, err := range bufferredErrs {
-		fmt.Fprintln(os.Stderr, err)
+	if output != "" {
+		fmt.Println(output)
 	}
 }
 
-func run(version string, config *configuration) (verionText string, bufferredErrs []error, err error) {
+// output はバージョンやタスクの一覧など, 変換の代わりに標準出力に出すもの
+func run(version string, config *configuration) (output string, bufferredErrs []error, err error) {
 	if config.ver {
 		return fmt.Sprintf("v%s", version), nil, nil
 	}
@@ -50,12 +51,24 @@ func run(version string, config *configuration) (verionText string, bufferredErr
 	if err != nil {
 		return "", nil, err
 	}
+	if config.taskReport != "" {
+		report, err := newTaskReport(config.src, config.tgt, skipper, config.taskReport)
+		if err != nil {
+			return "", nil, err
+		}
+		return report, nil, nil
+	}
 	processor, err := newDefaultProcessor(config)
 	if err != nil {
 		return "", nil, err
 	}
 	if err := process.Walk(config.tgt, config.dst, skipper, processor); err != nil {
-		return "", nil, err
+		return "", processor.errbuf, err
+	}
+	if config.strictSchema {
+		if n := countSchemaViolations(processor.errbuf); n > 0 {
+			return "", processor.errbuf, newMainErrf(MAIN_ERR_KIND_SCHEMA_VIOLATED, "%d violations of %s found", n, FLAG_SCHEMA)
+		}
 	}
 	return "", processor.errbuf, nil
 }
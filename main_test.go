@@ -10,6 +10,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/qawatake/obsdconv/convert"
@@ -29,23 +30,25 @@ func TestRun(t *testing.T) {
 		convert.ERR_KIND_UNEXPECTED_HREF:                  "unexpected href",
 		convert.ERR_KIND_INVALID_SHORTHAND_OBSIDIAN_URL:   "invalid shorthand obsidian url",
 		convert.ERR_KIND_PATH_NOT_FOUND:                   "path not found",
+		convert.ERR_KIND_UNDEFINED_REFERENCE_LABEL:        "undefined reference label",
+		convert.ERR_KIND_UNSUPPORTED_DATAVIEW_QUERY:       "unsupported dataview query",
 	}
 
 	cases := []struct {
-		name            string
-		cmdflags        map[string]string
-		version         string
-		wantDstDir      string
-		wantVersionText string
-		wantErrKinds    []convert.ErrKind
+		name         string
+		cmdflags     map[string]string
+		version      string
+		wantDstDir   string
+		wantOutput   string
+		wantErrKinds []convert.ErrKind
 	}{
 		{
 			name: "-version",
 			cmdflags: map[string]string{
 				FLAG_VERSION: "1",
 			},
-			version:         "1.0.0",
-			wantVersionText: "v1.0.0",
+			version:    "1.0.0",
+			wantOutput: "v1.0.0",
 		},
 		{
 			name: "[SAMPLE] -obs",
@@ -135,6 +138,206 @@ func TestRun(t *testing.T) {
 				convert.ERR_KIND_PATH_NOT_FOUND,
 			},
 		},
+		{
+			name: "-link (reference-style links)",
+			cmdflags: map[string]string{
+				FLAG_SOURCE:        filepath.Join(testdataDir, "reflink", src),
+				FLAG_DESTINATION:   filepath.Join(testdataDir, "reflink", tmp),
+				FLAG_CONVERT_LINKS: "1",
+			},
+			wantDstDir: filepath.Join(testdataDir, "reflink", dst),
+			wantErrKinds: []convert.ErrKind{
+				convert.ERR_KIND_UNDEFINED_REFERENCE_LABEL,
+			},
+		},
+		{
+			name: "-footnote",
+			cmdflags: map[string]string{
+				FLAG_SOURCE:            filepath.Join(testdataDir, "footnote", src),
+				FLAG_DESTINATION:       filepath.Join(testdataDir, "footnote", tmp),
+				FLAG_CONVERT_FOOTNOTES: "1",
+			},
+			wantDstDir: filepath.Join(testdataDir, "footnote", dst),
+		},
+		{
+			name: "-callout=mkdocs -calloutAlias",
+			cmdflags: map[string]string{
+				FLAG_SOURCE:          filepath.Join(testdataDir, "callout", src),
+				FLAG_DESTINATION:     filepath.Join(testdataDir, "callout", tmp),
+				FLAG_CALLOUT:         convert.CALLOUT_STYLE_MKDOCS,
+				FLAG_CALLOUT_ALIASES: "caution>warning",
+			},
+			wantDstDir: filepath.Join(testdataDir, "callout", dst),
+		},
+		{
+			name: "-highlight -htmlcmmt",
+			cmdflags: map[string]string{
+				FLAG_SOURCE:       filepath.Join(testdataDir, "highlight", src),
+				FLAG_DESTINATION:  filepath.Join(testdataDir, "highlight", tmp),
+				FLAG_HIGHLIGHT:    "1",
+				FLAG_HTML_COMMENT: "1",
+			},
+			wantDstDir: filepath.Join(testdataDir, "highlight", dst),
+		},
+		{
+			name: "-dataview",
+			cmdflags: map[string]string{
+				FLAG_SOURCE:      filepath.Join(testdataDir, "dataview", src),
+				FLAG_DESTINATION: filepath.Join(testdataDir, "dataview", tmp),
+				FLAG_DATAVIEW:    "1",
+			},
+			wantDstDir: filepath.Join(testdataDir, "dataview", dst),
+			wantErrKinds: []convert.ErrKind{
+				convert.ERR_KIND_UNSUPPORTED_DATAVIEW_QUERY,
+			},
+		},
+		{
+			name: "-inlinefield -rminlinefield -inlineFieldMerge=overwrite",
+			cmdflags: map[string]string{
+				FLAG_SOURCE:               filepath.Join(testdataDir, "inlinefield", src),
+				FLAG_DESTINATION:          filepath.Join(testdataDir, "inlinefield", tmp),
+				FLAG_INLINE_FIELDS:        "1",
+				FLAG_REMOVE_INLINE_FIELDS: "1",
+				FLAG_INLINE_FIELD_MERGE:   INLINE_FIELD_MERGE_OVERWRITE,
+			},
+			wantDstDir: filepath.Join(testdataDir, "inlinefield", dst),
+		},
+		{
+			name: "-taskcount",
+			cmdflags: map[string]string{
+				FLAG_SOURCE:      filepath.Join(testdataDir, "tasks", src),
+				FLAG_DESTINATION: filepath.Join(testdataDir, "tasks", tmp),
+				FLAG_TASK_COUNTS: "1",
+			},
+			wantDstDir: filepath.Join(testdataDir, "tasks", dst),
+		},
+		{
+			name: "-taskReport=md",
+			cmdflags: map[string]string{
+				FLAG_SOURCE:      filepath.Join(testdataDir, "tasks", src),
+				FLAG_TASK_REPORT: TASK_REPORT_MARKDOWN,
+			},
+			wantOutput: `# Tasks
+
+## Open
+
+- [/] draft #project/a ⏫ 📅 2026-11-01 ([[projects/a|a]], line 3)
+- [ ] plan #project 📅 2026-11-10 ([[index|index]], line 7)
+
+## Done
+
+- [x] setup ✅ 2026-10-01 ([[index|index]], line 8)
+
+## Cancelled
+
+- [-] dropped ([[projects/a|a]], line 4)`,
+		},
+		{
+			name: "-math=latex -setmath",
+			cmdflags: map[string]string{
+				FLAG_SOURCE:      filepath.Join(testdataDir, "math", src),
+				FLAG_DESTINATION: filepath.Join(testdataDir, "math", tmp),
+				FLAG_MATH:        convert.MATH_STYLE_LATEX,
+				FLAG_SET_MATH:    "1",
+			},
+			wantDstDir: filepath.Join(testdataDir, "math", dst),
+		},
+		{
+			name: "-cptag -expandtag -slugtag -tagMap -routeTag",
+			cmdflags: map[string]string{
+				FLAG_SOURCE:       filepath.Join(testdataDir, "tagrules", src),
+				FLAG_DESTINATION:  filepath.Join(testdataDir, "tagrules", tmp),
+				FLAG_COPY_TAGS:    "1",
+				FLAG_EXPAND_TAGS:  "1",
+				FLAG_SLUGIFY_TAGS: "1",
+				FLAG_TAG_MAP:      filepath.Join(testdataDir, "tagrules", "tagmap.yml"),
+				FLAG_ROUTE_TAG:    "cat>categories",
+			},
+			wantDstDir: filepath.Join(testdataDir, "tagrules", dst),
+		},
+		{
+			name: "-cptag -linktag -tagLinkTemplate",
+			cmdflags: map[string]string{
+				FLAG_SOURCE:            filepath.Join(testdataDir, "linktag", src),
+				FLAG_DESTINATION:       filepath.Join(testdataDir, "linktag", tmp),
+				FLAG_COPY_TAGS:         "1",
+				FLAG_LINK_TAGS:         "1",
+				FLAG_TAG_LINK_TEMPLATE: "https://example.com/tags/{}/",
+			},
+			wantDstDir: filepath.Join(testdataDir, "linktag", dst),
+		},
+		{
+			name: "-title -fallbacktitle -titleTemplate",
+			cmdflags: map[string]string{
+				FLAG_SOURCE:         filepath.Join(testdataDir, "titlefallback", src),
+				FLAG_DESTINATION:    filepath.Join(testdataDir, "titlefallback", tmp),
+				FLAG_COPY_TITLE:     "1",
+				FLAG_FALLBACK_TITLE: "1",
+				FLAG_TITLE_TEMPLATE: "{{trimDate .Title}}",
+			},
+			wantDstDir: filepath.Join(testdataDir, "titlefallback", dst),
+		},
+		{
+			name: "-rmh1 -shiftHeadings=-1",
+			cmdflags: map[string]string{
+				FLAG_SOURCE:         filepath.Join(testdataDir, "shiftheadings", src),
+				FLAG_DESTINATION:    filepath.Join(testdataDir, "shiftheadings", tmp),
+				FLAG_REMOVE_H1:      "1",
+				FLAG_SHIFT_HEADINGS: "-1",
+			},
+			wantDstDir: filepath.Join(testdataDir, "shiftheadings", dst),
+		},
+		{
+			name: "-toch1 -cmmt",
+			cmdflags: map[string]string{
+				FLAG_SOURCE:         filepath.Join(testdataDir, "toc", src),
+				FLAG_DESTINATION:    filepath.Join(testdataDir, "toc", tmp),
+				FLAG_TOC_AFTER_H1:   "1",
+				FLAG_REMOVE_COMMENT: "1",
+			},
+			wantDstDir: filepath.Join(testdataDir, "toc", dst),
+		},
+		{
+			name: "-summary -summaryLength=20 -readingtime",
+			cmdflags: map[string]string{
+				FLAG_SOURCE:         filepath.Join(testdataDir, "summary", src),
+				FLAG_DESTINATION:    filepath.Join(testdataDir, "summary", tmp),
+				FLAG_SUMMARY:        "1",
+				FLAG_SUMMARY_LENGTH: "20",
+				FLAG_READING_TIME:   "1",
+			},
+			wantDstDir: filepath.Join(testdataDir, "summary", dst),
+		},
+		{
+			name: "-setdate -setlastmod -dateFormat=2006-01-02",
+			cmdflags: map[string]string{
+				FLAG_SOURCE:      filepath.Join(testdataDir, "dates", src),
+				FLAG_DESTINATION: filepath.Join(testdataDir, "dates", tmp),
+				FLAG_SET_DATE:    "1",
+				FLAG_SET_LASTMOD: "1",
+				FLAG_DATE_FORMAT: "2006-01-02",
+			},
+			wantDstDir: filepath.Join(testdataDir, "dates", dst),
+		},
+		{
+			name: "-fmrules",
+			cmdflags: map[string]string{
+				FLAG_SOURCE:             filepath.Join(testdataDir, "fmrules", src),
+				FLAG_DESTINATION:        filepath.Join(testdataDir, "fmrules", tmp),
+				FLAG_FRONT_MATTER_RULES: filepath.Join(testdataDir, "fmrules", "rules.yml"),
+			},
+			wantDstDir: filepath.Join(testdataDir, "fmrules", dst),
+		},
+		{
+			name: "-cptag -frontmatter=toml",
+			cmdflags: map[string]string{
+				FLAG_SOURCE:              filepath.Join(testdataDir, "frontmatter", src),
+				FLAG_DESTINATION:         filepath.Join(testdataDir, "frontmatter", tmp),
+				FLAG_COPY_TAGS:           "1",
+				FLAG_FRONT_MATTER_FORMAT: "toml",
+			},
+			wantDstDir: filepath.Join(testdataDir, "frontmatter", dst),
+		},
 		{
 			name: "-obs -synctag",
 			cmdflags: map[string]string{
@@ -246,6 +449,16 @@ func TestRun(t *testing.T) {
 			},
 			wantDstDir: filepath.Join(testdataDir, "formatAnchorMarkdownIt", dst),
 		},
+		{
+			name: "-import -importtag",
+			cmdflags: map[string]string{
+				FLAG_SOURCE:      filepath.Join(testdataDir, "import", src),
+				FLAG_DESTINATION: filepath.Join(testdataDir, "import", tmp),
+				FLAG_IMPORT:      "1",
+				FLAG_IMPORT_TAGS: "1",
+			},
+			wantDstDir: filepath.Join(testdataDir, "import", dst),
+		},
 	}
 
 	for _, tt := range cases {
@@ -258,14 +471,14 @@ func TestRun(t *testing.T) {
 		}
 		setConfig(flagset, config)
 
-		gotVersionText, gotBufferredErrs, err := run(tt.version, config)
+		gotOutput, gotBufferredErrs, err := run(tt.version, config)
 		if err != nil {
 			t.Fatalf("[FATAL | %s] unexpected err occurred: %v", tt.name, err)
 		}
 
-		// check version text
-		if gotVersionText != tt.wantVersionText {
-			t.Errorf("[ERROR | version // %s] got: %q, want: %q", tt.name, gotVersionText, tt.wantVersionText)
+		// check output
+		if gotOutput != tt.wantOutput {
+			t.Errorf("[ERROR | output // %s] got: %q, want: %q", tt.name, gotOutput, tt.wantOutput)
 			continue
 		}
 
@@ -510,6 +723,52 @@ func TestCheckFilter(t *testing.T) {
 			filter: "",
 			want:   true,
 		},
+		{
+			fm: map[interface{}]interface{}{
+				"status": "done",
+				"weight": 3,
+			},
+			filter: `status == "done" && weight > 2 && weight <= 3.0`,
+			want:   true,
+		},
+		{
+			fm: map[interface{}]interface{}{
+				"status": "done",
+			},
+			filter: `status != 'done' || missing == "x"`,
+			want:   false,
+		},
+		{
+			fm: map[interface{}]interface{}{
+				"tags": []interface{}{"go", "public"},
+			},
+			filter: `tags contains "public" && "go" in tags && !(tags contains "private")`,
+			want:   true,
+		},
+		{
+			fm: map[interface{}]interface{}{
+				"date": "2021-06-01",
+				"due":  time.Date(2021, 7, 1, 0, 0, 0, 0, time.Local),
+			},
+			filter: `date >= 2021-06-01 && date < "2021-06-02" && due > date`,
+			want:   true,
+		},
+		{
+			fm: map[interface{}]interface{}{
+				"meta": map[interface{}]interface{}{
+					"status": "wip",
+				},
+			},
+			filter: `meta.status == "wip" && exists(meta.status) && !exists(meta.owner)`,
+			want:   true,
+		},
+		{
+			fm: map[interface{}]interface{}{
+				"title": "note",
+			},
+			filter: `exists(title) && title > 1`,
+			want:   false,
+		},
 	}
 
 	for _, tt := range cases {
@@ -522,3 +781,58 @@ func TestCheckFilter(t *testing.T) {
 		}
 	}
 }
+
+func TestCompileFilter(t *testing.T) {
+	cases := []struct {
+		filter string
+		want   string
+	}{
+		{
+			filter: "(key1 || key2 && key3",
+			want:   "expected ')' at column 22\n\t(key1 || key2 && key3\n\t                     ↑",
+		},
+		{
+			filter: "key1 &&",
+			want:   "expected a key or a value at column 8\n\tkey1 &&\n\t       ↑",
+		},
+		{
+			filter: "key1 key2",
+			want:   "expected '&&', '||' or the end of the filter at column 6\n\tkey1 key2\n\t     ↑",
+		},
+		{
+			filter: `status == `,
+			want:   "expected a key or a value after == at column 11\n\tstatus == \n\t          ↑",
+		},
+		{
+			filter: `path glob blog`,
+			want:   "expected a quoted pattern after glob at column 11\n\tpath glob blog\n\t          ↑",
+		},
+		{
+			filter: `"done" && key`,
+			want:   "expected an operator at column 8\n\t\"done\" && key\n\t       ↑",
+		},
+		{
+			filter: `exists(key`,
+			want:   "expected ')' at column 11\n\texists(key\n\t          ↑",
+		},
+		{
+			filter: `status == "done`,
+			want:   "unterminated string at column 11\n\tstatus == \"done\n\t          ↑",
+		},
+		{
+			filter: `key1 & key2`,
+			want:   "unexpected character '&' at column 6\n\tkey1 & key2\n\t     ↑",
+		},
+	}
+
+	for _, tt := range cases {
+		_, err := compileFilter(tt.filter)
+		if err == nil {
+			t.Errorf("[ERROR] filter: %s\nerror expected but got nil", tt.filter)
+			continue
+		}
+		if err.Error() != tt.want {
+			t.Errorf("[ERROR] filter: %s\n got: %q\nwant: %q", tt.filter, err.Error(), tt.want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/qawatake/obsdconv/convert"
+	"github.com/qawatake/obsdconv/process"
+)
+
+// vault 内の markdown ファイルの front matter とタグを集める (-dataview).
+// skipper で無視されるファイルと, examinator (-filter と -pub) で変換しないファイルは含めない.
+// front matter を読めないファイルは含めずに, 警告を warnings に追加する.
+func newPageIndex(vault string, tgt string, skipper process.Skipper, examinator process.YamlExaminator) (pages []convert.Page, warnings []error, err error) {
+	pages = make([]convert.Page, 0)
+	err = filepath.Walk(vault, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rpath, err := filepath.Rel(vault, path)
+		if err != nil {
+			return err
+		}
+		if skipper.Skip(rpath) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() || filepath.Ext(path) != ".md" {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read %s", path)
+		}
+		doc := process.SplitFrontMatter(content)
+		fm, err := process.ParseFrontMatter(doc.FrontMatter, doc.Format)
+		if err != nil {
+			warnings = append(warnings, handleWarning(path, errors.Wrapf(err, "%s skipped this note because it failed to read %s front matter", FLAG_DATAVIEW, doc.Format)))
+			return nil
+		}
+		body := doc.Body()
+		// filter の path は変換のときと同じく tgt からの相対パス
+		tpath, err := filepath.Rel(tgt, path)
+		if err != nil {
+			return err
+		}
+		if ok, err := examinator.ExamineYaml(fm, body, tpath); err != nil {
+			warnings = append(warnings, handleWarning(path, errors.Wrapf(err, "%s skipped this note because it failed to examine front matter", FLAG_DATAVIEW)))
+			return nil
+		} else if !ok {
+			return nil
+		}
+		frontMatter := make(map[string]interface{})
+		for key, v := range fm.Map() {
+			frontMatter[fmt.Sprint(key)] = v
+		}
+
+		tags := make(map[string]struct{})
+		for _, t := range frontMatterTags(frontMatter) {
+			tags[t] = struct{}{}
+		}
+		if _, err := convert.NewTagFinder(tags).Convert(body); err != nil {
+			return errors.Wrapf(err, "TagFinder failed in %s", path)
+		}
+		page := convert.Page{
+			Path:        filepath.ToSlash(rpath),
+			FrontMatter: frontMatter,
+			Tags:        make([]string, 0, len(tags)),
+		}
+		for t := range tags {
+			page.Tags = append(page.Tags, t)
+		}
+		sort.Strings(page.Tags)
+		pages = append(pages, page)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return pages, warnings, nil
+}
+
+// front matter の tags はリストでも文字列でもよい. 読めない値は無視する.
+func frontMatterTags(frontMatter map[string]interface{}) []string {
+	tags := make([]string, 0)
+	for _, key := range []string{"tags", "tag"} {
+		if v, err := tagsInYaml(frontMatter[key]); err == nil {
+			tags = append(tags, v...)
+		}
+	}
+	return uniqueFold(tags)
+}
@@ -4,19 +4,26 @@ import (
 	"errors"
 	"sort"
 	"strings"
+	"text/template"
 
 	"github.com/qawatake/obsdconv/process"
 )
 
 type argPasserImpl struct {
-	title bool
-	alias bool
+	title         bool
+	alias         bool
+	fallbackTitle bool
+	titleTemplate *template.Template
 }
 
-func newArgPasserImpl(title bool, alias bool) *argPasserImpl {
+// fallbackTitle のとき, H1 がなければ titleSources から title を決める.
+// titleTemplate != nil のとき, title を titleTemplate で書き換える.
+func newArgPasserImpl(title bool, alias bool, fallbackTitle bool, titleTemplate *template.Template) *argPasserImpl {
 	return &argPasserImpl{
-		title: title,
-		alias: alias,
+		title:         title,
+		alias:         alias,
+		fallbackTitle: fallbackTitle,
+		titleTemplate: titleTemplate,
 	}
 }
 
@@ -32,6 +39,15 @@ func (passer *argPasserImpl) PassArg(frombody process.BodyConvAuxOut) (toyaml pr
 	}
 	if passer.title {
 		title = args.title
+		if title == "" && passer.fallbackTitle && args.sources != nil {
+			title = args.sources.fallback()
+		}
+		if passer.titleTemplate != nil {
+			title, err = executeTitleTemplate(passer.titleTemplate, title, args.title, args.sources)
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 	if passer.alias {
 		alias = args.title
@@ -45,5 +61,5 @@ func (passer *argPasserImpl) PassArg(frombody process.BodyConvAuxOut) (toyaml pr
 		return strings.Compare(newtags[i], newtags[j]) <= 0
 	})
 
-	return newYamlConvAuxInImpl(title, alias, newtags), nil
+	return newYamlConvAuxInImpl(title, alias, newtags, args.fields, countTasks(args.tasks), args.math, args.summary, args.stats, args.dates, args.path), nil
 }
@@ -29,6 +29,14 @@ func (p *processorImplWithErrHandling) Process(relativePath, orgpath, newpath st
 		return nil
 	}
 
+	// 警告の場合はファイルが書き込まれているので, 出力だけする
+	if w, ok := errors.Cause(err).(process.ErrWarning); ok {
+		for _, warning := range w.Warnings() {
+			p.errbuf = append(p.errbuf, handleWarning(orgpath, warning))
+		}
+		return nil
+	}
+
 	// 予想済みのエラーの場合は処理を止めずに, エラー出力だけする
 	public, debug, buffered := handleErr(orgpath, err)
 	if public == nil && debug == nil {
@@ -60,15 +68,147 @@ func newDefaultProcessor(config *configuration) (processor *processorImplWithErr
 	if err != nil {
 		return nil, err
 	}
-	bc := newBodyConverterImpl(db, config.cptag || config.synctag, config.rmtag, config.cmmt, config.title || config.alias || config.synctlal, config.link, config.rmH1, config.formatLink, config.formatAnchor, pathPrefixRemap)
+	vaults, err := parseVaults(config.vaults, config.dst, config.strictref, config.formatLink)
+	if err != nil {
+		return nil, err
+	}
+	var idb convert.FileIdDB
+	targetPrefix := ""
+	if config.imprt {
+		idb = convert.NewFileIdDB(config.src)
+		targetPrefix, err = filepath.Rel(config.src, config.tgt)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var calloutRenderer convert.CalloutRenderer
+	if config.callout != "" {
+		calloutRenderer, err = convert.NewCalloutRenderer(config.callout)
+		if err != nil {
+			return nil, err
+		}
+	}
+	calloutAliases, err := parseCalloutAliases(config.calloutAlias)
+	if err != nil {
+		return nil, err
+	}
+	highlightWrapper := ""
+	if config.highlight {
+		highlightWrapper = config.highlightWrapper
+		if highlightWrapper == "" {
+			highlightWrapper = convert.DEFAULT_HIGHLIGHT_WRAPPER
+		}
+	}
+	tagLinkTemplate := ""
+	if config.linktag {
+		tagLinkTemplate = config.tagLinkTemplate
+		if tagLinkTemplate == "" {
+			tagLinkTemplate = convert.DEFAULT_TAG_LINK_TEMPLATE
+		}
+	}
+	tocMinLevel, tocMaxLevel := tocLevels(config)
+	var dates *fileDateIndex
+	if config.setdate || config.setlastmod {
+		dates = newFileDateIndex(config.tgt)
+	}
+	// ファイルごとではなく, 最初に一度だけ filter を parse する
+	var filter *nodeImpl
+	if config.filter != "" {
+		filter, err = compileFilter(config.filter)
+		if err != nil {
+			return nil, newMainErrf(MAIN_ERR_KIND_INVALID_FILTER_FORMAT, "%s has an invalid format: %v", FLAG_FILTER, err)
+		}
+	}
+	examinator := newYamlExaminatorImpl(filter, config.publishable)
+	var pages []convert.Page
+	var pageWarnings []error
+	if config.dataview {
+		// 変換しないノートへのリンクを作らないように, 変換と同じ examinator で選ぶ
+		pages, pageWarnings, err = newPageIndex(config.src, config.tgt, skipper, examinator)
+		if err != nil {
+			return nil, err
+		}
+	}
+	tagRules, err := newTagRules(config.expandtag, config.tagMap, config.lowertag, config.slugtag, config.routeTag, config.fmtagrule)
+	if err != nil {
+		return nil, err
+	}
+	bc := newBodyConverterImpl(bodyConverterOptions{
+		db:                    db,
+		cptag:                 config.cptag || config.synctag,
+		rmtag:                 config.rmtag,
+		cmmt:                  config.cmmt,
+		title:                 config.title || config.alias || config.synctlal,
+		link:                  config.link,
+		rmH1:                  config.rmH1,
+		formatLink:            config.formatLink,
+		anchorFormattingStyle: config.formatAnchor,
+		pathPrefixRemap:       pathPrefixRemap,
+		vaults:                vaults,
+		idb:                   idb,
+		importtag:             config.importtag,
+		vault:                 config.src,
+		targetPrefix:          targetPrefix,
+		footnote:              config.footnote,
+		calloutRenderer:       calloutRenderer,
+		calloutAliases:        calloutAliases,
+		highlightWrapper:      highlightWrapper,
+		htmlComment:           config.htmlcmmt,
+		pages:                 pages,
+		inlineField:           config.inlineField,
+		rmInlineField:         config.rmInlineField,
+		taskCount:             config.taskCount,
+		mathStyle:             config.math,
+		setMath:               config.setmath,
+		tagLinkTemplate:       tagLinkTemplate,
+		tagRules:              tagRules,
+		titleSources:          config.fallbackTitle || config.titleTemplate != "",
+		headingShift:          config.shiftHeadings,
+		toc:                   config.toc,
+		tocAfterH1:            config.tocAfterH1,
+		tocMinLevel:           tocMinLevel,
+		tocMaxLevel:           tocMaxLevel,
+		summaryLength:         summaryLength(config),
+		readingTime:           config.readingTime,
+		dates:                 dates,
+	})
 	metaKeyRemap, err := parseRemap(config.remapkey)
 	if err != nil {
 		return nil, err
 	}
-	yc := newYamlConverterImpl(config.synctag, config.synctlal, config.publishable, metaKeyRemap)
-	passer := newArgPasserImpl(config.title || config.synctlal, config.alias || config.synctlal)
-	examinator := newYamlExaminatorImpl(config.filter, config.publishable)
-	return newProcessorImplWithErrHandling(config.debug, process.NewProcessor(bc, yc, passer, examinator)), nil
+	fmrules, err := readFrontMatterRules(config.fmrules)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := readFrontMatterSchema(config.schema)
+	if err != nil {
+		return nil, err
+	}
+	yc := newYamlConverterImpl(yamlConverterOptions{
+		synctag:          config.synctag,
+		synctlal:         config.synctlal,
+		publishable:      config.publishable,
+		remap:            metaKeyRemap,
+		importtag:        config.importtag,
+		inlineFieldMerge: config.inlineFieldMerge,
+		tagRules:         tagRules,
+		dateOptions:      newDateOptions(config),
+		rules:            fmrules,
+		schema:           schema,
+	})
+	titleTemplate, err := parseTitleTemplate(config.titleTemplate)
+	if err != nil {
+		return nil, err
+	}
+	passer := newArgPasserImpl(config.title || config.synctlal, config.alias || config.synctlal, config.fallbackTitle, titleTemplate)
+	var sub process.Processor = process.NewProcessor(bc, yc, passer, examinator, process.FrontMatterFormat(config.fmformat))
+	if config.fixfm {
+		sub = newFrontMatterFixer(sub)
+	}
+	processor = newProcessorImplWithErrHandling(config.debug, sub)
+	// 読めなかったノートは変換のときのエラーといっしょに出力する
+	processor.errbuf = append(processor.errbuf, pageWarnings...)
+	return processor, nil
 }
 
 func handleErr(path string, err error) (public error, debug error, buffered error) {
@@ -96,3 +236,11 @@ func handleErr(path string, err error) (public error, debug error, buffered erro
 	// 想定済みのエラー
 	return nil, nil, errors.Wrapf(ee, "[ERROR] path: %s, around line: %d", path, line)
 }
+
+func handleWarning(path string, warning error) error {
+	e, ok := errors.Cause(warning).(convert.ErrConvert)
+	if !ok {
+		return errors.Wrapf(warning, "[WARNING] path: %s", path)
+	}
+	return errors.Wrapf(e.Source(), "[WARNING] path: %s, around line: %d", path, e.Line())
+}
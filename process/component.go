@@ -2,14 +2,18 @@ package process
 
 type BodyConvAuxOut interface{}
 
+// front matter は元の形式によらず 1 度だけ FrontMatter に読んで渡される. YAML に直してから渡すわけではない.
+// 元の形式は FrontMatter.Format で分かる. 名前に Yaml とあっても TOML と JSON の front matter をそのまま扱う.
+// YamlConverter は変更する key だけを FrontMatter の上で書き換え, 書き出すときに ProcessorImpl が -frontmatter の形式に直す.
+
 type BodyConverter interface {
-	ConvertBody(raw []rune, selfRelativePath string) (output []rune, aux BodyConvAuxOut, err error)
+	ConvertBody(raw []rune, frontMatter *FrontMatter, selfRelativePath string) (output []rune, aux BodyConvAuxOut, err error)
 }
 
 type YamlConvAuxIn interface{}
 
 type YamlConverter interface {
-	ConvertYAML(raw []byte, aux YamlConvAuxIn) (output []byte, err error)
+	ConvertYAML(fm *FrontMatter, aux YamlConvAuxIn) (err error)
 }
 
 type ArgPasser interface {
@@ -17,5 +21,5 @@ type ArgPasser interface {
 }
 
 type YamlExaminator interface {
-	ExamineYaml(yml []byte) (beProcessed bool, err error)
+	ExamineYaml(fm *FrontMatter, body []rune, relativePath string) (beProcessed bool, err error)
 }
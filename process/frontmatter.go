@@ -0,0 +1,697 @@
+package process
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// front matter の書き方.
+// BodyConverter, YamlConverter, YamlExaminator にはどの形式の front matter も FrontMatter にして渡す.
+type FrontMatterFormat string
+
+const (
+	FRONT_MATTER_YAML FrontMatterFormat = "yaml" // --- で囲む
+	FRONT_MATTER_TOML FrontMatterFormat = "toml" // +++ で囲む
+	FRONT_MATTER_JSON FrontMatterFormat = "json" // ファイルの先頭の { から } まで
+)
+
+var FRONT_MATTER_FORMATS = []string{string(FRONT_MATTER_YAML), string(FRONT_MATTER_TOML), string(FRONT_MATTER_JSON)}
+
+var utf8BOM = []byte("\ufeff")
+
+// ファイルの中身をバイト単位で front matter と本文に分けたもの.
+// 書き出すときは Join で BOM と改行を元に戻す.
+type Document struct {
+	Format      FrontMatterFormat // front matter がなければ空
+	FrontMatter []byte            // 区切りの行を除いた front matter. JSON の場合は { から } まで. 改行は \n にそろえる
+	BodyLine    int               // 本文より前の行数
+	body        []byte            // 元のバイト列のままの本文
+	bom         bool              // 先頭に UTF-8 の BOM がある
+	headerCRLF  bool              // 区切りの行を含めて front matter の改行がすべて \r\n
+	bodyCRLF    bool              // 本文の改行がすべて \r\n
+	open, close []byte            // 元の区切りの行. 改行を含む
+}
+
+// front matter と本文を切り離す.
+// 区切りの行の後ろの空白は無視する. YAML の front matter は ... でも閉じられる.
+func SplitFrontMatter(content []byte) *Document {
+	d := new(Document)
+	if bytes.HasPrefix(content, utf8BOM) {
+		d.bom = true
+		content = content[len(utf8BOM):]
+	}
+
+	var raw []byte
+	start := -1
+	if len(content) > 0 && content[0] == '{' {
+		if raw, start = splitJSONFrontMatter(content); start >= 0 {
+			d.Format = FRONT_MATTER_JSON
+		}
+	} else if raw, start = d.splitDelimitedFrontMatter(content, "---", "---", "..."); start >= 0 {
+		d.Format = FRONT_MATTER_YAML
+	} else if raw, start = d.splitDelimitedFrontMatter(content, "+++", "+++"); start >= 0 {
+		d.Format = FRONT_MATTER_TOML
+	}
+	if start < 0 {
+		d.body = content
+		d.bodyCRLF = isCRLF(content)
+		// front matter を追加するときは本文に合わせる
+		d.headerCRLF = d.bodyCRLF
+		return d
+	}
+	d.FrontMatter = bytes.ReplaceAll(raw, []byte("\r\n"), []byte("\n"))
+	d.BodyLine = bytes.Count(content[:start], []byte("\n"))
+	d.body = content[start:]
+	d.headerCRLF = isCRLF(content[:start])
+	d.bodyCRLF = isCRLF(d.body)
+	return d
+}
+
+func isCRLF(b []byte) bool {
+	n := bytes.Count(b, []byte("\n"))
+	return n > 0 && bytes.Count(b, []byte("\r\n")) == n
+}
+
+func toCRLF(b []byte) []byte {
+	return bytes.ReplaceAll(bytes.ReplaceAll(b, []byte("\r\n"), []byte("\n")), []byte("\n"), []byte("\r\n"))
+}
+
+// start は本文の始まりの位置. 閉じる区切りがなければ -1
+func (d *Document) splitDelimitedFrontMatter(content []byte, open string, closes ...string) (raw []byte, start int) {
+	first, begin := readLine(content, 0)
+	if !isDelimiterLine(first, open) {
+		return nil, -1
+	}
+	for cur := begin; cur < len(content); {
+		line, next := readLine(content, cur)
+		if isDelimiterLine(line, closes...) {
+			d.open = content[:begin]
+			d.close = content[cur:next]
+			// ファイルの最後の行でも本文を続けて書けるように改行を足す
+			if !bytes.HasSuffix(d.close, []byte("\n")) {
+				d.close = append(append([]byte{}, d.close...), '\n')
+			}
+			return content[begin:cur], next
+		}
+		cur = next
+	}
+	return nil, -1
+}
+
+// start からの 1 行を改行を除いて返す. next は次の行の始まり
+func readLine(content []byte, start int) (line []byte, next int) {
+	end := bytes.IndexByte(content[start:], '\n')
+	if end < 0 {
+		return bytes.TrimSuffix(content[start:], []byte("\r")), len(content)
+	}
+	return bytes.TrimSuffix(content[start:start+end], []byte("\r")), start + end + 1
+}
+
+func isDelimiterLine(line []byte, delimiters ...string) bool {
+	trimmed := string(bytes.TrimRight(line, " \t"))
+	for _, delimiter := range delimiters {
+		if trimmed == delimiter {
+			return true
+		}
+	}
+	return false
+}
+
+// 閉じる } の後ろは改行まで空白しか書けない
+func splitJSONFrontMatter(content []byte) (raw []byte, start int) {
+	dec := json.NewDecoder(bytes.NewReader(content))
+	var object map[string]json.RawMessage
+	if err := dec.Decode(&object); err != nil {
+		return nil, -1
+	}
+	end := int(dec.InputOffset())
+	rest, next := readLine(content, end)
+	if len(bytes.TrimSpace(rest)) > 0 {
+		return nil, -1
+	}
+	return content[:end], next
+}
+
+// 本文. 改行がすべて \r\n なら \n にそろえる
+func (d *Document) Body() []rune {
+	if d.bodyCRLF {
+		return []rune(string(bytes.ReplaceAll(d.body, []byte("\r\n"), []byte("\n"))))
+	}
+	return []rune(string(d.body))
+}
+
+// 元と同じ形式なら元の区切りの行をそのまま使う
+func (d *Document) FormatFrontMatter(fm *FrontMatter, format FrontMatterFormat) ([]byte, error) {
+	if format != d.Format || d.open == nil {
+		return FormatFrontMatter(fm, format)
+	}
+	inner, err := fm.Encode(format)
+	if err != nil {
+		return nil, err
+	}
+	output := make([]byte, 0, len(d.open)+len(inner)+len(d.close))
+	output = append(output, d.open...)
+	output = append(output, inner...)
+	return append(output, d.close...), nil
+}
+
+// BOM と \r\n の改行を元に戻してつなげる
+func (d *Document) Join(frontMatter []byte, body []rune) []byte {
+	if d.headerCRLF {
+		frontMatter = toCRLF(frontMatter)
+	}
+	b := []byte(string(body))
+	if d.bodyCRLF {
+		b = toCRLF(b)
+	}
+	output := make([]byte, 0, len(utf8BOM)+len(frontMatter)+len(b))
+	if d.bom {
+		output = append(output, utf8BOM...)
+	}
+	output = append(output, frontMatter...)
+	return append(output, b...)
+}
+
+// yaml front matter と本文を切り離す. TOML と JSON の front matter も YAML にする.
+// 読めない front matter は本文として扱う.
+func SplitMarkdown(content []rune) (yml []byte, body []rune) {
+	d := SplitFrontMatter([]byte(string(content)))
+	fm, err := ParseFrontMatter(d.FrontMatter, d.Format)
+	if err != nil {
+		return nil, content
+	}
+	if fm.Len() == 0 && d.Format != FRONT_MATTER_YAML {
+		return []byte{}, d.Body()
+	}
+	yml, err = fm.Encode(FRONT_MATTER_YAML)
+	if err != nil {
+		return nil, content
+	}
+	return yml, d.Body()
+}
+
+// 形式によらない front matter. 最上位の key の順番を保ち, 値を yaml.v3 の Node で持つ.
+// 書き出すときは変更しなかった key を元のバイト列のまま残し, 変更した key だけを書き直す.
+type FrontMatter struct {
+	format         FrontMatterFormat
+	raw            []byte
+	doc            *yamlv3.Node        // YAML の document. コメントを含む
+	origin         []*frontMatterEntry // 読み込んだときの key
+	entries        []*frontMatterEntry
+	splice         bool // 元のバイト列を key ごとに切り貼りできる
+	indentSequence bool // YAML のリストの - を key より字下げする
+	tail           int  // YAML は最後の key の後ろのコメントの始まり, TOML は key を追加する位置
+}
+
+type frontMatterEntry struct {
+	key    *yamlv3.Node
+	value  *yamlv3.Node
+	origin *frontMatterEntry // 追加した key は nil
+	span   *entrySpan        // 元の front matter での位置. 切り貼りできない key は nil
+}
+
+// バイト単位の位置. start から end までが key 全体で, YAML では start から keyStart までが key の前のコメントと空行.
+type entrySpan struct {
+	start, end           int
+	keyStart, keyEnd     int
+	valueStart, valueEnd int
+}
+
+// raw は区切りの行を除いた front matter. format が空なら YAML として読む.
+func ParseFrontMatter(raw []byte, format FrontMatterFormat) (*FrontMatter, error) {
+	f := &FrontMatter{format: format, raw: raw}
+	// key を追加しても前の行とつながらないように最後の改行をそろえる
+	if format != FRONT_MATTER_JSON && len(raw) > 0 && raw[len(raw)-1] != '\n' {
+		f.raw = append(raw[:len(raw):len(raw)], '\n')
+	}
+	var err error
+	switch format {
+	case FRONT_MATTER_TOML:
+		err = f.parseTOML()
+	case FRONT_MATTER_JSON:
+		err = f.parseJSON()
+	default:
+		err = f.parseYAML()
+	}
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	for _, o := range f.origin {
+		if seen[o.key.Value] {
+			return nil, fmt.Errorf("duplicate key in front matter: %s", o.key.Value)
+		}
+		seen[o.key.Value] = true
+		// 値を読めることを先に確かめておく
+		if _, err := decodeNode(o.value, false); err != nil {
+			return nil, fmt.Errorf("failed to read %s in front matter: %w", o.key.Value, err)
+		}
+		f.entries = append(f.entries, &frontMatterEntry{key: o.key, value: o.value, origin: o})
+	}
+	return f, nil
+}
+
+// 元の形式. front matter がなかった場合は空
+func (f *FrontMatter) Format() FrontMatterFormat {
+	return f.format
+}
+
+func (f *FrontMatter) Len() int {
+	return len(f.entries)
+}
+
+func (f *FrontMatter) Keys() []string {
+	keys := make([]string, 0, len(f.entries))
+	for _, e := range f.entries {
+		keys = append(keys, e.key.Value)
+	}
+	return keys
+}
+
+func (f *FrontMatter) index(key string) int {
+	for i, e := range f.entries {
+		if e.key.Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func (f *FrontMatter) Has(key string) bool {
+	return f.index(key) >= 0
+}
+
+// yaml.v2 で読んだときと同じ型で返す. mapping は map[interface{}]interface{}, 日時は書かれたままの文字列.
+func (f *FrontMatter) Get(key string) (v interface{}, ok bool) {
+	i := f.index(key)
+	if i < 0 {
+		return nil, false
+	}
+	v, _ = decodeNode(f.entries[i].value, false)
+	return v, true
+}
+
+// 値が変わらなければ何もしない. 変わった場合も元の引用符, [a, b] の形式, 行末のコメントを引き継ぐ.
+// 新しい key は最後に追加する.
+func (f *FrontMatter) Set(key string, v interface{}) error {
+	n, err := valueNode(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", key, err)
+	}
+	i := f.index(key)
+	if i < 0 {
+		f.entries = append(f.entries, &frontMatterEntry{key: keyNode(key), value: n})
+		return nil
+	}
+	e := f.entries[i]
+	old, err := decodeNode(e.value, false)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	if newValue, err := decodeNode(n, false); err == nil && reflect.DeepEqual(old, newValue) {
+		return nil
+	}
+	keepStyle(n, e.value)
+	e.value = n
+	return nil
+}
+
+func (f *FrontMatter) Delete(key string) {
+	if i := f.index(key); i >= 0 {
+		f.entries = append(f.entries[:i], f.entries[i+1:]...)
+	}
+}
+
+// to がすでにある場合は from と to のうち先に現れた位置に to を残す
+func (f *FrontMatter) Rename(from string, to string) {
+	i := f.index(from)
+	if i < 0 || from == to {
+		return
+	}
+	j := f.index(to)
+	if j >= 0 && j < i {
+		f.entries[j].value = f.entries[i].value
+		f.entries = append(f.entries[:i], f.entries[i+1:]...)
+		return
+	}
+	k := keyNode(to)
+	k.HeadComment = f.entries[i].key.HeadComment
+	f.entries[i].key = k
+	if j >= 0 {
+		f.entries = append(f.entries[:j], f.entries[j+1:]...)
+	}
+}
+
+// filter や schema に渡すためのコピー. 値の型は Get と同じ.
+func (f *FrontMatter) Map() map[interface{}]interface{} {
+	mapping := &yamlv3.Node{Kind: yamlv3.MappingNode}
+	for _, e := range f.entries {
+		mapping.Content = append(mapping.Content, e.key, e.value)
+	}
+	m, _ := decodeNode(mapping, false)
+	if mm, ok := m.(map[interface{}]interface{}); ok {
+		return mm
+	}
+	return make(map[interface{}]interface{})
+}
+
+// 読み込んだときから key や値が変わったかどうか
+func (f *FrontMatter) Changed() bool {
+	if len(f.entries) != len(f.origin) {
+		return true
+	}
+	for i, e := range f.entries {
+		o := f.origin[i]
+		if e.origin != o || e.key != o.key || e.value != o.value {
+			return true
+		}
+	}
+	return false
+}
+
+// 区切りの行を除いた front matter を format で書く. format が空なら YAML. JSON は } の後の改行まで.
+// 元と同じ形式なら, 変更しなかった key は元のバイト列のまま残す.
+func (f *FrontMatter) Encode(format FrontMatterFormat) ([]byte, error) {
+	if format == "" {
+		format = FRONT_MATTER_YAML
+	}
+	original := f.format
+	if original == "" {
+		original = FRONT_MATTER_YAML
+	}
+	same := format == original && f.splice
+	switch format {
+	case FRONT_MATTER_YAML:
+		if same {
+			return f.spliceYAML()
+		}
+		return f.encodeYAML()
+	case FRONT_MATTER_TOML:
+		if same {
+			if output, ok, err := f.spliceTOML(); ok || err != nil {
+				return output, err
+			}
+		}
+		m, err := f.mapSlice()
+		if err != nil {
+			return nil, err
+		}
+		return encodeTOML(m)
+	case FRONT_MATTER_JSON:
+		if same {
+			if output, ok, err := f.spliceJSON(); ok || err != nil {
+				return output, err
+			}
+		}
+		m, err := f.mapSlice()
+		if err != nil {
+			return nil, err
+		}
+		output, err := encodeJSON(m)
+		if err != nil {
+			return nil, err
+		}
+		return append(output, '\n'), nil
+	}
+	return nil, fmt.Errorf("unknown front matter format: %s", format)
+}
+
+// TOML と JSON に書くために key の順番を保った yaml.MapSlice にする
+func (f *FrontMatter) mapSlice() (yaml.MapSlice, error) {
+	m := make(yaml.MapSlice, 0, len(f.entries))
+	for _, e := range f.entries {
+		v, err := decodeNode(e.value, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", e.key.Value, err)
+		}
+		m = append(m, yaml.MapItem{Key: e.key.Value, Value: v})
+	}
+	return m, nil
+}
+
+// front matter を format で区切りまで含めて書き出す. format が空なら YAML.
+func FormatFrontMatter(fm *FrontMatter, format FrontMatterFormat) ([]byte, error) {
+	inner, err := fm.Encode(format)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case FRONT_MATTER_TOML:
+		return []byte(fmt.Sprintf("+++\n%s+++\n", string(inner))), nil
+	case FRONT_MATTER_JSON:
+		return inner, nil
+	}
+	return []byte(fmt.Sprintf("---\n%s---\n", string(inner))), nil
+}
+
+// 最上位の key ごとに元のバイト列での位置を覚えておく
+func (f *FrontMatter) parseJSON() error {
+	dec := json.NewDecoder(bytes.NewReader(f.raw))
+	dec.UseNumber()
+	if token, err := dec.Token(); err != nil {
+		return fmt.Errorf("json: %w", err)
+	} else if token != json.Delim('{') {
+		return fmt.Errorf("json: front matter is not an object")
+	}
+	end := int(dec.InputOffset())
+	for dec.More() {
+		span := &entrySpan{start: skipJSONSeparators(f.raw, end)}
+		span.keyStart = span.start
+		key, err := dec.Token()
+		if err != nil {
+			return fmt.Errorf("json: %w", err)
+		}
+		span.keyEnd = int(dec.InputOffset())
+		span.valueStart = skipJSONSeparators(f.raw, span.keyEnd)
+		v, err := readJSONValue(dec)
+		if err != nil {
+			return fmt.Errorf("json: %w", err)
+		}
+		span.valueEnd = int(dec.InputOffset())
+		span.end = span.valueEnd
+		end = span.end
+		n, err := valueNode(v)
+		if err != nil {
+			return fmt.Errorf("json: %w", err)
+		}
+		f.origin = append(f.origin, &frontMatterEntry{key: keyNode(fmt.Sprint(key)), value: n, span: span})
+	}
+	if _, err := dec.Token(); err != nil {
+		return fmt.Errorf("json: %w", err)
+	}
+	f.splice = true
+	return nil
+}
+
+// 空白と , と : を飛ばす
+func skipJSONSeparators(raw []byte, i int) int {
+	for i < len(raw) && strings.IndexByte(" \t\r\n,:", raw[i]) >= 0 {
+		i++
+	}
+	return i
+}
+
+// 変更した値だけを書き直す. 追加する key は最後の key の後ろに同じ字下げで書く.
+// 元の key がすべてなくなった場合は ok = false
+func (f *FrontMatter) spliceJSON() (output []byte, ok bool, err error) {
+	if len(f.origin) == 0 || len(f.entries) == 0 {
+		return nil, false, nil
+	}
+	first, last := f.origin[0].span, f.origin[len(f.origin)-1].span
+	indent := lineIndent(f.raw, first.keyStart)
+	sep := []byte(",\n" + indent)
+	if len(f.origin) > 1 {
+		sep = f.raw[first.end:f.origin[1].span.start]
+	} else if !bytes.ContainsRune(f.raw[:first.keyStart], '\n') {
+		sep = []byte(", ")
+	}
+	b := new(bytes.Buffer)
+	b.Write(f.raw[:first.start])
+	for i, e := range f.entries {
+		if i > 0 {
+			b.Write(sep)
+		}
+		o := e.origin
+		if o != nil && e.key == o.key && e.value == o.value {
+			b.Write(f.raw[o.span.start:o.span.end])
+			continue
+		}
+		if o != nil && e.key == o.key {
+			b.Write(f.raw[o.span.keyStart:o.span.keyEnd])
+		} else if err := writeJSONScalar(b, e.key.Value); err != nil {
+			return nil, false, err
+		}
+		if o != nil {
+			b.Write(f.raw[o.span.keyEnd:o.span.valueStart])
+		} else {
+			b.WriteString(": ")
+		}
+		if o != nil && e.value == o.value {
+			b.Write(f.raw[o.span.valueStart:o.span.valueEnd])
+			continue
+		}
+		v, err := decodeNode(e.value, true)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read %s: %w", e.key.Value, err)
+		}
+		if err := writeJSONValue(b, v, indent); err != nil {
+			return nil, false, err
+		}
+	}
+	b.Write(f.raw[last.end:])
+	b.WriteString("\n")
+	return b.Bytes(), true, nil
+}
+
+// pos を含む行の先頭の空白
+func lineIndent(raw []byte, pos int) string {
+	start := bytes.LastIndexByte(raw[:pos], '\n') + 1
+	end := start
+	for end < pos && (raw[end] == ' ' || raw[end] == '\t') {
+		end++
+	}
+	return string(raw[start:end])
+}
+
+func readJSONValue(dec *json.Decoder) (interface{}, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := token.(type) {
+	case json.Delim:
+		if t == '{' {
+			m := make(yaml.MapSlice, 0)
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				v, err := readJSONValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				m = append(m, yaml.MapItem{Key: key, Value: v})
+			}
+			if _, err := dec.Token(); err != nil {
+				return nil, err
+			}
+			return m, nil
+		}
+		list := make([]interface{}, 0)
+		for dec.More() {
+			v, err := readJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return list, nil
+	case json.Number:
+		if n, err := t.Int64(); err == nil {
+			return int(n), nil
+		}
+		// int64 に収まらない整数は float64 にすると桁が落ちるので, uint64 か文字列のまま残す
+		if !strings.ContainsAny(t.String(), ".eE") {
+			if n, err := strconv.ParseUint(t.String(), 10, 64); err == nil {
+				return n, nil
+			}
+			return t.String(), nil
+		}
+		return t.Float64()
+	}
+	return token, nil
+}
+
+// 2 文字ずつ字下げして書く. 日時は RFC3339 の文字列にする.
+func encodeJSON(m yaml.MapSlice) ([]byte, error) {
+	b := new(bytes.Buffer)
+	if err := writeJSONValue(b, m, ""); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func writeJSONValue(b *bytes.Buffer, v interface{}, indent string) error {
+	switch vv := normalizeYamlMap(v).(type) {
+	case yaml.MapSlice:
+		if len(vv) == 0 {
+			b.WriteString("{}")
+			return nil
+		}
+		b.WriteString("{\n")
+		for i, item := range vv {
+			b.WriteString(indent + "  ")
+			if err := writeJSONScalar(b, fmt.Sprint(item.Key)); err != nil {
+				return err
+			}
+			b.WriteString(": ")
+			if err := writeJSONValue(b, item.Value, indent+"  "); err != nil {
+				return err
+			}
+			if i < len(vv)-1 {
+				b.WriteString(",")
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString(indent + "}")
+	case []interface{}:
+		if len(vv) == 0 {
+			b.WriteString("[]")
+			return nil
+		}
+		b.WriteString("[\n")
+		for i, a := range vv {
+			b.WriteString(indent + "  ")
+			if err := writeJSONValue(b, a, indent+"  "); err != nil {
+				return err
+			}
+			if i < len(vv)-1 {
+				b.WriteString(",")
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString(indent + "]")
+	case time.Time:
+		return writeJSONScalar(b, vv.Format(time.RFC3339Nano))
+	case float64:
+		if math.IsInf(vv, 0) || math.IsNaN(vv) {
+			return fmt.Errorf("json: unsupported value: %v", vv)
+		}
+		return writeJSONScalar(b, vv)
+	default:
+		return writeJSONScalar(b, vv)
+	}
+	return nil
+}
+
+// < や > をエスケープしない
+func writeJSONScalar(b *bytes.Buffer, v interface{}) error {
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("json: %w", err)
+	}
+	b.Write(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+	return nil
+}
+
+func sortMapSlice(ms yaml.MapSlice) {
+	sort.Slice(ms, func(i, j int) bool {
+		return fmt.Sprint(ms[i].Key) < fmt.Sprint(ms[j].Key)
+	})
+}
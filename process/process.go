@@ -1,8 +1,6 @@
 package process
 
 import (
-	"bufio"
-	"fmt"
 	"io"
 	"os"
 	"path/filepath"
@@ -15,19 +13,56 @@ type Processor interface {
 	Process(relativePath, orgpath, newpath string) (err error)
 }
 
+// 処理を止めずに報告だけするエラー.
+// BodyConverter や YamlConverter がこれを返した場合も, 変換結果は書き込まれる.
+type ErrWarning interface {
+	error
+	Warnings() []error
+}
+
+type errWarningImpl struct {
+	warnings []error
+}
+
+func NewErrWarning(warnings ...error) ErrWarning {
+	return &errWarningImpl{warnings: warnings}
+}
+
+func (e *errWarningImpl) Warnings() []error {
+	return e.warnings
+}
+
+func (e *errWarningImpl) Error() string {
+	msgs := make([]string, len(e.warnings))
+	for i, w := range e.warnings {
+		msgs[i] = w.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// err が ErrWarning の場合は警告を取り出して, err を nil にする
+func takeWarnings(err error) (warnings []error, rest error) {
+	if w, ok := errors.Cause(err).(ErrWarning); ok {
+		return w.Warnings(), nil
+	}
+	return nil, err
+}
+
 type ProcessorImpl struct {
 	BodyConverter
 	YamlConverter
 	ArgPasser
 	YamlExaminator
+	format FrontMatterFormat // 書き出す front matter の形式. 空なら元の形式のまま
 }
 
-func NewProcessor(bc BodyConverter, yc YamlConverter, passer ArgPasser, examinator YamlExaminator) Processor {
+func NewProcessor(bc BodyConverter, yc YamlConverter, passer ArgPasser, examinator YamlExaminator, format FrontMatterFormat) Processor {
 	return &ProcessorImpl{
 		BodyConverter:  bc,
 		YamlConverter:  yc,
 		ArgPasser:      passer,
 		YamlExaminator: examinator,
+		format:         format,
 	}
 }
 
@@ -43,30 +78,33 @@ func (p *ProcessorImpl) Process(relativePath, orgpath, newpath string) error {
 		if err != nil {
 			return err
 		}
-		defer newfile.Close()
-
-		io.Copy(newfile, file)
-		return nil
+		if _, err := io.Copy(newfile, file); err != nil {
+			newfile.Close()
+			return errors.Wrapf(err, "failed to copy %s to %s", orgpath, newpath)
+		}
+		return newfile.Close()
 	}
 
-	readFrom, err := os.Open(orgpath)
+	content, err := os.ReadFile(orgpath)
 	if err != nil {
-		return errors.Errorf("failed to open %s", orgpath)
+		return errors.Wrapf(err, "failed to read %s", orgpath)
 	}
-	content, err := io.ReadAll(readFrom)
+
+	doc := SplitFrontMatter(content)
+	format := doc.Format
+	fm, err := ParseFrontMatter(doc.FrontMatter, format)
 	if err != nil {
-		return errors.New("failed to read file")
+		return errors.Wrapf(err, "failed to read %s front matter", format)
 	}
-	readFrom.Close()
-
-	yml, body := splitMarkdown([]rune(string(content)))
-	if ok, err := p.ExamineYaml(yml); err != nil {
-		return errors.Wrap(err, "failed to examine yaml front mattter")
+	body := doc.Body()
+	if ok, err := p.ExamineYaml(fm, body, relativePath); err != nil {
+		return errors.Wrapf(err, "failed to examine %s front matter", fm.Format())
 	} else if !ok {
 		return nil
 	}
 
-	output, frombody, err := p.ConvertBody(body, relativePath)
+	output, frombody, err := p.ConvertBody(body, fm, relativePath)
+	warnings, err := takeWarnings(err)
 	if err != nil {
 		return errors.Wrap(err, "failed to convert body")
 	}
@@ -76,62 +114,33 @@ func (p *ProcessorImpl) Process(relativePath, orgpath, newpath string) error {
 		return errors.Wrap(err, "failed to pass args from body converter to yaml converter")
 	}
 
-	yml, err = p.ConvertYAML(yml, toyaml)
-	if err != nil {
-		return errors.Wrap(err, "failed to convert yaml")
-	}
-
-	// os.Create によってファイルの内容は削除されるので,
-	// 変換がすべて正常に行われた後で, 書き込み先のファイルを開く
-	writeTo, err := os.Create(newpath)
+	err = p.ConvertYAML(fm, toyaml)
+	yamlWarnings, err := takeWarnings(err)
+	warnings = append(warnings, yamlWarnings...)
 	if err != nil {
-		return errors.Wrapf(err, "failed to create %s", newpath)
-	}
-	defer writeTo.Close()
-
-	// front matter
-	if yml != nil {
-		fmt.Fprintf(writeTo, "---\n%s---\n", string(yml))
-	}
-
-	// body
-	io.WriteString(writeTo, string(output))
-	return nil
-}
-
-// yaml front matter と本文を切り離す
-func splitMarkdown(content []rune) (yml []byte, body []rune) {
-	scanner := bufio.NewScanner(strings.NewReader(string(content)))
-
-	if !scanner.Scan() {
-		return nil, nil
+		return errors.Wrapf(err, "failed to convert %s front matter", fm.Format())
 	}
 
-	// --- が見つからなかったら front matter なし
-	if scanner.Text() != "---" {
-		return nil, content
+	// -frontmatter が指定されていなければ元の形式で書き出す
+	if p.format != "" {
+		format = p.format
 	}
-
-	// --- が見つかるまで front matter に追加していく
-	frontMatter := make([]rune, 0)
-	endFound := false
-	for scanner.Scan() {
-		if scanner.Text() == "---" {
-			endFound = true
-			break
-		} else {
-			frontMatter = append(frontMatter, []rune(scanner.Text())...)
-			frontMatter = append(frontMatter, '\n')
+	var frontMatter []byte
+	if fm.Len() > 0 {
+		frontMatter, err = doc.FormatFrontMatter(fm, format)
+		if err != nil {
+			return errors.Wrap(err, "failed to format front matter")
 		}
 	}
 
-	if !endFound {
-		return nil, content
+	// 書き込み先のファイルの内容は削除されるので,
+	// 変換がすべて正常に行われた後で書き込む
+	if err := os.WriteFile(newpath, doc.Join(frontMatter, output), 0666); err != nil {
+		return errors.Wrapf(err, "failed to write %s", newpath)
 	}
 
-	for scanner.Scan() {
-		body = append(body, []rune(scanner.Text())...)
-		body = append(body, '\n')
+	if len(warnings) > 0 {
+		return NewErrWarning(warnings...)
 	}
-	return []byte(string(frontMatter)), body
+	return nil
 }
@@ -1,6 +1,10 @@
 package process
 
-import "testing"
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
 
 func TestSplitMarkdown(t *testing.T) {
 	cases := []struct {
@@ -31,7 +35,7 @@ func TestSplitMarkdown(t *testing.T) {
 	}
 
 	for _, tt := range cases {
-		gotFrontMatter, gotBody := splitMarkdown([]rune(tt.input))
+		gotFrontMatter, gotBody := SplitMarkdown([]rune(tt.input))
 		if string(gotFrontMatter) != tt.wantFrontMatter {
 			t.Errorf("[ERROR] got %q, want: %q", string(gotFrontMatter), tt.wantFrontMatter)
 		}
@@ -40,3 +44,425 @@ func TestSplitMarkdown(t *testing.T) {
 		}
 	}
 }
+
+func TestSplitFrontMatter(t *testing.T) {
+	longLine := strings.Repeat("a", 100000)
+	cases := []struct {
+		name         string
+		input        string
+		wantRaw      string
+		wantFormat   FrontMatterFormat
+		wantBody     string
+		wantBodyLine int
+	}{
+		{
+			name:         "yaml",
+			input:        "---\ntitle: test\n---\n# test\n",
+			wantRaw:      "title: test\n",
+			wantFormat:   FRONT_MATTER_YAML,
+			wantBody:     "# test\n",
+			wantBodyLine: 3,
+		},
+		{
+			name:         "toml",
+			input:        "+++\ntitle = \"test\"\n+++\n# test\n",
+			wantRaw:      "title = \"test\"\n",
+			wantFormat:   FRONT_MATTER_TOML,
+			wantBody:     "# test\n",
+			wantBodyLine: 3,
+		},
+		{
+			name:         "json",
+			input:        "{\n  \"title\": \"test\"\n}\n# test\n",
+			wantRaw:      "{\n  \"title\": \"test\"\n}",
+			wantFormat:   FRONT_MATTER_JSON,
+			wantBody:     "# test\n",
+			wantBodyLine: 3,
+		},
+		{
+			name:       "json followed by text on the same line",
+			input:      "{\"title\": \"test\"} # test\n",
+			wantRaw:    "",
+			wantFormat: "",
+			wantBody:   "{\"title\": \"test\"} # test\n",
+		},
+		{
+			name:       "mismatched delimiters",
+			input:      "+++\ntitle = \"test\"\n---\n# test\n",
+			wantRaw:    "",
+			wantFormat: "",
+			wantBody:   "+++\ntitle = \"test\"\n---\n# test\n",
+		},
+		{
+			name:         "crlf",
+			input:        "---\r\ntitle: test\r\n---\r\n# test\r\n\r\ntext\r\n",
+			wantRaw:      "title: test\n",
+			wantFormat:   FRONT_MATTER_YAML,
+			wantBody:     "# test\n\ntext\n",
+			wantBodyLine: 3,
+		},
+		{
+			name:         "bom",
+			input:        "\ufeff---\ntitle: test\n---\n# test\n",
+			wantRaw:      "title: test\n",
+			wantFormat:   FRONT_MATTER_YAML,
+			wantBody:     "# test\n",
+			wantBodyLine: 3,
+		},
+		{
+			name:         "delimiters with trailing spaces and ... terminator",
+			input:        "--- \ntitle: test\n...\t\n# test\n",
+			wantRaw:      "title: test\n",
+			wantFormat:   FRONT_MATTER_YAML,
+			wantBody:     "# test\n",
+			wantBodyLine: 3,
+		},
+		{
+			name:       "... does not close toml",
+			input:      "+++\ntitle = \"test\"\n...\n# test\n",
+			wantRaw:    "",
+			wantFormat: "",
+			wantBody:   "+++\ntitle = \"test\"\n...\n# test\n",
+		},
+		{
+			name:         "long lines",
+			input:        "---\nimage: " + longLine + "\n---\n" + longLine + "\nend",
+			wantRaw:      "image: " + longLine + "\n",
+			wantFormat:   FRONT_MATTER_YAML,
+			wantBody:     longLine + "\nend",
+			wantBodyLine: 3,
+		},
+		{
+			name:         "no newline after closing delimiter",
+			input:        "---\ntitle: test\n---",
+			wantRaw:      "title: test\n",
+			wantFormat:   FRONT_MATTER_YAML,
+			wantBody:     "",
+			wantBodyLine: 2,
+		},
+	}
+
+	for _, tt := range cases {
+		d := SplitFrontMatter([]byte(tt.input))
+		if string(d.FrontMatter) != tt.wantRaw {
+			t.Errorf("[ERROR | raw - %s] got: %q, want: %q", tt.name, truncate(string(d.FrontMatter)), truncate(tt.wantRaw))
+		}
+		if d.Format != tt.wantFormat {
+			t.Errorf("[ERROR | format - %s] got: %q, want: %q", tt.name, d.Format, tt.wantFormat)
+		}
+		if got := string(d.Body()); got != tt.wantBody {
+			t.Errorf("[ERROR | body - %s] got: %q, want: %q", tt.name, truncate(got), truncate(tt.wantBody))
+		}
+		if d.BodyLine != tt.wantBodyLine {
+			t.Errorf("[ERROR | body line - %s] got: %d, want: %d", tt.name, d.BodyLine, tt.wantBodyLine)
+		}
+
+		// front matter は変換しなければ元のバイト列に戻る
+		if d.Format == "" || !strings.HasSuffix(tt.input, "\n") {
+			continue
+		}
+		fm, err := ParseFrontMatter(d.FrontMatter, d.Format)
+		if err != nil {
+			t.Errorf("[FATAL | %s] unexpected error occurred: %v", tt.name, err)
+			continue
+		}
+		frontMatter, err := d.FormatFrontMatter(fm, d.Format)
+		if err != nil {
+			t.Errorf("[FATAL | %s] unexpected error occurred: %v", tt.name, err)
+			continue
+		}
+		if got := string(d.Join(frontMatter, d.Body())); got != tt.input {
+			t.Errorf("[ERROR | join - %s] got: %q, want: %q", tt.name, truncate(got), truncate(tt.input))
+		}
+	}
+}
+
+func truncate(s string) string {
+	if len(s) > 100 {
+		return s[:100] + "..."
+	}
+	return s
+}
+
+func TestParseFrontMatter(t *testing.T) {
+	cases := []struct {
+		name    string
+		raw     string
+		format  FrontMatterFormat
+		wantYml string
+		wantErr bool
+	}{
+		{
+			name:    "yaml is unchanged",
+			raw:     "title: test\n",
+			format:  FRONT_MATTER_YAML,
+			wantYml: "title: test\n",
+		},
+		{
+			name: "toml",
+			raw: "title = \"test\" # comment\ntags = ['a', \"b\"]\ndraft = false\ncount = 0x10\n" +
+				"date = 2021-01-02T03:04:05Z\n\n[params]\nsub.key = 1.5\n\n[[links]]\nname = \"x\"\n",
+			format:  FRONT_MATTER_TOML,
+			wantYml: "title: test\ntags:\n- a\n- b\ndraft: false\ncount: 16\ndate: 2021-01-02T03:04:05Z\nparams:\n  sub:\n    key: 1.5\nlinks:\n- name: x\n",
+		},
+		{
+			name:    "toml multiline string",
+			raw:     "text = \"\"\"\nline1\nline2\"\"\"\n",
+			format:  FRONT_MATTER_TOML,
+			wantYml: "text: |-\n  line1\n  line2\n",
+		},
+		{
+			name:    "invalid toml",
+			raw:     "title = \n",
+			format:  FRONT_MATTER_TOML,
+			wantErr: true,
+		},
+		{
+			name:    "toml with duplicate keys",
+			raw:     "title = \"a\"\ntitle = \"b\"\n",
+			format:  FRONT_MATTER_TOML,
+			wantErr: true,
+		},
+		{
+			name:    "json keeps key order",
+			raw:     "{\"title\": \"test\", \"count\": 3, \"ratio\": 0.5, \"tags\": [\"a\"], \"a\": null}",
+			format:  FRONT_MATTER_JSON,
+			wantYml: "title: test\ncount: 3\nratio: 0.5\ntags:\n- a\na: null\n",
+		},
+		{
+			name:    "json big integers",
+			raw:     "{\"id\": 12345678901234567890, \"big\": 123456789012345678901234567890, \"neg\": -9223372036854775809}",
+			format:  FRONT_MATTER_JSON,
+			wantYml: "id: 12345678901234567890\nbig: \"123456789012345678901234567890\"\nneg: \"-9223372036854775809\"\n",
+		},
+		{
+			name:    "empty json",
+			raw:     "{}",
+			format:  FRONT_MATTER_JSON,
+			wantYml: "",
+		},
+	}
+
+	for _, tt := range cases {
+		fm, err := ParseFrontMatter([]byte(tt.raw), tt.format)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("[ERROR | %s] expected an error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("[FATAL | %s] unexpected error occurred: %v", tt.name, err)
+			continue
+		}
+		got, err := fm.Encode(FRONT_MATTER_YAML)
+		if err != nil {
+			t.Errorf("[FATAL | %s] unexpected error occurred: %v", tt.name, err)
+			continue
+		}
+		if fm.Len() == 0 {
+			got = nil
+		}
+		if string(got) != tt.wantYml {
+			t.Errorf("[ERROR | %s] got: %q, want: %q", tt.name, string(got), tt.wantYml)
+		}
+	}
+}
+
+func TestFormatFrontMatter(t *testing.T) {
+	yml := "title: \"test\"\ntags:\n- a\n- b\ndraft: true\ndate: 2021-01-02\nparams:\n  weight: 1\n  ratio: 0.5\nlinks:\n- name: x\n- name: \"y\"\n"
+	cases := []struct {
+		format FrontMatterFormat
+		want   string
+	}{
+		{
+			format: FRONT_MATTER_YAML,
+			want:   "---\n" + yml + "---\n",
+		},
+		{
+			format: FRONT_MATTER_TOML,
+			want:   "+++\ntitle = \"test\"\ntags = [\"a\", \"b\"]\ndraft = true\ndate = 2021-01-02\n\n[params]\nweight = 1\nratio = 0.5\n\n[[links]]\nname = \"x\"\n\n[[links]]\nname = \"y\"\n+++\n",
+		},
+		{
+			format: FRONT_MATTER_JSON,
+			want:   "{\n  \"title\": \"test\",\n  \"tags\": [\n    \"a\",\n    \"b\"\n  ],\n  \"draft\": true,\n  \"date\": \"2021-01-02\",\n  \"params\": {\n    \"weight\": 1,\n    \"ratio\": 0.5\n  },\n  \"links\": [\n    {\n      \"name\": \"x\"\n    },\n    {\n      \"name\": \"y\"\n    }\n  ]\n}\n",
+		},
+	}
+
+	fm, err := ParseFrontMatter([]byte(yml), FRONT_MATTER_YAML)
+	if err != nil {
+		t.Fatalf("[FATAL] unexpected error occurred: %v", err)
+	}
+	want, err := fm.mapSlice()
+	if err != nil {
+		t.Fatalf("[FATAL] unexpected error occurred: %v", err)
+	}
+	for _, tt := range cases {
+		got, err := FormatFrontMatter(fm, tt.format)
+		if err != nil {
+			t.Errorf("[FATAL | %s] unexpected error occurred: %v", tt.format, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("[ERROR | %s] got: %q, want: %q", tt.format, string(got), tt.want)
+		}
+
+		// 書き出した front matter を読み直すと同じ内容になる
+		d := SplitFrontMatter(got)
+		if d.Format != tt.format {
+			t.Errorf("[ERROR | %s] read back as %q", tt.format, d.Format)
+			continue
+		}
+		back, err := ParseFrontMatter(d.FrontMatter, d.Format)
+		if err != nil {
+			t.Errorf("[FATAL | %s] unexpected error occurred while reading back: %v", tt.format, err)
+			continue
+		}
+		gotBack, err := back.mapSlice()
+		if err != nil {
+			t.Errorf("[FATAL | %s] unexpected error occurred while reading back: %v", tt.format, err)
+			continue
+		}
+		if !reflect.DeepEqual(gotBack, want) {
+			t.Errorf("[ERROR | %s] read back: %v, want: %v", tt.format, gotBack, want)
+		}
+	}
+}
+
+func TestFrontMatterEdit(t *testing.T) {
+	cases := []struct {
+		name   string
+		raw    string
+		format FrontMatterFormat
+		edit   func(fm *FrontMatter) error
+		want   string
+	}{
+		{
+			name:   "yaml keeps untouched keys and comments",
+			raw:    "# head\ntitle: 'old' # title\ntags: [a, b]\n\n# nested\nparams:\n  x: 1 # x\n# tail\n",
+			format: FRONT_MATTER_YAML,
+			edit: func(fm *FrontMatter) error {
+				if err := fm.Set("tags", []string{"a", "b"}); err != nil {
+					return err
+				}
+				if err := fm.Set("draft", true); err != nil {
+					return err
+				}
+				return fm.Set("title", "new")
+			},
+			want: "# head\ntitle: 'new' # title\ntags: [a, b]\n\n# nested\nparams:\n  x: 1 # x\ndraft: true\n# tail\n",
+		},
+		{
+			name:   "yaml rename keeps position",
+			raw:    "tag: a\n# keep\ndate: 2021-01-02\nalias: [x]\n",
+			format: FRONT_MATTER_YAML,
+			edit: func(fm *FrontMatter) error {
+				fm.Rename("tag", "tags")
+				fm.Rename("alias", "aliases")
+				fm.Delete("date")
+				return fm.Set("tags", []string{"a", "b"})
+			},
+			want: "tags:\n- a\n- b\naliases: [x]\n",
+		},
+		{
+			name:   "yaml keeps indentation of lists",
+			raw:    "aliases:\n  - x\ntags: a\n",
+			format: FRONT_MATTER_YAML,
+			edit: func(fm *FrontMatter) error {
+				return fm.Set("tags", []string{"a", "b"})
+			},
+			want: "aliases:\n  - x\ntags:\n  - a\n  - b\n",
+		},
+		{
+			name:   "yaml keeps list items",
+			raw:    "tags:\n- 'a'\ndue: 2021-01-02\n",
+			format: FRONT_MATTER_YAML,
+			edit: func(fm *FrontMatter) error {
+				if err := fm.Set("tags", []string{"a", "b"}); err != nil {
+					return err
+				}
+				return fm.Set("due", []string{"2021-01-02", "x"})
+			},
+			want: "tags:\n- 'a'\n- b\ndue:\n- 2021-01-02\n- x\n",
+		},
+		{
+			name:   "yaml without trailing newline",
+			raw:    "title: a",
+			format: FRONT_MATTER_YAML,
+			edit: func(fm *FrontMatter) error {
+				return fm.Set("draft", true)
+			},
+			want: "title: a\ndraft: true\n",
+		},
+		{
+			name:   "yaml with anchors keeps order and comments",
+			raw:    "# comment\ntitle: x\ndate: 2021-01-02\nbase: &b {x: 1}\nother: *b\ntags: [a]  # tags\n",
+			format: FRONT_MATTER_YAML,
+			edit: func(fm *FrontMatter) error {
+				if err := fm.Set("tags", []string{"a", "b"}); err != nil {
+					return err
+				}
+				return fm.Set("added", 1)
+			},
+			want: "# comment\ntitle: x\ndate: 2021-01-02\nbase: &b {x: 1}\nother: *b\ntags: [a, b] # tags\nadded: 1\n",
+		},
+		{
+			name:   "toml keeps comments and number formats",
+			raw:    "# head\ntitle = \"old\" # title\ncount = 1_000\nmask = 0xff\ntime = 07:32:00\nday = 2021-01-02\n\n[params]\nx = 1\n",
+			format: FRONT_MATTER_TOML,
+			edit: func(fm *FrontMatter) error {
+				if err := fm.Set("count", 1000); err != nil {
+					return err
+				}
+				if err := fm.Set("title", "new"); err != nil {
+					return err
+				}
+				fm.Delete("mask")
+				return fm.Set("draft", true)
+			},
+			want: "# head\ntitle = \"new\" # title\ncount = 1_000\ntime = 07:32:00\nday = 2021-01-02\ndraft = true\n\n[params]\nx = 1\n",
+		},
+		{
+			name:   "toml table changed",
+			raw:    "title = \"a\" # title\n\n[params]\nx = 1\n",
+			format: FRONT_MATTER_TOML,
+			edit: func(fm *FrontMatter) error {
+				return fm.Set("params", map[interface{}]interface{}{"x": 2})
+			},
+			want: "title = \"a\"\n\n[params]\nx = 2\n",
+		},
+		{
+			name:   "json",
+			raw:    "{\n  \"title\": \"old\",\n  \"big\": 1e3,\n  \"tags\": [\"a\"]\n}",
+			format: FRONT_MATTER_JSON,
+			edit: func(fm *FrontMatter) error {
+				if err := fm.Set("title", "new"); err != nil {
+					return err
+				}
+				fm.Delete("tags")
+				return fm.Set("aliases", []string{"x"})
+			},
+			want: "{\n  \"title\": \"new\",\n  \"big\": 1e3,\n  \"aliases\": [\n    \"x\"\n  ]\n}\n",
+		},
+	}
+
+	for _, tt := range cases {
+		fm, err := ParseFrontMatter([]byte(tt.raw), tt.format)
+		if err != nil {
+			t.Errorf("[FATAL | %s] unexpected error occurred: %v", tt.name, err)
+			continue
+		}
+		if err := tt.edit(fm); err != nil {
+			t.Errorf("[FATAL | %s] unexpected error occurred: %v", tt.name, err)
+			continue
+		}
+		got, err := fm.Encode(tt.format)
+		if err != nil {
+			t.Errorf("[FATAL | %s] unexpected error occurred: %v", tt.name, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("[ERROR | %s] got: %q, want: %q", tt.name, string(got), tt.want)
+		}
+	}
+}
@@ -0,0 +1,955 @@
+package process
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Zola や Hugo の +++ で囲まれた TOML の front matter を読み書きする.
+// 外部のライブラリは使わず, front matter に書かれる範囲の TOML を扱う.
+
+type tomlTable struct {
+	keys    []string
+	values  map[string]interface{}
+	defined bool // [a] の形ですでに定義した table
+}
+
+func newTomlTable() *tomlTable {
+	return &tomlTable{values: make(map[string]interface{})}
+}
+
+func (t *tomlTable) set(key string, v interface{}) error {
+	if _, ok := t.values[key]; ok {
+		return fmt.Errorf("duplicate key: %s", key)
+	}
+	t.keys = append(t.keys, key)
+	t.values[key] = v
+	return nil
+}
+
+// a.b.c の途中の table をたどる. なければ作る.
+// array of tables の場合は最後の要素をたどる.
+func (t *tomlTable) descend(keys []string) (*tomlTable, error) {
+	cur := t
+	for _, key := range keys {
+		v, ok := cur.values[key]
+		if !ok {
+			next := newTomlTable()
+			cur.set(key, next)
+			cur = next
+			continue
+		}
+		switch vv := v.(type) {
+		case *tomlTable:
+			cur = vv
+		case []*tomlTable:
+			cur = vv[len(vv)-1]
+		default:
+			return nil, fmt.Errorf("%s is not a table", key)
+		}
+	}
+	return cur, nil
+}
+
+func (t *tomlTable) mapSlice() yaml.MapSlice {
+	m := make(yaml.MapSlice, 0, len(t.keys))
+	for _, key := range t.keys {
+		m = append(m, yaml.MapItem{Key: key, Value: tomlToYamlValue(t.values[key])})
+	}
+	return m
+}
+
+func tomlToYamlValue(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case *tomlTable:
+		return vv.mapSlice()
+	case []*tomlTable:
+		list := make([]interface{}, 0, len(vv))
+		for _, t := range vv {
+			list = append(list, t.mapSlice())
+		}
+		return list
+	case []interface{}:
+		list := make([]interface{}, 0, len(vv))
+		for _, a := range vv {
+			list = append(list, tomlToYamlValue(a))
+		}
+		return list
+	}
+	return v
+}
+
+type tomlParser struct {
+	src        []rune
+	p          int
+	keyEnd     int // 最後に読んだ key = value の key の終わり
+	valueStart int
+}
+
+// 最上位の key = value の行は位置を覚えておき, 値を変えたときにその部分だけを書き直す
+func (f *FrontMatter) parseTOML() error {
+	m, spans, tail, err := decodeTOML(f.raw)
+	if err != nil {
+		return err
+	}
+	for _, item := range m {
+		key := fmt.Sprint(item.Key)
+		n, err := valueNode(item.Value)
+		if err != nil {
+			return fmt.Errorf("toml: %w", err)
+		}
+		f.origin = append(f.origin, &frontMatterEntry{key: keyNode(key), value: n, span: spans[key]})
+	}
+	f.splice = true
+	f.tail = tail
+	return nil
+}
+
+// 変更した key の名前と値だけを書き換え, 削除した key は行ごと除く. 追加する key は最上位の最後の key = value の後ろに書く.
+// table として書かれた key を変えた場合は ok = false
+func (f *FrontMatter) spliceTOML() (output []byte, ok bool, err error) {
+	if !f.Changed() {
+		return f.raw, true, nil
+	}
+	type edit struct {
+		start, end int
+		text       string
+	}
+	edits := make([]edit, 0)
+	kept := make(map[*frontMatterEntry]bool)
+	added := new(bytes.Buffer)
+	for _, e := range f.entries {
+		o := e.origin
+		if o != nil {
+			kept[o] = true
+			if e.key == o.key && e.value == o.value {
+				continue
+			}
+		}
+		v, err := decodeNode(e.value, true)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read %s: %w", e.key.Value, err)
+		}
+		if o == nil {
+			if v == nil {
+				continue
+			}
+			added.WriteString(tomlKey(e.key.Value) + " = ")
+			if err := writeTomlValue(added, v); err != nil {
+				return nil, false, err
+			}
+			added.WriteString("\n")
+			continue
+		}
+		if o.span == nil {
+			return nil, false, nil
+		}
+		if v == nil {
+			edits = append(edits, edit{o.span.start, o.span.end, ""})
+			continue
+		}
+		if e.key != o.key {
+			edits = append(edits, edit{o.span.keyStart, o.span.keyEnd, tomlKey(e.key.Value)})
+		}
+		if e.value != o.value {
+			b := new(bytes.Buffer)
+			if err := writeTomlValue(b, v); err != nil {
+				return nil, false, err
+			}
+			edits = append(edits, edit{o.span.valueStart, o.span.valueEnd, b.String()})
+		}
+	}
+	for _, o := range f.origin {
+		if kept[o] {
+			continue
+		}
+		if o.span == nil {
+			return nil, false, nil
+		}
+		edits = append(edits, edit{o.span.start, o.span.end, ""})
+	}
+	if added.Len() > 0 {
+		text := added.String()
+		if f.tail > 0 && f.raw[f.tail-1] != '\n' {
+			text = "\n" + text
+		}
+		edits = append(edits, edit{f.tail, f.tail, text})
+	}
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].start < edits[j].start
+	})
+	b := new(bytes.Buffer)
+	cur := 0
+	for _, ed := range edits {
+		b.Write(f.raw[cur:ed.start])
+		b.WriteString(ed.text)
+		cur = ed.end
+	}
+	b.Write(f.raw[cur:])
+	return b.Bytes(), true, nil
+}
+
+// key の順番を保つために yaml.MapSlice で返す.
+// offset 付きの日時は time.Time, それ以外の日付と日時は LocalDateTime, 時刻は書かれたままの文字列にする.
+// spans は最上位の key = value の行のバイト単位の位置. tail は最上位に key を追加する位置.
+func decodeTOML(raw []byte) (m yaml.MapSlice, spans map[string]*entrySpan, tail int, err error) {
+	ps := &tomlParser{src: []rune(string(raw))}
+	root := newTomlTable()
+	cur := root
+	spans = make(map[string]*entrySpan)
+	tail = -1
+	for {
+		ps.skipBlank()
+		if ps.eof() {
+			break
+		}
+		if ps.peek() == '[' {
+			if tail < 0 {
+				tail = ps.offset(ps.lineStart())
+			}
+			array := ps.hasPrefix("[[")
+			closing := "]"
+			if array {
+				closing = "]]"
+			}
+			ps.p += len(closing)
+			keys, err := ps.key()
+			if err != nil {
+				return nil, nil, 0, err
+			}
+			ps.skipSpaces()
+			if !ps.hasPrefix(closing) {
+				return nil, nil, 0, ps.errorf("expected %s", closing)
+			}
+			ps.p += len(closing)
+			if err := ps.endOfLine(); err != nil {
+				return nil, nil, 0, err
+			}
+			parent, err := root.descend(keys[:len(keys)-1])
+			if err != nil {
+				return nil, nil, 0, ps.errorf("%v", err)
+			}
+			last := keys[len(keys)-1]
+			next := newTomlTable()
+			next.defined = true
+			switch v, ok := parent.values[last]; {
+			case !ok && array:
+				parent.set(last, []*tomlTable{next})
+			case !ok:
+				parent.set(last, next)
+			case array:
+				list, ok := v.([]*tomlTable)
+				if !ok {
+					return nil, nil, 0, ps.errorf("%s is not an array of tables", strings.Join(keys, "."))
+				}
+				parent.values[last] = append(list, next)
+			default:
+				t, ok := v.(*tomlTable)
+				if !ok || t.defined {
+					return nil, nil, 0, ps.errorf("duplicate table: %s", strings.Join(keys, "."))
+				}
+				t.defined = true
+				next = t
+			}
+			cur = next
+			continue
+		}
+		start := ps.p
+		keys, v, err := ps.keyValue()
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		valueEnd := ps.p
+		if err := ps.endOfLine(); err != nil {
+			return nil, nil, 0, err
+		}
+		parent, err := cur.descend(keys[:len(keys)-1])
+		if err != nil {
+			return nil, nil, 0, ps.errorf("%v", err)
+		}
+		if err := parent.set(keys[len(keys)-1], v); err != nil {
+			return nil, nil, 0, ps.errorf("%v", err)
+		}
+		if cur == root && len(keys) == 1 {
+			end := ps.p
+			if end > len(ps.src) {
+				end = len(ps.src)
+			}
+			spans[keys[0]] = &entrySpan{
+				start:      ps.offset(ps.lineStartOf(start)),
+				end:        ps.offset(end),
+				keyStart:   ps.offset(start),
+				keyEnd:     ps.offset(ps.keyEnd),
+				valueStart: ps.offset(ps.valueStart),
+				valueEnd:   ps.offset(valueEnd),
+			}
+			tail = spans[keys[0]].end
+		}
+	}
+	if tail < 0 {
+		tail = len(raw)
+	}
+	return root.mapSlice(), spans, tail, nil
+}
+
+// rune の位置をバイト単位にする
+func (ps *tomlParser) offset(p int) int {
+	return len(string(ps.src[:p]))
+}
+
+func (ps *tomlParser) lineStart() int {
+	return ps.lineStartOf(ps.p)
+}
+
+func (ps *tomlParser) lineStartOf(p int) int {
+	for p > 0 && ps.src[p-1] != '\n' {
+		p--
+	}
+	return p
+}
+
+func (ps *tomlParser) errorf(format string, a ...interface{}) error {
+	line := 1 + strings.Count(string(ps.src[:ps.p]), "\n")
+	return fmt.Errorf("toml: line %d: %s", line, fmt.Sprintf(format, a...))
+}
+
+func (ps *tomlParser) eof() bool {
+	return ps.p >= len(ps.src)
+}
+
+func (ps *tomlParser) peek() rune {
+	if ps.eof() {
+		return 0
+	}
+	return ps.src[ps.p]
+}
+
+func (ps *tomlParser) hasPrefix(s string) bool {
+	i := ps.p
+	for _, r := range s {
+		if i >= len(ps.src) || ps.src[i] != r {
+			return false
+		}
+		i++
+	}
+	return true
+}
+
+func (ps *tomlParser) skipSpaces() {
+	for !ps.eof() && (ps.peek() == ' ' || ps.peek() == '\t') {
+		ps.p++
+	}
+}
+
+func (ps *tomlParser) skipComment() {
+	if ps.peek() != '#' {
+		return
+	}
+	for !ps.eof() && ps.peek() != '\n' {
+		ps.p++
+	}
+}
+
+// 空白, 改行, コメントを飛ばす
+func (ps *tomlParser) skipBlank() {
+	for !ps.eof() {
+		switch ps.peek() {
+		case ' ', '\t', '\r', '\n':
+			ps.p++
+		case '#':
+			ps.skipComment()
+		default:
+			return
+		}
+	}
+}
+
+func (ps *tomlParser) endOfLine() error {
+	ps.skipSpaces()
+	ps.skipComment()
+	if ps.hasPrefix("\r\n") {
+		ps.p += 2
+		return nil
+	}
+	if ps.eof() || ps.peek() == '\n' {
+		ps.p++
+		return nil
+	}
+	return ps.errorf("unexpected %q", ps.peek())
+}
+
+func (ps *tomlParser) keyValue() (keys []string, v interface{}, err error) {
+	keys, err = ps.key()
+	if err != nil {
+		return nil, nil, err
+	}
+	keyEnd := ps.p
+	ps.skipSpaces()
+	if ps.peek() != '=' {
+		return nil, nil, ps.errorf("expected =")
+	}
+	ps.p++
+	ps.skipSpaces()
+	valueStart := ps.p
+	v, err = ps.value()
+	if err != nil {
+		return nil, nil, err
+	}
+	// inline table の中の key = value で上書きされないように値を読んだ後で覚える
+	ps.keyEnd, ps.valueStart = keyEnd, valueStart
+	return keys, v, nil
+}
+
+// a."b c".d のような . で区切られた key
+func (ps *tomlParser) key() (keys []string, err error) {
+	for {
+		ps.skipSpaces()
+		var key string
+		switch ps.peek() {
+		case '"':
+			key, err = ps.basicString()
+		case '\'':
+			key, err = ps.literalString()
+		default:
+			start := ps.p
+			for !ps.eof() && isBareKeyChar(ps.peek()) {
+				ps.p++
+			}
+			if start == ps.p {
+				return nil, ps.errorf("expected a key")
+			}
+			key = string(ps.src[start:ps.p])
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+		ps.skipSpaces()
+		if ps.peek() != '.' {
+			return keys, nil
+		}
+		ps.p++
+	}
+}
+
+func isBareKeyChar(r rune) bool {
+	return ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') || r == '_' || r == '-'
+}
+
+var (
+	tomlDateTimePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}(?:[Tt ]\d{2}:\d{2}(?::\d{2}(?:\.\d+)?)?(?:[Zz]|[+-]\d{2}:\d{2})?)?`)
+	tomlTimePattern     = regexp.MustCompile(`^\d{2}:\d{2}(?::\d{2}(?:\.\d+)?)?`)
+	tomlOffsetPattern   = regexp.MustCompile(`(?:[Zz]|[+-]\d{2}:\d{2})$`)
+	// 書き出すときは TOML 1.0 で読める, 秒まである形だけ日時として扱う
+	tomlDateTimeValuePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}(?:T\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:\d{2})?)?$`)
+)
+
+func (ps *tomlParser) value() (interface{}, error) {
+	switch {
+	case ps.hasPrefix(`"""`):
+		return ps.multilineString(`"""`)
+	case ps.hasPrefix("'''"):
+		return ps.multilineString("'''")
+	case ps.peek() == '"':
+		return ps.basicString()
+	case ps.peek() == '\'':
+		return ps.literalString()
+	case ps.peek() == '[':
+		return ps.array()
+	case ps.peek() == '{':
+		return ps.inlineTable()
+	case ps.hasPrefix("true"):
+		ps.p += 4
+		return true, nil
+	case ps.hasPrefix("false"):
+		ps.p += 5
+		return false, nil
+	}
+	// 日時は長くても 40 文字ほど
+	end := ps.p + 64
+	if end > len(ps.src) {
+		end = len(ps.src)
+	}
+	rest := string(ps.src[ps.p:end])
+	if s := tomlDateTimePattern.FindString(rest); s != "" {
+		ps.p += len([]rune(s))
+		return tomlDateTime(s)
+	}
+	if s := tomlTimePattern.FindString(rest); s != "" {
+		ps.p += len([]rune(s))
+		return s, nil
+	}
+	return ps.number()
+}
+
+// offset がなければ書かれたままの LocalDateTime
+func tomlDateTime(s string) (interface{}, error) {
+	if len(s) <= len("2006-01-02") || !tomlOffsetPattern.MatchString(s) {
+		return LocalDateTime(s), nil
+	}
+	normalized := s[:10] + "T" + strings.ToUpper(s[11:])
+	// 秒を省略した 15:04 の形
+	if normalized[16] != ':' {
+		normalized = normalized[:16] + ":00" + normalized[16:]
+	}
+	t, err := time.Parse(time.RFC3339Nano, normalized)
+	if err != nil {
+		return nil, fmt.Errorf("toml: invalid datetime: %s", s)
+	}
+	return t, nil
+}
+
+func (ps *tomlParser) number() (interface{}, error) {
+	start := ps.p
+	for !ps.eof() && strings.ContainsRune("0123456789abcdefABCDEFxobinINFN_+-.", ps.peek()) {
+		ps.p++
+	}
+	s := string(ps.src[start:ps.p])
+	switch strings.TrimLeft(s, "+-") {
+	case "inf":
+		if strings.HasPrefix(s, "-") {
+			return math.Inf(-1), nil
+		}
+		return math.Inf(1), nil
+	case "nan":
+		return math.NaN(), nil
+	}
+	digits := strings.ReplaceAll(s, "_", "")
+	for prefix, base := range map[string]int{"0x": 16, "0o": 8, "0b": 2} {
+		if strings.HasPrefix(digits, prefix) {
+			n, err := strconv.ParseInt(digits[2:], base, 64)
+			if err != nil {
+				return nil, ps.errorf("invalid number: %s", s)
+			}
+			return int(n), nil
+		}
+	}
+	if strings.ContainsAny(digits, ".eE") {
+		f, err := strconv.ParseFloat(digits, 64)
+		if err != nil {
+			return nil, ps.errorf("invalid number: %s", s)
+		}
+		return f, nil
+	}
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		if s == "" {
+			return nil, ps.errorf("expected a value")
+		}
+		return nil, ps.errorf("invalid value: %s", s)
+	}
+	return int(n), nil
+}
+
+func (ps *tomlParser) basicString() (string, error) {
+	ps.p++
+	b := new(strings.Builder)
+	for !ps.eof() {
+		r := ps.peek()
+		switch {
+		case r == '"':
+			ps.p++
+			return b.String(), nil
+		case r == '\n':
+			return "", ps.errorf("unterminated string")
+		case r == '\\':
+			if err := ps.escape(b); err != nil {
+				return "", err
+			}
+		default:
+			b.WriteRune(r)
+			ps.p++
+		}
+	}
+	return "", ps.errorf("unterminated string")
+}
+
+func (ps *tomlParser) literalString() (string, error) {
+	ps.p++
+	start := ps.p
+	for !ps.eof() {
+		switch ps.peek() {
+		case '\'':
+			s := string(ps.src[start:ps.p])
+			ps.p++
+			return s, nil
+		case '\n':
+			return "", ps.errorf("unterminated string")
+		}
+		ps.p++
+	}
+	return "", ps.errorf("unterminated string")
+}
+
+// 開きの引用符の直後の改行は含めない. basic string では \ で行末の空白と改行を飛ばせる. literal string ではエスケープしない.
+func (ps *tomlParser) multilineString(quote string) (string, error) {
+	ps.p += 3
+	if ps.hasPrefix("\r\n") {
+		ps.p += 2
+	} else if ps.peek() == '\n' {
+		ps.p++
+	}
+	b := new(strings.Builder)
+	for !ps.eof() {
+		if ps.hasPrefix(quote) {
+			// """" のように閉じる引用符の前に 2 つまで引用符を書ける
+			n := 3
+			for n < 5 && ps.p+n < len(ps.src) && ps.src[ps.p+n] == rune(quote[0]) {
+				n++
+			}
+			b.WriteString(strings.Repeat(quote[:1], n-3))
+			ps.p += n
+			return b.String(), nil
+		}
+		r := ps.peek()
+		if r == '\\' && quote == `"""` {
+			if ps.atLineEndingBackslash() {
+				ps.p++
+				ps.skipBlankSpacesOnly()
+				continue
+			}
+			if err := ps.escape(b); err != nil {
+				return "", err
+			}
+			continue
+		}
+		b.WriteRune(r)
+		ps.p++
+	}
+	return "", ps.errorf("unterminated string")
+}
+
+// \ の後ろに空白と改行しかないか
+func (ps *tomlParser) atLineEndingBackslash() bool {
+	for i := ps.p + 1; i < len(ps.src); i++ {
+		switch ps.src[i] {
+		case ' ', '\t', '\r':
+			continue
+		case '\n':
+			return true
+		}
+		return false
+	}
+	return false
+}
+
+func (ps *tomlParser) skipBlankSpacesOnly() {
+	for !ps.eof() && strings.ContainsRune(" \t\r\n", ps.peek()) {
+		ps.p++
+	}
+}
+
+func (ps *tomlParser) escape(b *strings.Builder) error {
+	ps.p++
+	if ps.eof() {
+		return ps.errorf("unterminated string")
+	}
+	r := ps.peek()
+	ps.p++
+	switch r {
+	case 'b':
+		b.WriteRune('\b')
+	case 't':
+		b.WriteRune('\t')
+	case 'n':
+		b.WriteRune('\n')
+	case 'f':
+		b.WriteRune('\f')
+	case 'r':
+		b.WriteRune('\r')
+	case 'e':
+		b.WriteRune('\x1b')
+	case '"', '\\':
+		b.WriteRune(r)
+	case 'u', 'U':
+		size := 4
+		if r == 'U' {
+			size = 8
+		}
+		if ps.p+size > len(ps.src) {
+			return ps.errorf("invalid escape sequence")
+		}
+		n, err := strconv.ParseUint(string(ps.src[ps.p:ps.p+size]), 16, 32)
+		if err != nil {
+			return ps.errorf("invalid escape sequence")
+		}
+		b.WriteRune(rune(n))
+		ps.p += size
+	default:
+		return ps.errorf("invalid escape sequence: \\%c", r)
+	}
+	return nil
+}
+
+func (ps *tomlParser) array() (interface{}, error) {
+	ps.p++
+	list := make([]interface{}, 0)
+	for {
+		ps.skipBlank()
+		if ps.peek() == ']' {
+			ps.p++
+			return list, nil
+		}
+		v, err := ps.value()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+		ps.skipBlank()
+		switch ps.peek() {
+		case ',':
+			ps.p++
+		case ']':
+			ps.p++
+			return list, nil
+		default:
+			return nil, ps.errorf("expected , or ]")
+		}
+	}
+}
+
+func (ps *tomlParser) inlineTable() (interface{}, error) {
+	ps.p++
+	t := newTomlTable()
+	ps.skipBlank()
+	if ps.peek() == '}' {
+		ps.p++
+		return t, nil
+	}
+	for {
+		ps.skipBlank()
+		keys, v, err := ps.keyValue()
+		if err != nil {
+			return nil, err
+		}
+		parent, err := t.descend(keys[:len(keys)-1])
+		if err != nil {
+			return nil, ps.errorf("%v", err)
+		}
+		if err := parent.set(keys[len(keys)-1], v); err != nil {
+			return nil, ps.errorf("%v", err)
+		}
+		ps.skipBlank()
+		switch ps.peek() {
+		case ',':
+			ps.p++
+		case '}':
+			ps.p++
+			return t, nil
+		default:
+			return nil, ps.errorf("expected , or }")
+		}
+	}
+}
+
+// key = value を先に書き, table と array of tables を後に書く.
+// TOML には null がないので, 値が null の key は書かない.
+// 日付や日時として読める文字列は TOML の日付や日時として書く.
+func encodeTOML(m yaml.MapSlice) ([]byte, error) {
+	b := new(bytes.Buffer)
+	if err := writeTomlTable(b, nil, m); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func writeTomlTable(b *bytes.Buffer, path []string, m yaml.MapSlice) error {
+	tables := make(yaml.MapSlice, 0)
+	for _, item := range m {
+		switch v := normalizeYamlMap(item.Value).(type) {
+		case nil:
+			continue
+		case yaml.MapSlice:
+			tables = append(tables, yaml.MapItem{Key: item.Key, Value: v})
+			continue
+		case []interface{}:
+			if isTableArray(v) {
+				tables = append(tables, yaml.MapItem{Key: item.Key, Value: v})
+				continue
+			}
+		}
+		b.WriteString(tomlKey(fmt.Sprint(item.Key)) + " = ")
+		if err := writeTomlValue(b, item.Value); err != nil {
+			return err
+		}
+		b.WriteString("\n")
+	}
+	for _, item := range tables {
+		p := append(append([]string{}, path...), tomlKey(fmt.Sprint(item.Key)))
+		switch v := item.Value.(type) {
+		case yaml.MapSlice:
+			if b.Len() > 0 {
+				b.WriteString("\n")
+			}
+			b.WriteString("[" + strings.Join(p, ".") + "]\n")
+			if err := writeTomlTable(b, p, v); err != nil {
+				return err
+			}
+		case []interface{}:
+			for _, a := range v {
+				if b.Len() > 0 {
+					b.WriteString("\n")
+				}
+				b.WriteString("[[" + strings.Join(p, ".") + "]]\n")
+				if err := writeTomlTable(b, p, normalizeYamlMap(a).(yaml.MapSlice)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func isTableArray(list []interface{}) bool {
+	if len(list) == 0 {
+		return false
+	}
+	for _, a := range list {
+		if _, ok := normalizeYamlMap(a).(yaml.MapSlice); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func writeTomlValue(b *bytes.Buffer, v interface{}) error {
+	switch vv := normalizeYamlMap(v).(type) {
+	case string:
+		if tomlDateTimeValuePattern.MatchString(vv) {
+			if _, err := tomlDateTime(vv); err == nil {
+				b.WriteString(vv)
+				return nil
+			}
+		}
+		b.WriteString(tomlString(vv))
+	case bool:
+		b.WriteString(strconv.FormatBool(vv))
+	case int:
+		b.WriteString(strconv.Itoa(vv))
+	case int64:
+		b.WriteString(strconv.FormatInt(vv, 10))
+	case uint64:
+		b.WriteString(strconv.FormatUint(vv, 10))
+	case float64:
+		b.WriteString(tomlFloat(vv))
+	case time.Time:
+		b.WriteString(vv.Format(time.RFC3339Nano))
+	case []interface{}:
+		b.WriteString("[")
+		written := 0
+		for _, a := range vv {
+			if a == nil {
+				continue
+			}
+			if written > 0 {
+				b.WriteString(", ")
+			}
+			if err := writeTomlValue(b, a); err != nil {
+				return err
+			}
+			written++
+		}
+		b.WriteString("]")
+	case yaml.MapSlice:
+		b.WriteString("{")
+		written := 0
+		for _, item := range vv {
+			if item.Value == nil {
+				continue
+			}
+			if written > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(tomlKey(fmt.Sprint(item.Key)) + " = ")
+			if err := writeTomlValue(b, item.Value); err != nil {
+				return err
+			}
+			written++
+		}
+		b.WriteString("}")
+	default:
+		return fmt.Errorf("toml: unsupported value: %T", v)
+	}
+	return nil
+}
+
+func tomlKey(key string) string {
+	if key == "" {
+		return `""`
+	}
+	for _, r := range key {
+		if !isBareKeyChar(r) {
+			return tomlString(key)
+		}
+	}
+	return key
+}
+
+func tomlString(s string) string {
+	b := new(strings.Builder)
+	b.WriteString(`"`)
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(b, `\u%04X`, r)
+				continue
+			}
+			b.WriteRune(r)
+		}
+	}
+	b.WriteString(`"`)
+	return b.String()
+}
+
+func tomlFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
+
+// map[interface{}]interface{} は key の名前の順の yaml.MapSlice にする
+func normalizeYamlMap(v interface{}) interface{} {
+	m, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return v
+	}
+	ms := make(yaml.MapSlice, 0, len(m))
+	for key, value := range m {
+		ms = append(ms, yaml.MapItem{Key: key, Value: value})
+	}
+	sortMapSlice(ms)
+	return ms
+}
@@ -0,0 +1,442 @@
+package process
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"regexp"
+
+	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// YAML の front matter は yaml.v3 の Node に読む.
+// 最上位が block 形式の mapping で, key がすべて行の先頭から始まる場合は key ごとに元の行を切り貼りして書き出す.
+// 書き直したリストの - は元の front matter のリストと同じ列に書く. リストがなければ key と同じ列.
+
+func (f *FrontMatter) parseYAML() error {
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal(f.raw, &doc); err != nil {
+		return fmt.Errorf("failed to unmarshal front matter: %w", err)
+	}
+	// 空かコメントだけ
+	if len(doc.Content) == 0 || (doc.Content[0].Kind == yamlv3.ScalarNode && doc.Content[0].ShortTag() == "!!null") {
+		f.splice = true
+		return nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yamlv3.MappingNode {
+		return fmt.Errorf("failed to unmarshal front matter: not a mapping but %s", root.ShortTag())
+	}
+	f.doc = &doc
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		f.origin = append(f.origin, &frontMatterEntry{key: root.Content[i], value: root.Content[i+1]})
+	}
+	// 書き直すリストは最初に見つかったリストの字下げにそろえる
+	for _, o := range f.origin {
+		if o.value.Kind == yamlv3.SequenceNode && o.value.Style&yamlv3.FlowStyle == 0 && len(o.value.Content) > 0 {
+			f.indentSequence = o.value.Column > o.key.Column
+			break
+		}
+	}
+	// 他の key の anchor を参照している場合などは 1 つずつ書けない
+	f.splice = root.Style&yamlv3.FlowStyle == 0 && !hasAnchor(root) && f.yamlSpans()
+	return nil
+}
+
+func hasAnchor(n *yamlv3.Node) bool {
+	if n.Anchor != "" || n.Kind == yamlv3.AliasNode {
+		return true
+	}
+	for _, c := range n.Content {
+		if hasAnchor(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// key の前のコメントと空行はその key に含める. key が行の先頭から始まらなければ false
+func (f *FrontMatter) yamlSpans() bool {
+	starts := []int{0}
+	for i, c := range f.raw {
+		if c == '\n' && i+1 < len(f.raw) {
+			starts = append(starts, i+1)
+		}
+	}
+	starts = append(starts, len(f.raw))
+	lines := len(starts) - 1
+	line := func(i int) []byte {
+		return f.raw[starts[i]:starts[i+1]]
+	}
+
+	prev := -1 // 前の key の行
+	var last *frontMatterEntry
+	for _, e := range f.origin {
+		l := e.key.Line - 1
+		if e.key.Kind != yamlv3.ScalarNode || e.key.Column != 1 || l <= prev || l >= lines {
+			return false
+		}
+		start := l
+		for start-1 > prev && isYamlLeadingLine(line(start-1), last) {
+			start--
+		}
+		if last == nil {
+			start = 0
+		} else {
+			last.span.end = starts[start]
+		}
+		e.span = &entrySpan{start: starts[start], keyStart: starts[l]}
+		prev = l
+		last = e
+	}
+	if last == nil {
+		return true
+	}
+	// 最後の key の後ろのコメントと空行
+	end := lines
+	for end-1 > prev && isYamlLeadingLine(line(end-1), last) {
+		end--
+	}
+	last.span.end = starts[end]
+	f.tail = last.span.end
+	return true
+}
+
+// 前の key の値が | や > の場合, 空行はその値に含まれることがある
+func isYamlLeadingLine(line []byte, prev *frontMatterEntry) bool {
+	if len(line) > 0 && line[0] == '#' {
+		return true
+	}
+	if prev != nil && prev.value.Style&(yamlv3.LiteralStyle|yamlv3.FoldedStyle) != 0 {
+		return false
+	}
+	return len(bytes.TrimSpace(line)) == 0
+}
+
+// 変更しなかった key は元の行のまま, 変更した key はその位置で書き直し, 追加した key は最後に書く.
+// key の前のコメントは残す.
+func (f *FrontMatter) spliceYAML() ([]byte, error) {
+	if !f.Changed() {
+		return f.raw, nil
+	}
+	b := new(bytes.Buffer)
+	if len(f.origin) == 0 {
+		b.Write(f.raw)
+	}
+	for _, e := range f.entries {
+		o := e.origin
+		if o == nil {
+			text, err := f.encodeYamlEntry(e.key, e.value)
+			if err != nil {
+				return nil, err
+			}
+			b.Write(text)
+			continue
+		}
+		if e.key == o.key && e.value == o.value {
+			b.Write(f.raw[o.span.start:o.span.end])
+			continue
+		}
+		b.Write(f.raw[o.span.start:o.span.keyStart])
+		if e.value == o.value {
+			if text, ok := renameYamlKey(f.raw[o.span.keyStart:o.span.end], o.key, e.key); ok {
+				b.Write(text)
+				continue
+			}
+		}
+		text, err := f.encodeYamlEntry(e.key, e.value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(text)
+	}
+	if len(f.origin) > 0 {
+		b.Write(f.raw[f.tail:])
+	}
+	return b.Bytes(), nil
+}
+
+// key の前のコメントは元の行を使うので書かない
+func (f *FrontMatter) encodeYamlEntry(key *yamlv3.Node, value *yamlv3.Node) ([]byte, error) {
+	k := *key
+	k.HeadComment = ""
+	k.FootComment = ""
+	text, err := encodeYamlNode(&yamlv3.Node{Kind: yamlv3.MappingNode, Content: []*yamlv3.Node{&k, value}})
+	if err != nil || f.indentSequence {
+		return text, err
+	}
+	return compactSequences(text), nil
+}
+
+// 引用符のない key だけを書き換えて, 値は元の行のまま残す
+func renameYamlKey(text []byte, from *yamlv3.Node, to *yamlv3.Node) ([]byte, bool) {
+	if from.Style != 0 || !bytes.HasPrefix(text, []byte(from.Value)) {
+		return nil, false
+	}
+	rest := text[len(from.Value):]
+	if !bytes.HasPrefix(bytes.TrimLeft(rest, " \t"), []byte(":")) {
+		return nil, false
+	}
+	k := *to
+	k.HeadComment = ""
+	key, err := encodeYamlNode(&k)
+	if err != nil || bytes.Count(key, []byte("\n")) != 1 {
+		return nil, false
+	}
+	return append(bytes.TrimSuffix(key, []byte("\n")), rest...), true
+}
+
+// 切り貼りできない場合や別の形式から書き出す場合. コメントは Node に残っているものを書く.
+// 字下げは yaml.v3 の書き方にそろえ直される.
+func (f *FrontMatter) encodeYAML() ([]byte, error) {
+	mapping := &yamlv3.Node{Kind: yamlv3.MappingNode}
+	doc := &yamlv3.Node{Kind: yamlv3.DocumentNode}
+	if f.doc != nil {
+		*mapping = *f.doc.Content[0]
+		*doc = *f.doc
+	}
+	mapping.Content = make([]*yamlv3.Node, 0, 2*len(f.entries))
+	for _, e := range f.entries {
+		mapping.Content = append(mapping.Content, e.key, e.value)
+	}
+	doc.Content = []*yamlv3.Node{mapping}
+	text, err := encodeYamlNode(doc)
+	if err != nil || f.indentSequence {
+		return text, err
+	}
+	return compactSequences(text), nil
+}
+
+var blockKeyLine = regexp.MustCompile(`:( +#.*)?\n$`)
+
+// yaml.v3 はリストを字下げして書くので, 最上位の key の値のリストだけ - を key と同じ列に戻す.
+// リストの中の値は 2 文字ずつ左に寄せても同じ値として読める.
+func compactSequences(text []byte) []byte {
+	lines := bytes.SplitAfter(text, []byte("\n"))
+	b := new(bytes.Buffer)
+	compact := false
+	for i, line := range lines {
+		switch {
+		case len(line) > 0 && line[0] != ' ' && line[0] != '\n':
+			compact = blockKeyLine.Match(line) && i+1 < len(lines) && isIndentedSequenceLine(lines[i+1])
+		case compact:
+			line = bytes.TrimPrefix(line, []byte("  "))
+		}
+		b.Write(line)
+	}
+	return b.Bytes()
+}
+
+func isIndentedSequenceLine(line []byte) bool {
+	return bytes.HasPrefix(line, []byte("  - ")) || bytes.HasPrefix(line, []byte("  -\n"))
+}
+
+func encodeYamlNode(n *yamlv3.Node) ([]byte, error) {
+	b := new(bytes.Buffer)
+	enc := yamlv3.NewEncoder(b)
+	enc.SetIndent(2)
+	if err := enc.Encode(n); err != nil {
+		return nil, fmt.Errorf("failed to marshal front matter: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to marshal front matter: %w", err)
+	}
+	return b.Bytes(), nil
+}
+
+func keyNode(key string) *yamlv3.Node {
+	return &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: key}
+}
+
+// 時差のない日付と日時. 時刻や時差を足さずに, 書かれたままの YAML の日時として書く.
+// TOML の local date と local date-time, inline field の日付に使う.
+type LocalDateTime string
+
+// yaml.MapSlice は key の順番を保つ. LocalDateTime は引用符のない YAML の日時として書く.
+func valueNode(v interface{}) (*yamlv3.Node, error) {
+	switch vv := v.(type) {
+	case *yamlv3.Node:
+		return vv, nil
+	case yaml.MapSlice:
+		n := &yamlv3.Node{Kind: yamlv3.MappingNode}
+		for _, item := range vv {
+			k, err := valueNode(item.Key)
+			if err != nil {
+				return nil, err
+			}
+			value, err := valueNode(item.Value)
+			if err != nil {
+				return nil, err
+			}
+			n.Content = append(n.Content, k, value)
+		}
+		return n, nil
+	case []interface{}:
+		n := &yamlv3.Node{Kind: yamlv3.SequenceNode}
+		for _, a := range vv {
+			c, err := valueNode(a)
+			if err != nil {
+				return nil, err
+			}
+			n.Content = append(n.Content, c)
+		}
+		return n, nil
+	case LocalDateTime:
+		// 15:04 のように YAML では日時として読めない形は文字列にする
+		var n yamlv3.Node
+		if err := yamlv3.Unmarshal([]byte(vv), &n); err == nil && len(n.Content) == 1 && n.Content[0].ShortTag() == "!!timestamp" {
+			return &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!timestamp", Value: string(vv)}, nil
+		}
+		v = string(vv)
+	}
+	n := new(yamlv3.Node)
+	if err := n.Encode(v); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// yaml.v2 で読んだときと同じ型にする. 日時は書かれたままの文字列.
+// ordered のとき mapping は key の順番を保った yaml.MapSlice, そうでなければ map[interface{}]interface{}.
+func decodeNode(n *yamlv3.Node, ordered bool) (interface{}, error) {
+	switch n.Kind {
+	case yamlv3.DocumentNode:
+		if len(n.Content) == 0 {
+			return nil, nil
+		}
+		return decodeNode(n.Content[0], ordered)
+	case yamlv3.AliasNode:
+		return decodeNode(n.Alias, ordered)
+	case yamlv3.SequenceNode:
+		list := make([]interface{}, 0, len(n.Content))
+		for _, c := range n.Content {
+			v, err := decodeNode(c, ordered)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	case yamlv3.MappingNode:
+		items, err := decodeMapping(n)
+		if err != nil {
+			return nil, err
+		}
+		if ordered {
+			for i := range items {
+				if items[i].Value, err = decodeNode(items[i].Value.(*yamlv3.Node), true); err != nil {
+					return nil, err
+				}
+			}
+			return items, nil
+		}
+		m := make(map[interface{}]interface{}, len(items))
+		for _, item := range items {
+			if m[item.Key], err = decodeNode(item.Value.(*yamlv3.Node), false); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case yamlv3.ScalarNode:
+		switch n.ShortTag() {
+		case "!!str", "!!timestamp":
+			return n.Value, nil
+		case "!!null":
+			return nil, nil
+		}
+		var v interface{}
+		if err := n.Decode(&v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+	return nil, nil
+}
+
+// 値は Node のまま返す. << で取り込んだ key は同じ mapping に書かれた key より後ろに置き, 上書きしない.
+func decodeMapping(n *yamlv3.Node) (yaml.MapSlice, error) {
+	items := make(yaml.MapSlice, 0, len(n.Content)/2)
+	merged := make(yaml.MapSlice, 0)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		if k.ShortTag() == "!!merge" {
+			sources := []*yamlv3.Node{v}
+			if v.Kind == yamlv3.SequenceNode {
+				sources = v.Content
+			}
+			for _, src := range sources {
+				for src.Kind == yamlv3.AliasNode {
+					src = src.Alias
+				}
+				if src.Kind != yamlv3.MappingNode {
+					return nil, fmt.Errorf("map merge requires map or sequence of maps as the value")
+				}
+				m, err := decodeMapping(src)
+				if err != nil {
+					return nil, err
+				}
+				merged = append(merged, m...)
+			}
+			continue
+		}
+		key, err := decodeNode(k, false)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, yaml.MapItem{Key: key, Value: v})
+	}
+	for _, item := range merged {
+		exists := false
+		for _, a := range items {
+			if a.Key == item.Key {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// 値を書き換えるときに元の書き方を引き継ぐ. リストに残った要素や, リストにした元の値は元の書き方のまま書く.
+func keepStyle(n *yamlv3.Node, org *yamlv3.Node) {
+	if n.Kind == yamlv3.SequenceNode {
+		items := org.Content
+		if org.Kind == yamlv3.ScalarNode {
+			// 行末のコメントはリストに付け直す
+			item := *org
+			item.LineComment = ""
+			items = []*yamlv3.Node{&item}
+		}
+		keepItems(n, items)
+	}
+	switch {
+	case n.Kind != org.Kind:
+	case n.Kind != yamlv3.ScalarNode:
+		n.Style = org.Style & yamlv3.FlowStyle
+	case n.ShortTag() == "!!str" && org.ShortTag() == "!!str":
+		n.Style = org.Style &^ yamlv3.TaggedStyle
+	}
+	n.LineComment = org.LineComment
+}
+
+func keepItems(n *yamlv3.Node, items []*yamlv3.Node) {
+	for i, c := range n.Content {
+		v, err := decodeNode(c, false)
+		if err != nil {
+			continue
+		}
+		for _, o := range items {
+			if o.Kind != c.Kind {
+				continue
+			}
+			if ov, err := decodeNode(o, false); err == nil && reflect.DeepEqual(v, ov) {
+				n.Content[i] = o
+				break
+			}
+		}
+	}
+}
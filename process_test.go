@@ -3,12 +3,17 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"io"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/qawatake/obsdconv/convert"
+	"github.com/qawatake/obsdconv/process"
 )
 
 func TestExamineYaml(t *testing.T) {
@@ -52,7 +57,69 @@ draft: true`),
 	}
 
 	for _, tt := range cases {
-		got, err := newYamlExaminatorImpl("", tt.publishable).ExamineYaml(tt.yml)
+		fm, err := process.ParseFrontMatter(tt.yml, process.FRONT_MATTER_YAML)
+		if err != nil {
+			t.Fatalf("[FATAL | %s] ParseFrontMatter failed: %v", tt.name, err)
+		}
+		got, err := newYamlExaminatorImpl(nil, tt.publishable).ExamineYaml(fm, nil, "")
+		if err != nil {
+			t.Fatalf("[FATAL | %s] ExamineYaml failed: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("[ERROR | %s] got: %v, want: %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestExamineYamlWithFilter(t *testing.T) {
+	cases := []struct {
+		name   string
+		filter string
+		yml    []byte
+		body   string
+		path   string
+		want   bool
+	}{
+		{
+			name:   "path glob",
+			filter: `path glob "blog/**" && !(path glob "blog/drafts/*")`,
+			yml:    []byte(`title: post`),
+			path:   "blog/2021/post.md",
+			want:   true,
+		},
+		{
+			name:   "path glob does not match",
+			filter: `path glob "blog/*"`,
+			yml:    []byte(`title: post`),
+			path:   "blog/2021/post.md",
+			want:   false,
+		},
+		{
+			name:   "body tags",
+			filter: `bodytags contains "public" && !("draft" in bodytags)`,
+			yml:    []byte(`tags: [draft]`),
+			body:   "text #public\n```\n#draft\n```\n",
+			want:   true,
+		},
+		{
+			name:   "non-bool values with comparisons",
+			filter: `publish == "yes" && count > 1`,
+			yml: []byte(`publish: "yes"
+count: 2`),
+			want: true,
+		},
+	}
+
+	for _, tt := range cases {
+		filter, err := compileFilter(tt.filter)
+		if err != nil {
+			t.Fatalf("[FATAL | %s] compileFilter failed: %v", tt.name, err)
+		}
+		fm, err := process.ParseFrontMatter(tt.yml, process.FRONT_MATTER_YAML)
+		if err != nil {
+			t.Fatalf("[FATAL | %s] ParseFrontMatter failed: %v", tt.name, err)
+		}
+		got, err := newYamlExaminatorImpl(filter, false).ExamineYaml(fm, []rune(tt.body), tt.path)
 		if err != nil {
 			t.Fatalf("[FATAL | %s] ExamineYaml failed: %v", tt.name, err)
 		}
@@ -171,7 +238,18 @@ func TestConvertBody(t *testing.T) {
 	for _, tt := range cases {
 		vault := filepath.Join(test_CONVERT_BODY_DIR, tt.rootDir, tt.srcDir)
 		db := convert.NewPathDB(vault)
-		c := newBodyConverterImpl(db, tt.cptag, tt.rmtag, tt.cmmt, tt.title, tt.link, tt.rmH1, tt.formatLink, tt.formatAnchor, nil)
+		c := newBodyConverterImpl(bodyConverterOptions{
+			db:                    db,
+			cptag:                 tt.cptag,
+			rmtag:                 tt.rmtag,
+			cmmt:                  tt.cmmt,
+			title:                 tt.title,
+			link:                  tt.link,
+			rmH1:                  tt.rmH1,
+			formatLink:            tt.formatLink,
+			anchorFormattingStyle: tt.formatAnchor,
+			vault:                 vault,
+		})
 
 		srcFileName := filepath.Join(vault, tt.rawFileName)
 		srcFile, err := os.Open(srcFileName)
@@ -185,7 +263,7 @@ func TestConvertBody(t *testing.T) {
 		srcFile.Close()
 
 		// output, gotTitle, gotTags, err := c.ConvertBody([]rune(string(raw)))
-		output, aux, err := c.ConvertBody([]rune(string(raw)), tt.rawFileName)
+		output, aux, err := c.ConvertBody([]rune(string(raw)), nil, tt.rawFileName)
 		if err != nil {
 			t.Fatalf("[FATAL | %s] unexpected error occurred: %v", tt.name, err)
 		}
@@ -261,10 +339,21 @@ func TestConvertYAML(t *testing.T) {
 		synctlal    bool
 		publishable bool
 		remap       map[string]string
+		importtag   bool
+		merge       string
+		tagRules    *tagRules
+		dateOptions *dateOptions
+		rules       string
 		raw         []byte
 		title       string
 		alias       string
 		tags        []string
+		fields      []convert.InlineField
+		tasks       *taskCounts
+		math        bool
+		summary     string
+		stats       *textStats
+		dates       *dateSources
 		want        string
 	}{
 		{
@@ -274,14 +363,14 @@ publish: true`),
 			title: "211027",
 			alias: "today",
 			tags:  []string{"todo", "math"},
-			want: `aliases:
-- today
-cssclass: index-page
+			want: `cssclass: index-page
 publish: true
+title: "211027"
+aliases:
+- today
 tags:
 - todo
 - math
-title: "211027"
 `,
 		},
 		//////////
@@ -293,14 +382,14 @@ title: 211026`),
 			title: "211027",
 			alias: "today",
 			tags:  []string{"todo", "math"},
-			want: `aliases:
-- today
-cssclass: index-page
+			want: `cssclass: index-page
 publish: true
+title: "211027"
+aliases:
+- today
 tags:
 - todo
 - math
-title: "211027"
 `,
 		},
 		//////////
@@ -314,15 +403,15 @@ aliases:
 			title: "211027",
 			alias: "birthday",
 			tags:  []string{"todo", "math"},
-			want: `aliases:
+			want: `cssclass: index-page
+publish: true
+aliases:
 - today
 - birthday
-cssclass: index-page
-publish: true
+title: "211027"
 tags:
 - todo
 - math
-title: "211027"
 `,
 		},
 		//////////
@@ -336,14 +425,14 @@ aliases:
 			title: "211027",
 			alias: "today",
 			tags:  []string{"todo", "math"},
-			want: `aliases:
-- today
-cssclass: index-page
+			want: `cssclass: index-page
 publish: true
+aliases:
+- today
+title: "211027"
 tags:
 - todo
 - math
-title: "211027"
 `,
 		},
 		//////////
@@ -357,15 +446,15 @@ tags:
 			title: "211027",
 			alias: "today",
 			tags:  []string{"todo", "math"},
-			want: `aliases:
-- today
-cssclass: index-page
+			want: `cssclass: index-page
 publish: true
 tags:
 - book
 - todo
 - math
 title: "211027"
+aliases:
+- today
 `,
 		},
 		//////////
@@ -380,15 +469,15 @@ tags:
 			title: "211027",
 			alias: "today",
 			tags:  []string{"todo", "math"},
-			want: `aliases:
-- today
-cssclass: index-page
+			want: `cssclass: index-page
 publish: true
 tags:
 - book
 - math
 - todo
 title: "211027"
+aliases:
+- today
 `,
 		},
 		//////////
@@ -401,15 +490,15 @@ publish: true
 			alias:       "today",
 			tags:        []string{"todo", "math"},
 			publishable: true,
-			want: `aliases:
-- today
-cssclass: index-page
-draft: false
+			want: `cssclass: index-page
 publish: true
+title: "211027"
+aliases:
+- today
 tags:
 - todo
 - math
-title: "211027"
+draft: false
 `,
 		},
 		//////////
@@ -421,15 +510,15 @@ publish: false`),
 			alias:       "today",
 			tags:        []string{"todo", "math"},
 			publishable: true,
-			want: `aliases:
-- today
-cssclass: index-page
-draft: true
+			want: `cssclass: index-page
 publish: false
+title: "211027"
+aliases:
+- today
 tags:
 - todo
 - math
-title: "211027"
+draft: true
 `,
 		},
 		//////////
@@ -441,14 +530,14 @@ title: "211027"
 			alias:       "today",
 			tags:        []string{"todo", "math"},
 			publishable: true,
-			want: `aliases:
+			want: `cssclass: index-page
+title: "211027"
+aliases:
 - today
-cssclass: index-page
-draft: true
 tags:
 - todo
 - math
-title: "211027"
+draft: true
 `,
 		},
 		//////////
@@ -461,15 +550,15 @@ draft: true`),
 			alias:       "today",
 			tags:        []string{"todo", "math"},
 			publishable: true,
-			want: `aliases:
-- today
-cssclass: index-page
-draft: true
+			want: `cssclass: index-page
 publish: true
+draft: true
+title: "211027"
+aliases:
+- today
 tags:
 - todo
 - math
-title: "211027"
 `,
 		},
 		//////////
@@ -482,14 +571,14 @@ publish: true
 			alias: "today",
 			tags:  []string{"todo", "math"},
 			// flags: &flagBundle{},
-			want: `aliases:
-- today
-cssclass: index-page
+			want: `cssclass: index-page
 publish: true
+title: "211027"
+aliases:
+- today
 tags:
 - todo
 - math
-title: "211027"
 `,
 		},
 		{
@@ -503,14 +592,14 @@ tags:
 			title: "211027",
 			alias: "today",
 			tags:  []string{"todo", "math"},
-			want: `aliases:
-- today
-cssclass: index-page
+			want: `cssclass: index-page
 publish: true
 tags:
 - todo
 - math
 title: "211027"
+aliases:
+- today
 `,
 		},
 		{
@@ -577,21 +666,343 @@ title: existing-title
 `),
 			title: "211208",
 			alias: "today",
-			want: `publish: true
+			want: `xaliases:
+- existing-alias
+- today
+publish: true
 tags:
 - book
 title: "211208"
-xaliases:
-- existing-alias
+`,
+		},
+		{
+			name:      "tags moved to text",
+			importtag: true,
+			raw: []byte(`publish: true
+tags:
+- book
+- with space
+`),
+			want: `publish: true
+tags:
+- with space
+`,
+		},
+		{
+			name: "inline fields",
+			raw:  []byte(`status: published`),
+			fields: []convert.InlineField{
+				{Key: "status", Value: "draft"},
+				{Key: "due", Value: "2026-11-01"},
+				{Key: "start", Value: "2026-11-01T09:30"},
+				{Key: "end", Value: "2026-11-01T18:00:00+09:00"},
+				{Key: "done", Value: "false"},
+				{Key: "rating", Value: "4.5"},
+				{Key: "authors", Value: "alice, bob"},
+				{Key: "author", Value: "carol"},
+				{Key: "author", Value: "dave"},
+				{Key: "quote", Value: `"a, b"`},
+			},
+			want: `status: published
+due: 2026-11-01
+start: 2026-11-01T09:30
+end: 2026-11-01T18:00:00+09:00
+done: false
+rating: 4.5
+authors:
+- alice
+- bob
+author:
+- carol
+- dave
+quote: a, b
+`,
+		},
+		{
+			name:  "inline fields overwrite",
+			merge: INLINE_FIELD_MERGE_OVERWRITE,
+			raw:   []byte(`status: published`),
+			fields: []convert.InlineField{
+				{Key: "status", Value: "draft"},
+			},
+			want: `status: draft
+`,
+		},
+		{
+			name:  "inline fields append",
+			merge: INLINE_FIELD_MERGE_APPEND,
+			raw: []byte(`priority: 1
+tags:
+- book
+`),
+			fields: []convert.InlineField{
+				{Key: "priority", Value: "1"},
+				{Key: "tags", Value: "book, novel"},
+			},
+			want: `priority: 1
+tags:
+- book
+- novel
+`,
+		},
+		{
+			name:  "inline field dates append",
+			merge: INLINE_FIELD_MERGE_APPEND,
+			raw: []byte(`due: 2026-11-01
+`),
+			fields: []convert.InlineField{
+				{Key: "due", Value: "2026-11-01"},
+				{Key: "due", Value: "2026-11-02"},
+			},
+			want: `due:
+- 2026-11-01
+- 2026-11-02
+`,
+		},
+		{
+			name:  "task counts",
+			raw:   []byte(`tasks_open: 5`),
+			tasks: &taskCounts{open: 2, done: 0, total: 3},
+			want: `tasks_open: 2
+tasks_done: 0
+`,
+		},
+		{
+			name:  "task counts removed without tasks",
+			raw:   []byte("title: note\ntasks_open: 5\ntasks_done: 1"),
+			tasks: &taskCounts{},
+			want: `title: note
+`,
+		},
+		{
+			name: "math",
+			raw:  []byte(`title: note`),
+			math: true,
+			want: `title: note
+math: true
+`,
+		},
+		{
+			name: "tag rules",
+			tagRules: &tagRules{
+				expand: true,
+				routes: []tagRoute{{prefix: "cat", key: "categories"}},
+			},
+			raw: []byte(`categories: tech
+tags:
+- area/old
+`),
+			tags: []string{"area/infra/k8s", "cat/tech/go"},
+			want: `categories:
+- tech
+- tech/go
+tags:
+- area/old
+- area
+- area/infra
+- area/infra/k8s
+`,
+		},
+		{
+			name: "tag rules for front matter",
+			tagRules: &tagRules{
+				rename:      map[string]string{"k8s": "kubernetes"},
+				slugify:     true,
+				routes:      []tagRoute{{prefix: "series", key: "series"}},
+				frontMatter: true,
+			},
+			raw: []byte(`tags:
+- K8s
+- Go Lang
+- series/intro
+`),
+			tags: []string{"k8s"},
+			want: `tags:
+- k8s
+- go-lang
+- kubernetes
+series:
+- intro
+`,
+		},
+		{
+			name: "tags and aliases as strings",
+			raw: []byte(`tags: "#todo, Math #work"
+aliases: birthday
+`),
+			alias: "today",
+			tags:  []string{"math", "new"},
+			want: `tags:
+- todo
+- Math
+- work
+- new
+aliases:
+- birthday
 - today
+`,
+		},
+		{
+			name: "legacy tag and alias keys",
+			raw: []byte(`tag: todo
+tags:
+- "#Todo"
+- done
+alias: [birthday]
+`),
+			want: `tags:
+- Todo
+- done
+aliases: [birthday]
+`,
+		},
+		{
+			name: "math already set",
+			raw:  []byte(`math: false`),
+			math: true,
+			want: `math: false
+`,
+		},
+		{
+			name:    "summary and reading time",
+			raw:     []byte(`title: note`),
+			summary: "Hello world.",
+			stats:   &textStats{words: 2, readingTime: 1},
+			want: `title: note
+description: Hello world.
+wordcount: 2
+readingtime: 1
+`,
+		},
+		{
+			name:    "description already set",
+			raw:     []byte(`description: written by hand`),
+			summary: "Hello world.",
+			want: `description: written by hand
+`,
+		},
+		{
+			name:    "remap summary",
+			raw:     []byte(``),
+			remap:   map[string]string{"description": "summary"},
+			summary: "Hello world.",
+			want: `summary: Hello world.
+`,
+		},
+		{
+			name:        "date from existing key and lastmod from git",
+			dateOptions: &dateOptions{date: true, lastmod: true, dateKey: "created", lastmodKey: "updated", format: "2006-01-02"},
+			raw:         []byte(`created: 2021-10-27 09:30`),
+			dates: &dateSources{
+				fileName:    time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC),
+				gitCreated:  time.Date(2021, 10, 2, 0, 0, 0, 0, time.UTC),
+				gitModified: time.Date(2021, 11, 3, 0, 0, 0, 0, time.UTC),
+				mtime:       time.Date(2021, 12, 4, 0, 0, 0, 0, time.UTC),
+			},
+			want: `created: 2021-10-27 09:30
+date: "2021-10-27"
+lastmod: "2021-11-03"
+`,
+		},
+		{
+			name:        "date from file name and lastmod from mtime",
+			dateOptions: &dateOptions{date: true, lastmod: true, dateKey: "created", lastmodKey: "updated", format: time.RFC3339},
+			raw:         []byte(`updated: not a date`),
+			dates: &dateSources{
+				fileName:   time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC),
+				gitCreated: time.Date(2021, 10, 2, 0, 0, 0, 0, time.UTC),
+				mtime:      time.Date(2021, 12, 4, 10, 0, 0, 0, time.UTC),
+			},
+			want: `updated: not a date
+date: "2021-10-01T00:00:00Z"
+lastmod: "2021-12-04T10:00:00Z"
+`,
+		},
+		{
+			name:        "existing date kept",
+			dateOptions: &dateOptions{date: true, dateKey: "created", format: "2006-01-02"},
+			raw:         []byte(`date: 2020-01-01`),
+			dates:       &dateSources{mtime: time.Date(2021, 12, 4, 0, 0, 0, 0, time.UTC)},
+			want: `date: 2020-01-01
+`,
+		},
+		{
+			name:        "existing date overwritten",
+			dateOptions: &dateOptions{date: true, dateKey: "created", format: "2006-01-02", overwrite: true},
+			raw:         []byte(`date: 2020-01-01`),
+			dates:       &dateSources{mtime: time.Date(2021, 12, 4, 0, 0, 0, 0, time.UTC)},
+			want: `date: "2021-12-04"
+`,
+		},
+		{
+			name: "front matter rules in order",
+			rules: `
+- default: {layout: post, draft: false}
+- copy: {from: title, to: linkTitle}
+- coerce: {publish: bool, weight: int}
+- map: {key: status, to: draft, values: {wip: true, done: false}}
+- delete: [cssclass, "kanban-*", status]
+- rename: {from: linkTitle, to: shortTitle}
+`,
+			raw: []byte(`title: note
+publish: "true"
+weight: "3"
+status: wip
+cssclass: wide
+kanban-plugin: basic`),
+			want: `title: note
+publish: true
+weight: 3
+layout: post
+draft: true
+shortTitle: note
+`,
+		},
+		{
+			name: "front matter rules with conditions",
+			rules: `
+- set: {draft: true}
+  if: "!publish"
+- map: {key: status, values: {wip: draft}, default: final}
+  if: publish
+`,
+			raw: []byte(`publish: true
+status: done`),
+			want: `publish: true
+status: final
+`,
+		},
+		{
+			name:        "draft from rules is kept by pub",
+			publishable: true,
+			rules: `
+- map: {key: status, to: draft, values: {done: false}}
+`,
+			raw: []byte(`status: done`),
+			want: `status: done
+draft: false
 `,
 		},
 	}
 
 	for _, tt := range cases {
-		yc := newYamlConverterImpl(tt.synctag, tt.synctlal, tt.publishable, tt.remap)
-		auxinput := newYamlConvAuxInImpl(tt.title, tt.alias, tt.tags)
-		got, err := yc.ConvertYAML(tt.raw, auxinput)
+		rules, err := parseFrontMatterRules([]byte(tt.rules))
+		if err != nil {
+			t.Fatalf("[FATAL | %s] unexpected error occurred while parsing rules: %v", tt.name, err)
+		}
+		yc := newYamlConverterImpl(yamlConverterOptions{
+			synctag:          tt.synctag,
+			synctlal:         tt.synctlal,
+			publishable:      tt.publishable,
+			remap:            tt.remap,
+			importtag:        tt.importtag,
+			inlineFieldMerge: tt.merge,
+			tagRules:         tt.tagRules,
+			dateOptions:      tt.dateOptions,
+			rules:            rules,
+		})
+		auxinput := newYamlConvAuxInImpl(tt.title, tt.alias, tt.tags, tt.fields, tt.tasks, tt.math, tt.summary, tt.stats, tt.dates, "")
+		got, err := convertYAML(yc, tt.raw, auxinput)
 		if err != nil {
 			t.Fatalf("[FATAL | %s] unexpected error occurred: %v", tt.name, err)
 		}
@@ -601,14 +1012,34 @@ xaliases:
 	}
 }
 
+// front matter を読んで変換し, YAML で書き出す
+func convertYAML(yc process.YamlConverter, raw []byte, aux process.YamlConvAuxIn) ([]byte, error) {
+	fm, err := process.ParseFrontMatter(raw, process.FRONT_MATTER_YAML)
+	if err != nil {
+		return nil, err
+	}
+	// 警告の場合も front matter は書き出す
+	convErr := yc.ConvertYAML(fm, aux)
+	if _, ok := convErr.(process.ErrWarning); convErr != nil && !ok {
+		return nil, convErr
+	}
+	got, err := fm.Encode(process.FRONT_MATTER_YAML)
+	if err != nil {
+		return nil, err
+	}
+	return got, convErr
+}
+
 func TestPassArg(t *testing.T) {
 	cases := []struct {
-		name       string
-		title      bool
-		alias      bool
-		iter       int
-		frombody   bodyConvAuxOutImpl
-		wantToyaml yamlConvAuxInImpl
+		name          string
+		title         bool
+		alias         bool
+		fallbackTitle bool
+		titleTemplate string
+		iter          int
+		frombody      bodyConvAuxOutImpl
+		wantToyaml    yamlConvAuxInImpl
 	}{
 		{
 			name:  "title & alias & tags",
@@ -645,6 +1076,77 @@ func TestPassArg(t *testing.T) {
 				alias: "title",
 			},
 		},
+		{
+			name:          "fallback to title in front matter",
+			title:         true,
+			alias:         true,
+			fallbackTitle: true,
+			frombody: bodyConvAuxOutImpl{
+				sources: &titleSources{frontMatter: "existing", heading: "section", fileName: "note"},
+			},
+			wantToyaml: yamlConvAuxInImpl{
+				title: "existing",
+			},
+		},
+		{
+			name:          "fallback to first heading",
+			title:         true,
+			fallbackTitle: true,
+			frombody: bodyConvAuxOutImpl{
+				sources: &titleSources{heading: "section", fileName: "note"},
+			},
+			wantToyaml: yamlConvAuxInImpl{
+				title: "section",
+			},
+		},
+		{
+			name:          "fallback to file name",
+			title:         true,
+			fallbackTitle: true,
+			frombody: bodyConvAuxOutImpl{
+				sources: &titleSources{fileName: "note"},
+			},
+			wantToyaml: yamlConvAuxInImpl{
+				title: "note",
+			},
+		},
+		{
+			name:          "h1 has priority over fallbacks",
+			title:         true,
+			fallbackTitle: true,
+			frombody: bodyConvAuxOutImpl{
+				title:   "title",
+				sources: &titleSources{frontMatter: "existing", fileName: "note"},
+			},
+			wantToyaml: yamlConvAuxInImpl{
+				title: "title",
+			},
+		},
+		{
+			name:          "title template with date prefix",
+			title:         true,
+			titleTemplate: "{{trimDate .FileName}} ({{.Date}})",
+			frombody: bodyConvAuxOutImpl{
+				sources: &titleSources{fileName: "2026-10-17 Standup"},
+			},
+			wantToyaml: yamlConvAuxInImpl{
+				title: "Standup (2026-10-17)",
+			},
+		},
+		{
+			name:          "title template with h1",
+			title:         true,
+			alias:         true,
+			titleTemplate: "{{.Title}} | Blog",
+			frombody: bodyConvAuxOutImpl{
+				title:   "title",
+				sources: &titleSources{fileName: "note"},
+			},
+			wantToyaml: yamlConvAuxInImpl{
+				title: "title | Blog",
+				alias: "title",
+			},
+		},
 	}
 
 	for _, tt := range cases {
@@ -652,8 +1154,12 @@ func TestPassArg(t *testing.T) {
 		if iter == 0 {
 			iter = 1
 		}
+		titleTemplate, err := parseTitleTemplate(tt.titleTemplate)
+		if err != nil {
+			t.Fatalf("[FATAL | %s] unexpected error occurred: %v", tt.name, err)
+		}
 		for range make([]struct{}, iter) {
-			passer := newArgPasserImpl(tt.title, tt.alias)
+			passer := newArgPasserImpl(tt.title, tt.alias, tt.fallbackTitle, titleTemplate)
 			got, err := passer.PassArg(&tt.frombody)
 			if err != nil {
 				t.Fatalf("[FATAL] unexpected error occurred: %v", err)
@@ -745,3 +1251,519 @@ func TestParseRemap(t *testing.T) {
 		}
 	}
 }
+
+func TestParseTagRoutes(t *testing.T) {
+	cases := []struct {
+		input   string
+		want    []tagRoute
+		wantErr mainErr
+	}{
+		{
+			input: "#cat/>categories|series>series",
+			want:  []tagRoute{{prefix: "cat", key: "categories"}, {prefix: "series", key: "series"}},
+		},
+		{
+			input:   "cat:categories",
+			wantErr: newMainErrf(MAIN_ERR_KIND_INVALID_ROUTE_TAG_FORMAT, "invalid route tag format"),
+		},
+		{
+			input: "",
+			want:  nil,
+		},
+	}
+
+	for _, tt := range cases {
+		got, gotErr := parseTagRoutes(tt.input)
+		if gotErr != nil {
+			if tt.wantErr == nil {
+				t.Fatalf("[FATAL] unexpected error occurred: %v with input: %s", gotErr, tt.input)
+			}
+			if e, ok := gotErr.(mainErr); !ok || e.Kind() != tt.wantErr.Kind() {
+				t.Fatalf("[FATAL] unexpected error occurred: %v with input: %s", gotErr, tt.input)
+			}
+			continue
+		} else if tt.wantErr != nil {
+			t.Errorf("[ERROR] expected error did not occurr: %v with input: %s", tt.wantErr, tt.input)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("[ERROR] got: %v, want: %v for input: %s", got, tt.want, tt.input)
+		}
+	}
+}
+
+func TestTagLinkWithTagRules(t *testing.T) {
+	rules := &tagRules{
+		rename:  map[string]string{"k8s": "kubernetes"},
+		slugify: true,
+		routes:  []tagRoute{{prefix: "cat", key: "categories"}},
+	}
+	raw := []rune("#Area/K8s #k8s #cat/tech [#Area/K8s](https://example.com)")
+	want := "[#Area/K8s](/tags/area/k8s/) [#k8s](/tags/kubernetes/) #cat/tech [#Area/K8s](https://example.com)"
+	got, err := convert.NewTagLinker(convert.DEFAULT_TAG_LINK_TEMPLATE, rules.pageName).Convert(raw)
+	if err != nil {
+		t.Fatalf("[FATAL] unexpected error occurred: %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("[ERROR] got: %q, want: %q", string(got), want)
+	}
+}
+
+func TestNewPageIndexSkipsBrokenFrontMatter(t *testing.T) {
+	vault := t.TempDir()
+	files := map[string]string{
+		"good.md":   "---\ntags: [a]\n---\ntext #b\n",
+		"broken.md": "---\ntags: [a\n---\ntext\n",
+		"toml.md":   "+++\ntags = \n+++\ntext\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(vault, name), []byte(content), 0644); err != nil {
+			t.Fatalf("[FATAL] failed to write %s: %v", name, err)
+		}
+	}
+	skipper, err := process.NewSkipper(filepath.Join(vault, DEFAULT_IGNORE_FILE_NAME))
+	if err != nil {
+		t.Fatalf("[FATAL] unexpected error occurred: %v", err)
+	}
+	pages, warnings, err := newPageIndex(vault, vault, skipper, newYamlExaminatorImpl(nil, false))
+	if err != nil {
+		t.Fatalf("[FATAL] unexpected error occurred: %v", err)
+	}
+	if len(pages) != 1 || pages[0].Path != "good.md" || !reflect.DeepEqual(pages[0].Tags, []string{"a", "b"}) {
+		t.Errorf("[ERROR] got pages: %v", pages)
+	}
+	if len(warnings) != 2 {
+		t.Fatalf("[FATAL] got warnings: %v", warnings)
+	}
+	for i, name := range []string{"broken.md", "toml.md"} {
+		if !strings.Contains(warnings[i].Error(), "[WARNING] path: "+filepath.Join(vault, name)) {
+			t.Errorf("[ERROR] warning %d does not name %s: %v", i, name, warnings[i])
+		}
+	}
+}
+
+func TestNewPageIndexExaminesNotes(t *testing.T) {
+	vault := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(vault, "blog"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"blog/post.md":  "---\npublish: true\n---\ntext\n",
+		"blog/draft.md": "---\npublish: false\n---\ntext\n",
+		"note.md":       "---\npublish: true\n---\ntext\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(vault, name), []byte(content), 0644); err != nil {
+			t.Fatalf("[FATAL] failed to write %s: %v", name, err)
+		}
+	}
+	skipper, err := process.NewSkipper(filepath.Join(vault, DEFAULT_IGNORE_FILE_NAME))
+	if err != nil {
+		t.Fatalf("[FATAL] unexpected error occurred: %v", err)
+	}
+	filter, err := compileFilter(`path glob "blog/**"`)
+	if err != nil {
+		t.Fatalf("[FATAL] unexpected error occurred: %v", err)
+	}
+	// -pub と -filter で変換しないノートは含めない
+	pages, warnings, err := newPageIndex(vault, vault, skipper, newYamlExaminatorImpl(filter, true))
+	if err != nil {
+		t.Fatalf("[FATAL] unexpected error occurred: %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("[ERROR] got warnings: %v", warnings)
+	}
+	if len(pages) != 1 || pages[0].Path != "blog/post.md" {
+		t.Errorf("[ERROR] got pages: %v", pages)
+	}
+}
+
+func TestCanonicalizeFrontMatter(t *testing.T) {
+	cases := []struct {
+		name        string
+		raw         string
+		want        string
+		wantChanged bool
+	}{
+		{
+			name: "already canonical",
+			raw: `title: note
+tags:
+- a
+`,
+			want: `title: note
+tags:
+- a
+`,
+			wantChanged: false,
+		},
+		{
+			name: "strings",
+			raw: `title: note
+tags: "#a, b #c"
+aliases: x
+draft: true
+`,
+			want: `title: note
+tags:
+- a
+- b
+- c
+aliases:
+- x
+draft: true
+`,
+			wantChanged: true,
+		},
+		{
+			name: "legacy keys are merged",
+			raw: `tag: a
+title: note
+tags: [A, b]
+`,
+			want: `tags:
+- A
+- b
+title: note
+`,
+			wantChanged: true,
+		},
+		{
+			name: "comments and quotes are kept",
+			raw: `# written by hand
+title: 'note'  # keep this
+tags: a
+`,
+			want: `# written by hand
+title: 'note'  # keep this
+tags:
+- a
+`,
+			wantChanged: true,
+		},
+	}
+
+	for _, tt := range cases {
+		fm, err := process.ParseFrontMatter([]byte(tt.raw), process.FRONT_MATTER_YAML)
+		if err != nil {
+			t.Fatalf("[FATAL | %s] ParseFrontMatter failed: %v", tt.name, err)
+		}
+		changed, err := canonicalizeFrontMatter(fm)
+		if err != nil {
+			t.Fatalf("[FATAL | %s] unexpected error occurred: %v", tt.name, err)
+		}
+		got, err := fm.Encode(process.FRONT_MATTER_YAML)
+		if err != nil {
+			t.Fatalf("[FATAL | %s] Encode failed: %v", tt.name, err)
+		}
+		if changed != tt.wantChanged {
+			t.Errorf("[ERROR | %s] got changed: %v, want: %v", tt.name, changed, tt.wantChanged)
+		}
+		if string(got) != tt.want {
+			t.Errorf("[ERROR | %s]\ngot:\n%q\nwant:\n%q", tt.name, string(got), tt.want)
+		}
+	}
+}
+
+func TestFixFrontMatter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "note.md")
+	raw := "---\ntags: a, b\n---\r\n# Title\r\nbody without newline"
+	if err := os.WriteFile(path, []byte(raw), 0644); err != nil {
+		t.Fatalf("[FATAL] failed to write %s: %v", path, err)
+	}
+	if err := fixFrontMatter(path); err != nil {
+		t.Fatalf("[FATAL] unexpected error occurred: %v", err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("[FATAL] failed to read %s: %v", path, err)
+	}
+	want := "---\ntags:\n- a\n- b\n---\r\n# Title\r\nbody without newline"
+	if string(got) != want {
+		t.Errorf("[ERROR]\ngot:\n%q\nwant:\n%q", string(got), want)
+	}
+}
+
+func TestParseGitLog(t *testing.T) {
+	out := []byte(strings.Join([]string{
+		"\x002021-11-04T10:00:00+09:00", "", "R100\tnotes/old.md\tnotes/new.md",
+		"\x002021-11-03T10:00:00+09:00", "", "M\tnotes/a.md", "A\tnotes/日本語.md", "M\tnotes/old.md",
+		"\x002021-10-02T09:00:00+09:00", "", "A\tnotes/a.md", "A\tnotes/old.md",
+		"\x002021-09-01T09:00:00+09:00", "", "D\tnotes/a.md",
+		"\x002021-08-01T09:00:00+09:00", "", "A\tnotes/a.md",
+	}, "\n") + "\n")
+	jst := time.FixedZone("", 9*60*60)
+	want := map[string]gitDates{
+		"notes/a.md": {
+			created:  time.Date(2021, 10, 2, 9, 0, 0, 0, jst),
+			modified: time.Date(2021, 11, 3, 10, 0, 0, 0, jst),
+		},
+		"notes/日本語.md": {
+			created:  time.Date(2021, 11, 3, 10, 0, 0, 0, jst),
+			modified: time.Date(2021, 11, 3, 10, 0, 0, 0, jst),
+		},
+		"notes/new.md": {
+			created:  time.Date(2021, 10, 2, 9, 0, 0, 0, jst),
+			modified: time.Date(2021, 11, 4, 10, 0, 0, 0, jst),
+		},
+	}
+	got, err := parseGitLog(out)
+	if err != nil {
+		t.Fatalf("[FATAL] parseGitLog failed: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("[FATAL] got: %v, want: %v", got, want)
+	}
+	for path, w := range want {
+		g, ok := got[path]
+		if !ok || !g.created.Equal(w.created) || !g.modified.Equal(w.modified) {
+			t.Errorf("[ERROR | %s] got: %v, want: %v", path, g, w)
+		}
+	}
+
+	// 長すぎる行は読めないので, 呼び出し側で mtime を使う
+	if _, err := parseGitLog([]byte("\x002021-11-03T10:00:00+09:00\n\nM\t" + strings.Repeat("a", 2*1024*1024) + "\n")); err == nil {
+		t.Errorf("[ERROR] error expected for too long line but got nil")
+	}
+}
+
+func TestParseFrontMatterRules(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+	}{
+		{name: "two actions", content: "- set: {a: 1}\n  delete: b\n"},
+		{name: "no action", content: "- if: a\n"},
+		{name: "unknown action", content: "- move: {from: a, to: b}\n"},
+		{name: "unknown type", content: "- coerce: {a: date}\n"},
+		{name: "copy without to", content: "- copy: {from: a}\n"},
+		{name: "invalid condition", content: "- set: {a: 1}\n  if: \"a &&\"\n"},
+		{name: "invalid pattern", content: "- delete: \"[\"\n"},
+	}
+
+	for _, tt := range cases {
+		_, err := parseFrontMatterRules([]byte(tt.content))
+		if err == nil {
+			t.Errorf("[ERROR | %s] error expected but got nil", tt.name)
+			continue
+		}
+		if e, ok := err.(mainErr); !ok || e.Kind() != MAIN_ERR_KIND_INVALID_FRONT_MATTER_RULES_FORMAT {
+			t.Errorf("[ERROR | %s] unexpected error: %v", tt.name, err)
+		}
+	}
+}
+
+func TestApplyFrontMatterRulesEvaluationError(t *testing.T) {
+	rules, err := parseFrontMatterRules([]byte("- set: {draft: true}\n  if: status\n"))
+	if err != nil {
+		t.Fatalf("[FATAL] unexpected error occurred while parsing rules: %v", err)
+	}
+	fm, err := process.ParseFrontMatter([]byte("status: wip\n"), process.FRONT_MATTER_YAML)
+	if err != nil {
+		t.Fatalf("[FATAL] ParseFrontMatter failed: %v", err)
+	}
+	err = applyFrontMatterRules(fm, rules)
+	if err == nil {
+		t.Fatalf("[FATAL] error expected but got nil")
+	}
+	// 評価に失敗した理由も出す
+	var e *FilterErr
+	if !errors.As(err, &e) || !strings.Contains(err.Error(), "status is not bool") {
+		t.Errorf("[ERROR] unexpected error: %v", err)
+	}
+}
+
+func TestValidateFrontMatterSchema(t *testing.T) {
+	schema := `
+- fields:
+    title: {required: true, type: string}
+    date: {required: true, type: date}
+    tags: {type: list, pattern: "^[a-z0-9-]+$"}
+    draft: {type: bool}
+- path: "blog/**"
+  fields:
+    status: {required: true, values: [wip, done]}
+- if: "!draft"
+  fields:
+    description: {required: true}
+`
+	cases := []struct {
+		name string
+		path string
+		raw  string
+		want []string
+	}{
+		{
+			name: "valid",
+			path: "blog/2021/post.md",
+			raw: `title: post
+date: 2021-06-01
+tags: [go, obsidian]
+draft: false
+status: done
+description: about go`,
+		},
+		{
+			name: "missing keys and wrong types",
+			path: "note.md",
+			raw: `date: yesterday
+tags: [Go]
+draft: "yes"`,
+			want: []string{
+				"schema violation: date: want date but got string",
+				"schema violation: draft: want bool but got string",
+				"schema violation: tags: Go does not match ^[a-z0-9-]+$",
+				"schema violation: title: required but not found",
+				"schema scope 3 (if: !draft) could not be evaluated: draft is not bool but string. use exists(draft) or a comparison",
+			},
+		},
+		{
+			name: "scoped by path and condition",
+			path: "blog/post.md",
+			raw: `title: post
+date: 2021-06-01T10:00:00+09:00
+status: draft
+draft: false`,
+			want: []string{
+				"schema violation: status: draft is not one of [wip done]",
+				"schema violation: description: required but not found",
+			},
+		},
+		{
+			name: "empty front matter",
+			path: "note.md",
+			raw:  ``,
+			want: []string{
+				"schema violation: date: required but not found",
+				"schema violation: title: required but not found",
+				"schema violation: description: required but not found",
+			},
+		},
+	}
+
+	s, err := parseFrontMatterSchema([]byte(schema))
+	if err != nil {
+		t.Fatalf("[FATAL] unexpected error occurred while parsing schema: %v", err)
+	}
+	for _, tt := range cases {
+		yc := newYamlConverterImpl(yamlConverterOptions{schema: s})
+		auxinput := newYamlConvAuxInImpl("", "", nil, nil, nil, false, "", nil, nil, tt.path)
+		got, err := convertYAML(yc, []byte(tt.raw), auxinput)
+		if tt.raw != "" && string(got) != tt.raw+"\n" {
+			t.Errorf("[ERROR | %s] front matter should be written as is but got %q", tt.name, string(got))
+		}
+		var violations []string
+		if err != nil {
+			w, ok := err.(process.ErrWarning)
+			if !ok {
+				t.Fatalf("[FATAL | %s] unexpected error occurred: %v", tt.name, err)
+			}
+			for _, w := range w.Warnings() {
+				violations = append(violations, w.Error())
+			}
+		}
+		if !reflect.DeepEqual(violations, tt.want) {
+			t.Errorf("[ERROR | %s]\ngot:\n%q\nwant:\n%q", tt.name, violations, tt.want)
+		}
+	}
+}
+
+func TestCountSchemaViolations(t *testing.T) {
+	errs := []error{
+		&schemaViolation{key: "title", message: "required but not found"},
+		&schemaScopeError{index: 1, cond: "draft", err: io.EOF},
+		io.EOF,
+	}
+	if got := countSchemaViolations(errs); got != 2 {
+		t.Errorf("[ERROR] got: %d, want: %d", got, 2)
+	}
+}
+
+func TestValidateFrontMatterSchemaWithBodyTags(t *testing.T) {
+	schema := `
+- fields:
+    tags: {type: list, values: [go, rust]}
+    aliases: {type: list, pattern: "^[A-Z]"}
+`
+	s, err := parseFrontMatterSchema([]byte(schema))
+	if err != nil {
+		t.Fatalf("[FATAL] unexpected error occurred while parsing schema: %v", err)
+	}
+	// 本文のタグと h1 からの aliases は []string のまま検証される
+	yc := newYamlConverterImpl(yamlConverterOptions{synctag: true, synctlal: true, schema: s})
+	auxinput := newYamlConvAuxInImpl("Go", "Go", []string{"go", "rust"}, nil, nil, false, "", nil, nil, "note.md")
+	got, err := convertYAML(yc, []byte("title: Go\n"), auxinput)
+	if err != nil {
+		t.Fatalf("[FATAL] unexpected error occurred: %v", err)
+	}
+	want := "title: Go\naliases:\n- Go\ntags:\n- go\n- rust\n"
+	if string(got) != want {
+		t.Errorf("[ERROR] got: %q, want: %q", string(got), want)
+	}
+}
+
+func TestParseFrontMatterSchema(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+	}{
+		{name: "unknown type", content: "- fields: {a: {type: datetime}}\n"},
+		{name: "unknown rule", content: "- fields: {a: {optional: true}}\n"},
+		{name: "invalid pattern", content: "- fields: {a: {pattern: \"[\"}}\n"},
+		{name: "invalid condition", content: "- if: \"a &&\"\n  fields: {a: {required: true}}\n"},
+		{name: "empty scope", content: "- \n"},
+	}
+
+	for _, tt := range cases {
+		_, err := parseFrontMatterSchema([]byte(tt.content))
+		if err == nil {
+			t.Errorf("[ERROR | %s] error expected but got nil", tt.name)
+			continue
+		}
+		if e, ok := err.(mainErr); !ok || e.Kind() != MAIN_ERR_KIND_INVALID_SCHEMA_FORMAT {
+			t.Errorf("[ERROR | %s] unexpected error: %v", tt.name, err)
+		}
+	}
+}
+
+func TestVaultPathDBsForNote(t *testing.T) {
+	base := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(base, "work", "posts"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(base, "work", "posts", "sample.md"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	input := "work>" + filepath.Join(base, "work") + "|blog>https://example.com/"
+	vaults, err := parseVaults(input, filepath.Join(base, "dst"), false, false)
+	if err != nil {
+		t.Fatalf("[FATAL] parseVaults failed: %v", err)
+	}
+
+	cases := []struct {
+		name         string
+		relativePath string
+		vault        string
+		fileId       string
+		want         string
+	}{
+		{name: "top", relativePath: "note.md", vault: "work", fileId: "sample", want: "../work/posts/sample.md"},
+		{name: "nested", relativePath: "a/b/note.md", vault: "work", fileId: "sample", want: "../../../work/posts/sample.md"},
+		{name: "remote", relativePath: "a/note.md", vault: "blog", fileId: "posts/sample", want: "https://example.com/posts/sample"},
+	}
+
+	for _, tt := range cases {
+		dbs, err := vaults.forNote(tt.relativePath)
+		if err != nil {
+			t.Errorf("[ERROR | %s] forNote failed: %v", tt.name, err)
+			continue
+		}
+		got, err := dbs[tt.vault].Get(tt.fileId)
+		if err != nil {
+			t.Errorf("[ERROR | %s] Get failed: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("[ERROR | %s] got: %q, want: %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -559,3 +559,324 @@ func ScanExternalLinkVarDefGroup(raw []rune, ptr int) (advance int, ptrs []int)
 		}
 	}
 }
+
+// ![alt](path/to/image.png "title") をスキャン
+func ScanImageLink(raw []rune, ptr int) (advance int, alt string, ref string, title string) {
+	if !unescaped(raw, ptr, "![") {
+		return 0, "", "", ""
+	}
+	cur := ptr + 1
+	adv, alt, ref, title := ScanExternalLink(raw, cur)
+	if adv == 0 {
+		return 0, "", "", ""
+	}
+	cur += adv
+	return cur - ptr, alt, ref, title
+}
+
+// ^[inline footnote] をスキャン
+// 中身の [, ] は対応がとれていれば許す
+func ScanInlineFootnote(raw []rune, ptr int) (advance int, content string) {
+	if !(unescaped(raw, ptr, "^[") && len(raw[ptr:]) >= 4) {
+		return 0, ""
+	}
+
+	cur := ptr + 2 // [ の次
+	depth := 0
+	for cur < len(raw) {
+		if raw[cur] == '\\' {
+			cur += 2
+			continue
+		}
+		// 空行をまたがない
+		if precededBy(raw, cur+1, []string{"\n\n", "\n\r\n"}) {
+			return 0, ""
+		}
+		if raw[cur] == '[' {
+			depth++
+		} else if raw[cur] == ']' {
+			if depth == 0 {
+				break
+			}
+			depth--
+		}
+		cur++
+	}
+	if cur >= len(raw) {
+		return 0, ""
+	}
+
+	content = strings.Trim(string(raw[ptr+2:cur]), " \t")
+	if content == "" {
+		return 0, ""
+	}
+	return cur + 1 - ptr, content
+}
+
+// [^label] をスキャン
+// 定義 [^label]: の場合も [^label] の部分だけスキャンする
+func ScanFootnoteRef(raw []rune, ptr int) (advance int, label string) {
+	if !(unescaped(raw, ptr, "[^") && len(raw[ptr:]) >= 4) {
+		return 0, ""
+	}
+
+	cur := ptr + 2
+	for cur < len(raw) && raw[cur] != ']' {
+		if unicode.IsSpace(raw[cur]) || raw[cur] == '[' {
+			return 0, ""
+		}
+		cur++
+	}
+	if cur >= len(raw) || cur == ptr+2 {
+		return 0, ""
+	}
+	return cur + 1 - ptr, string(raw[ptr+2 : cur])
+}
+
+// > [!type]+ title
+// > content
+// の形式の callout をスキャン.
+// content は各行の先頭の > (と直後の空白 1 つ) を除いたもの.
+// fold は折りたたみの指定 (+, - または空文字列).
+func ScanCallout(raw []rune, ptr int) (advance int, calloutType string, fold string, title string, content string) {
+	if !unescaped(raw, ptr, ">") {
+		return 0, "", "", "", ""
+	}
+	if ptr != 0 && !precededBy(raw, ptr, []string{"\n"}) {
+		return 0, "", "", "", ""
+	}
+
+	lines := make([]string, 0)
+	cur := ptr
+	for cur < len(raw) && raw[cur] == '>' {
+		lineEnd := cur
+		for lineEnd < len(raw) && raw[lineEnd] != '\n' {
+			lineEnd++
+		}
+		line := strings.TrimSuffix(string(raw[cur+1:lineEnd]), "\r")
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			line = line[1:]
+		}
+		lines = append(lines, line)
+		cur = lineEnd
+		if cur < len(raw) {
+			cur++ // \n の直後
+		}
+	}
+
+	head := strings.TrimLeft(lines[0], " \t")
+	if !strings.HasPrefix(head, "[!") {
+		return 0, "", "", "", ""
+	}
+	closing := strings.Index(head, "]")
+	if closing < 0 {
+		return 0, "", "", "", ""
+	}
+	calloutType = head[2:closing]
+	if calloutType == "" {
+		return 0, "", "", "", ""
+	}
+	for _, r := range calloutType {
+		if !(unicode.IsLetter(r) || unicode.IsNumber(r) || r == '-' || r == '_') {
+			return 0, "", "", "", ""
+		}
+	}
+	rest := head[closing+1:]
+	if strings.HasPrefix(rest, "+") || strings.HasPrefix(rest, "-") {
+		fold = rest[:1]
+		rest = rest[1:]
+	}
+	title = strings.Trim(rest, " \t")
+	content = strings.Join(lines[1:], "\n")
+	return cur - ptr, calloutType, fold, title, content
+}
+
+// ==highlight== をスキャン
+func ScanHighlight(raw []rune, ptr int) (advance int, content string) {
+	if !(unescaped(raw, ptr, "==") && len(raw[ptr:]) >= 5) {
+		return 0, ""
+	}
+	// === などの区切り線は除く
+	if raw[ptr+2] == '=' || precededBy(raw, ptr, []string{"="}) {
+		return 0, ""
+	}
+
+	cur := ptr + 2 // opening の == の直後
+	for {
+		adv := indexInRunes(raw[cur:], "==")
+		if adv < 0 {
+			return 0, ""
+		}
+		cur += adv
+		if unescaped(raw, cur, "==") {
+			break
+		}
+		cur++
+	}
+
+	content = string(raw[ptr+2 : cur])
+	if strings.Trim(content, " \t\r\n") == "" {
+		return 0, ""
+	}
+	// 空行をまたがない
+	if strings.Contains(content, "\n\n") || strings.Contains(content, "\n\r\n") {
+		return 0, ""
+	}
+	return cur + 2 - ptr, content
+}
+
+// [key:: value] または (key:: value) の形式の inline field をスキャン.
+// 行をまたがない. 直後に ( が続く場合はリンクなので除く.
+func ScanInlineField(raw []rune, ptr int) (advance int, key string, value string) {
+	var opening, closing rune
+	if unescaped(raw, ptr, "[") {
+		opening, closing = '[', ']'
+	} else if unescaped(raw, ptr, "(") {
+		opening, closing = '(', ')'
+	} else {
+		return 0, "", ""
+	}
+
+	depth := 0
+	cur := ptr + 1
+	for ; cur < len(raw); cur++ {
+		if raw[cur] == '\n' {
+			return 0, "", ""
+		}
+		if raw[cur] == opening {
+			depth++
+		} else if raw[cur] == closing {
+			if depth == 0 {
+				break
+			}
+			depth--
+		}
+	}
+	if cur >= len(raw) {
+		return 0, "", ""
+	}
+	if opening == '[' && followedBy(raw, cur, []string{"("}) {
+		return 0, "", ""
+	}
+
+	content := string(raw[ptr+1 : cur])
+	pos := strings.Index(content, "::")
+	if pos < 0 {
+		return 0, "", ""
+	}
+	key = strings.TrimRight(content[:pos], " \t")
+	if !validInlineFieldKey(key) {
+		return 0, "", ""
+	}
+	value = strings.Trim(content[pos+2:], " \t")
+	return cur + 1 - ptr, key, value
+}
+
+// 行頭の key:: value の形式の inline field をスキャン.
+// 先頭のリストマーカー (-, *, +) は許す.
+// advance は行末の改行を含まない.
+func ScanInlineFieldLine(raw []rune, ptr int) (advance int, key string, value string) {
+	if ptr != 0 && !precededBy(raw, ptr, []string{"\n"}) {
+		return 0, "", ""
+	}
+
+	cur := ptr
+	for cur < len(raw) && (raw[cur] == ' ' || raw[cur] == '\t') {
+		cur++
+	}
+	if cur+1 < len(raw) && (raw[cur] == '-' || raw[cur] == '*' || raw[cur] == '+') && raw[cur+1] == ' ' {
+		cur += 2
+		for cur < len(raw) && (raw[cur] == ' ' || raw[cur] == '\t') {
+			cur++
+		}
+	}
+	lineEnd := cur
+	for lineEnd < len(raw) && raw[lineEnd] != '\n' {
+		lineEnd++
+	}
+
+	line := string(raw[cur:lineEnd])
+	pos := strings.Index(line, "::")
+	if pos < 0 {
+		return 0, "", ""
+	}
+	key = strings.TrimRight(line[:pos], " \t")
+	if !validInlineFieldKey(key) {
+		return 0, "", ""
+	}
+	value = strings.Trim(line[pos+2:], " \t\r")
+	return lineEnd - ptr, key, value
+}
+
+// key には文字, 数字, 空白, -, _ だけを使える
+func validInlineFieldKey(key string) bool {
+	if key == "" || key[0] == ' ' || key[0] == '\t' {
+		return false
+	}
+	for _, r := range key {
+		if !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == ' ' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// - [ ] text の形式のタスクをスキャン.
+// リストマーカーは -, *, + または 1. 1) のような番号.
+// status は [ ] の中の文字, text は ] の後の行末までの文字列.
+// advance は行末の改行を含まない.
+func ScanTask(raw []rune, ptr int) (advance int, status rune, text string) {
+	if ptr != 0 && !precededBy(raw, ptr, []string{"\n"}) {
+		return 0, 0, ""
+	}
+
+	cur := ptr
+	for cur < len(raw) && (raw[cur] == ' ' || raw[cur] == '\t') {
+		cur++
+	}
+	if cur < len(raw) && (raw[cur] == '-' || raw[cur] == '*' || raw[cur] == '+') {
+		cur++
+	} else {
+		digits := 0
+		for cur < len(raw) && unicode.IsDigit(raw[cur]) {
+			cur++
+			digits++
+		}
+		if digits == 0 || cur >= len(raw) || (raw[cur] != '.' && raw[cur] != ')') {
+			return 0, 0, ""
+		}
+		cur++
+	}
+	if !(cur+3 < len(raw) && raw[cur] == ' ' && raw[cur+1] == '[' && raw[cur+2] != '\n' && raw[cur+3] == ']') {
+		return 0, 0, ""
+	}
+	status = raw[cur+2]
+	cur += 4
+	if cur < len(raw) && raw[cur] != ' ' && raw[cur] != '\t' && raw[cur] != '\r' && raw[cur] != '\n' {
+		return 0, 0, ""
+	}
+
+	lineEnd := cur
+	for lineEnd < len(raw) && raw[lineEnd] != '\n' {
+		lineEnd++
+	}
+	text = strings.Trim(string(raw[cur:lineEnd]), " \t\r")
+	return lineEnd - ptr, status, text
+}
+
+// [TOC] または %%toc%% だけの行をスキャン. 大文字小文字は区別しない.
+// 前後の空白は許す. advance は行末の改行を含まない.
+func ScanTOCMarker(raw []rune, ptr int) (advance int) {
+	if ptr != 0 && !precededBy(raw, ptr, []string{"\n"}) {
+		return 0
+	}
+	lineEnd := ptr
+	for lineEnd < len(raw) && raw[lineEnd] != '\n' {
+		lineEnd++
+	}
+	switch strings.ToLower(strings.Trim(string(raw[ptr:lineEnd]), " \t\r")) {
+	case "[toc]", "%%toc%%":
+		return lineEnd - ptr
+	}
+	return 0
+}
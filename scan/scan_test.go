@@ -1097,3 +1097,529 @@ func TestScanExternalLinkVarDefGroup(t *testing.T) {
 		}
 	}
 }
+
+func TestScanImageLink(t *testing.T) {
+	cases := []struct {
+		name        string
+		raw         []rune
+		ptr         int
+		wantAdvance int
+		wantAlt     string
+		wantRef     string
+		wantTitle   string
+	}{
+		{
+			name:        "simple",
+			raw:         []rune("![image](image.png)"),
+			ptr:         0,
+			wantAdvance: 19,
+			wantAlt:     "image",
+			wantRef:     "image.png",
+		},
+		{
+			name:        "with title",
+			raw:         []rune("![image](static/image.png \"title\")"),
+			ptr:         0,
+			wantAdvance: 34,
+			wantAlt:     "image",
+			wantRef:     "static/image.png",
+			wantTitle:   "title",
+		},
+		{
+			name:        "escaped",
+			raw:         []rune("\\![image](image.png)"),
+			ptr:         1,
+			wantAdvance: 0,
+		},
+		{
+			name:        "not image",
+			raw:         []rune("[image](image.png)"),
+			ptr:         0,
+			wantAdvance: 0,
+		},
+	}
+
+	for _, tt := range cases {
+		gotAdvance, gotAlt, gotRef, gotTitle := ScanImageLink(tt.raw, tt.ptr)
+		if gotAdvance != tt.wantAdvance {
+			t.Errorf("[ERROR | advance - %v]\ngot: %v, want: %v", tt.name, gotAdvance, tt.wantAdvance)
+		}
+		if gotAlt != tt.wantAlt {
+			t.Errorf("[ERROR | alt - %v]\ngot: %q, want: %q", tt.name, gotAlt, tt.wantAlt)
+		}
+		if gotRef != tt.wantRef {
+			t.Errorf("[ERROR | ref - %v]\ngot: %q, want: %q", tt.name, gotRef, tt.wantRef)
+		}
+		if gotTitle != tt.wantTitle {
+			t.Errorf("[ERROR | title - %v]\ngot: %q, want: %q", tt.name, gotTitle, tt.wantTitle)
+		}
+	}
+}
+
+func TestScanInlineFootnote(t *testing.T) {
+	cases := []struct {
+		name        string
+		raw         []rune
+		ptr         int
+		wantAdvance int
+		wantContent string
+	}{
+		{
+			name:        "simple",
+			raw:         []rune("^[note] text"),
+			ptr:         0,
+			wantAdvance: 7,
+			wantContent: "note",
+		},
+		{
+			name:        "with link",
+			raw:         []rune("^[see [google](https://google.com)]"),
+			ptr:         0,
+			wantAdvance: 35,
+			wantContent: "see [google](https://google.com)",
+		},
+		{
+			name:        "escaped bracket",
+			raw:         []rune("^[a \\] b]"),
+			ptr:         0,
+			wantAdvance: 9,
+			wantContent: "a \\] b",
+		},
+		{
+			name:        "escaped",
+			raw:         []rune("\\^[note]"),
+			ptr:         1,
+			wantAdvance: 0,
+		},
+		{
+			name:        "across blank line",
+			raw:         []rune("^[note\n\ntext]"),
+			ptr:         0,
+			wantAdvance: 0,
+		},
+		{
+			name:        "not closed",
+			raw:         []rune("^[note"),
+			ptr:         0,
+			wantAdvance: 0,
+		},
+		{
+			name:        "empty",
+			raw:         []rune("^[ ] "),
+			ptr:         0,
+			wantAdvance: 0,
+		},
+	}
+
+	for _, tt := range cases {
+		gotAdvance, gotContent := ScanInlineFootnote(tt.raw, tt.ptr)
+		if gotAdvance != tt.wantAdvance {
+			t.Errorf("[ERROR | advance - %v]\ngot: %v, want: %v", tt.name, gotAdvance, tt.wantAdvance)
+		}
+		if gotContent != tt.wantContent {
+			t.Errorf("[ERROR | content - %v]\ngot: %q, want: %q", tt.name, gotContent, tt.wantContent)
+		}
+	}
+}
+
+func TestScanFootnoteRef(t *testing.T) {
+	cases := []struct {
+		name        string
+		raw         []rune
+		ptr         int
+		wantAdvance int
+		wantLabel   string
+	}{
+		{
+			name:        "ref",
+			raw:         []rune("[^1] text"),
+			ptr:         0,
+			wantAdvance: 4,
+			wantLabel:   "1",
+		},
+		{
+			name:        "definition",
+			raw:         []rune("[^note]: text"),
+			ptr:         0,
+			wantAdvance: 7,
+			wantLabel:   "note",
+		},
+		{
+			name:        "with space",
+			raw:         []rune("[^a b]"),
+			ptr:         0,
+			wantAdvance: 0,
+		},
+		{
+			name:        "escaped",
+			raw:         []rune("\\[^1]"),
+			ptr:         1,
+			wantAdvance: 0,
+		},
+	}
+
+	for _, tt := range cases {
+		gotAdvance, gotLabel := ScanFootnoteRef(tt.raw, tt.ptr)
+		if gotAdvance != tt.wantAdvance {
+			t.Errorf("[ERROR | advance - %v]\ngot: %v, want: %v", tt.name, gotAdvance, tt.wantAdvance)
+		}
+		if gotLabel != tt.wantLabel {
+			t.Errorf("[ERROR | label - %v]\ngot: %q, want: %q", tt.name, gotLabel, tt.wantLabel)
+		}
+	}
+}
+
+func TestScanCallout(t *testing.T) {
+	cases := []struct {
+		name        string
+		raw         []rune
+		ptr         int
+		wantAdvance int
+		wantType    string
+		wantFold    string
+		wantTitle   string
+		wantContent string
+	}{
+		{
+			name:        "simple",
+			raw:         []rune("> [!warning] Title\n> content\n\ntext"),
+			ptr:         0,
+			wantAdvance: 29,
+			wantType:    "warning",
+			wantTitle:   "Title",
+			wantContent: "content",
+		},
+		{
+			name:        "foldable without title",
+			raw:         []rune("text\n>[!tip]-\n>line1\n> > [!note]\n> > nested"),
+			ptr:         5,
+			wantAdvance: 38,
+			wantType:    "tip",
+			wantFold:    "-",
+			wantContent: "line1\n> [!note]\n> nested",
+		},
+		{
+			name:        "blockquote",
+			raw:         []rune("> quote\n"),
+			ptr:         0,
+			wantAdvance: 0,
+		},
+		{
+			name:        "not at line head",
+			raw:         []rune("a > [!note]\n"),
+			ptr:         2,
+			wantAdvance: 0,
+		},
+	}
+
+	for _, tt := range cases {
+		gotAdvance, gotType, gotFold, gotTitle, gotContent := ScanCallout(tt.raw, tt.ptr)
+		if gotAdvance != tt.wantAdvance {
+			t.Errorf("[ERROR | advance - %v]\ngot: %v, want: %v", tt.name, gotAdvance, tt.wantAdvance)
+		}
+		if gotType != tt.wantType || gotFold != tt.wantFold || gotTitle != tt.wantTitle {
+			t.Errorf("[ERROR | head - %v]\ngot: %q, %q, %q, want: %q, %q, %q", tt.name, gotType, gotFold, gotTitle, tt.wantType, tt.wantFold, tt.wantTitle)
+		}
+		if gotContent != tt.wantContent {
+			t.Errorf("[ERROR | content - %v]\ngot: %q, want: %q", tt.name, gotContent, tt.wantContent)
+		}
+	}
+}
+
+func TestScanHighlight(t *testing.T) {
+	cases := []struct {
+		name        string
+		raw         []rune
+		ptr         int
+		wantAdvance int
+		wantContent string
+	}{
+		{
+			name:        "simple",
+			raw:         []rune("==important== text"),
+			ptr:         0,
+			wantAdvance: 13,
+			wantContent: "important",
+		},
+		{
+			name:        "escaped closing",
+			raw:         []rune("==a \\== b=="),
+			ptr:         0,
+			wantAdvance: 11,
+			wantContent: "a \\== b",
+		},
+		{
+			name:        "escaped",
+			raw:         []rune("\\==a=="),
+			ptr:         1,
+			wantAdvance: 0,
+		},
+		{
+			name:        "setext heading",
+			raw:         []rune("Title\n=====\n"),
+			ptr:         6,
+			wantAdvance: 0,
+		},
+		{
+			name:        "across blank line",
+			raw:         []rune("==a\n\nb=="),
+			ptr:         0,
+			wantAdvance: 0,
+		},
+		{
+			name:        "not closed",
+			raw:         []rune("==abc"),
+			ptr:         0,
+			wantAdvance: 0,
+		},
+	}
+
+	for _, tt := range cases {
+		gotAdvance, gotContent := ScanHighlight(tt.raw, tt.ptr)
+		if gotAdvance != tt.wantAdvance {
+			t.Errorf("[ERROR | advance - %v]\ngot: %v, want: %v", tt.name, gotAdvance, tt.wantAdvance)
+		}
+		if gotContent != tt.wantContent {
+			t.Errorf("[ERROR | content - %v]\ngot: %q, want: %q", tt.name, gotContent, tt.wantContent)
+		}
+	}
+}
+
+func TestScanInlineField(t *testing.T) {
+	cases := []struct {
+		name        string
+		raw         []rune
+		ptr         int
+		wantAdvance int
+		wantKey     string
+		wantValue   string
+	}{
+		{
+			name:        "brackets",
+			raw:         []rune("[due:: 2026-11-01] text"),
+			ptr:         0,
+			wantAdvance: 18,
+			wantKey:     "due",
+			wantValue:   "2026-11-01",
+		},
+		{
+			name:        "parentheses",
+			raw:         []rune("(review date::later)"),
+			ptr:         0,
+			wantAdvance: 20,
+			wantKey:     "review date",
+			wantValue:   "later",
+		},
+		{
+			name:        "nested link",
+			raw:         []rune("[project:: [[Foo]]]"),
+			ptr:         0,
+			wantAdvance: 19,
+			wantKey:     "project",
+			wantValue:   "[[Foo]]",
+		},
+		{
+			name:        "external link",
+			raw:         []rune("[a:: b](https://example.com)"),
+			ptr:         0,
+			wantAdvance: 0,
+		},
+		{
+			name:        "internal link",
+			raw:         []rune("[[a::b]]"),
+			ptr:         0,
+			wantAdvance: 0,
+		},
+		{
+			name:        "across lines",
+			raw:         []rune("[a::\nb]"),
+			ptr:         0,
+			wantAdvance: 0,
+		},
+		{
+			name:        "escaped",
+			raw:         []rune("\\[a:: b]"),
+			ptr:         1,
+			wantAdvance: 0,
+		},
+	}
+
+	for _, tt := range cases {
+		gotAdvance, gotKey, gotValue := ScanInlineField(tt.raw, tt.ptr)
+		if gotAdvance != tt.wantAdvance {
+			t.Errorf("[ERROR | advance - %v]\ngot: %v, want: %v", tt.name, gotAdvance, tt.wantAdvance)
+		}
+		if gotKey != tt.wantKey {
+			t.Errorf("[ERROR | key - %v]\ngot: %q, want: %q", tt.name, gotKey, tt.wantKey)
+		}
+		if gotValue != tt.wantValue {
+			t.Errorf("[ERROR | value - %v]\ngot: %q, want: %q", tt.name, gotValue, tt.wantValue)
+		}
+	}
+}
+
+func TestScanInlineFieldLine(t *testing.T) {
+	cases := []struct {
+		name        string
+		raw         []rune
+		ptr         int
+		wantAdvance int
+		wantKey     string
+		wantValue   string
+	}{
+		{
+			name:        "simple",
+			raw:         []rune("status:: draft\nnext"),
+			ptr:         0,
+			wantAdvance: 14,
+			wantKey:     "status",
+			wantValue:   "draft",
+		},
+		{
+			name:        "list item",
+			raw:         []rune("text\n- tags:: a, b\r\n"),
+			ptr:         5,
+			wantAdvance: 14,
+			wantKey:     "tags",
+			wantValue:   "a, b",
+		},
+		{
+			name:        "not at the beginning of line",
+			raw:         []rune("a status:: draft"),
+			ptr:         2,
+			wantAdvance: 0,
+		},
+		{
+			name:        "invalid key",
+			raw:         []rune("see http://example.com::x"),
+			ptr:         0,
+			wantAdvance: 0,
+		},
+		{
+			name:        "inline field in brackets",
+			raw:         []rune("- [due:: today]"),
+			ptr:         0,
+			wantAdvance: 0,
+		},
+	}
+
+	for _, tt := range cases {
+		gotAdvance, gotKey, gotValue := ScanInlineFieldLine(tt.raw, tt.ptr)
+		if gotAdvance != tt.wantAdvance {
+			t.Errorf("[ERROR | advance - %v]\ngot: %v, want: %v", tt.name, gotAdvance, tt.wantAdvance)
+		}
+		if gotKey != tt.wantKey {
+			t.Errorf("[ERROR | key - %v]\ngot: %q, want: %q", tt.name, gotKey, tt.wantKey)
+		}
+		if gotValue != tt.wantValue {
+			t.Errorf("[ERROR | value - %v]\ngot: %q, want: %q", tt.name, gotValue, tt.wantValue)
+		}
+	}
+}
+
+func TestScanTask(t *testing.T) {
+	cases := []struct {
+		name        string
+		raw         []rune
+		ptr         int
+		wantAdvance int
+		wantStatus  rune
+		wantText    string
+	}{
+		{
+			name:        "open",
+			raw:         []rune("- [ ] task #project 📅 2026-11-01\nnext"),
+			ptr:         0,
+			wantAdvance: 32,
+			wantStatus:  ' ',
+			wantText:    "task #project 📅 2026-11-01",
+		},
+		{
+			name:        "done with indent and number",
+			raw:         []rune("text\n  1. [x] done\r\n"),
+			ptr:         5,
+			wantAdvance: 14,
+			wantStatus:  'x',
+			wantText:    "done",
+		},
+		{
+			name:        "empty text",
+			raw:         []rune("* [/]"),
+			ptr:         0,
+			wantAdvance: 5,
+			wantStatus:  '/',
+		},
+		{
+			name:        "link",
+			raw:         []rune("- [a](b)"),
+			ptr:         0,
+			wantAdvance: 0,
+		},
+		{
+			name:        "not a list",
+			raw:         []rune("[ ] task"),
+			ptr:         0,
+			wantAdvance: 0,
+		},
+		{
+			name:        "not at the beginning of line",
+			raw:         []rune("a - [ ] task"),
+			ptr:         2,
+			wantAdvance: 0,
+		},
+	}
+
+	for _, tt := range cases {
+		gotAdvance, gotStatus, gotText := ScanTask(tt.raw, tt.ptr)
+		if gotAdvance != tt.wantAdvance {
+			t.Errorf("[ERROR | advance - %v]\ngot: %v, want: %v", tt.name, gotAdvance, tt.wantAdvance)
+		}
+		if gotStatus != tt.wantStatus {
+			t.Errorf("[ERROR | status - %v]\ngot: %q, want: %q", tt.name, gotStatus, tt.wantStatus)
+		}
+		if gotText != tt.wantText {
+			t.Errorf("[ERROR | text - %v]\ngot: %q, want: %q", tt.name, gotText, tt.wantText)
+		}
+	}
+}
+
+func TestScanTOCMarker(t *testing.T) {
+	cases := []struct {
+		name        string
+		raw         []rune
+		ptr         int
+		wantAdvance int
+	}{
+		{
+			name:        "[TOC]",
+			raw:         []rune("[TOC]\ntext"),
+			ptr:         0,
+			wantAdvance: 5,
+		},
+		{
+			name:        "%%toc%% with spaces",
+			raw:         []rune("text\n %%TOC%% \r\n"),
+			ptr:         5,
+			wantAdvance: 10,
+		},
+		{
+			name:        "not alone in the line",
+			raw:         []rune("see [TOC]"),
+			ptr:         4,
+			wantAdvance: 0,
+		},
+		{
+			name:        "other text",
+			raw:         []rune("[TOC] here"),
+			ptr:         0,
+			wantAdvance: 0,
+		},
+	}
+
+	for _, tt := range cases {
+		gotAdvance := ScanTOCMarker(tt.raw, tt.ptr)
+		if gotAdvance != tt.wantAdvance {
+			t.Errorf("[ERROR | advance - %v]\ngot: %v, want: %v", tt.name, gotAdvance, tt.wantAdvance)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// -schema で読み込む front matter の規則.
+// path (tgt からの相対パスの glob) と if (-filter と同じ形式の条件) の両方に合うノートに fields を適用する.
+//
+//	# schema.yml
+//	- path: "posts/**"
+//	  if: "!draft"
+//	  fields:
+//	    title: {required: true, type: string}
+//	    tags: {type: list, pattern: "^[a-z0-9-]+$"}
+//	    status: {values: [wip, done]}
+type frontMatterSchema struct {
+	scopes []*schemaScope
+}
+
+type schemaScope struct {
+	Path   string                  `yaml:"path"`
+	If     string                  `yaml:"if"`
+	Fields map[string]*schemaField `yaml:"fields"`
+	path   *regexp.Regexp
+	cond   *nodeImpl
+	keys   []string // エラーの順番をそろえるため
+}
+
+// リストの場合, values と pattern は要素ごとに確かめる
+type schemaField struct {
+	Required bool          `yaml:"required"`
+	Type     string        `yaml:"type"`
+	Values   []interface{} `yaml:"values"`
+	Pattern  string        `yaml:"pattern"`
+	pattern  *regexp.Regexp
+}
+
+const (
+	SCHEMA_TYPE_STRING = "string"
+	SCHEMA_TYPE_BOOL   = "bool"
+	SCHEMA_TYPE_INT    = "int"
+	SCHEMA_TYPE_NUMBER = "number"
+	SCHEMA_TYPE_DATE   = "date"
+	SCHEMA_TYPE_LIST   = "list"
+	SCHEMA_TYPE_MAP    = "map"
+)
+
+var SCHEMA_TYPES = []string{SCHEMA_TYPE_STRING, SCHEMA_TYPE_BOOL, SCHEMA_TYPE_INT, SCHEMA_TYPE_NUMBER, SCHEMA_TYPE_DATE, SCHEMA_TYPE_LIST, SCHEMA_TYPE_MAP}
+
+// front matter が schema に合わない
+type schemaViolation struct {
+	key     string
+	message string
+}
+
+func (v *schemaViolation) Error() string {
+	return fmt.Sprintf("schema violation: %s: %s", v.key, v.message)
+}
+
+// scope の if を評価できなかった.
+// そのノートは検証できていないので, -strictschema では違反として数える.
+type schemaScopeError struct {
+	index int // -schema の何番目の scope か (1 から)
+	cond  string
+	err   error
+}
+
+func (e *schemaScopeError) Error() string {
+	return fmt.Sprintf("schema scope %d (if: %s) could not be evaluated: %v", e.index, e.cond, e.err)
+}
+
+func countSchemaViolations(errs []error) int {
+	n := 0
+	for _, err := range errs {
+		switch errors.Cause(err).(type) {
+		case *schemaViolation, *schemaScopeError:
+			n++
+		}
+	}
+	return n
+}
+
+func readFrontMatterSchema(path string) (schema *frontMatterSchema, err error) {
+	if path == "" {
+		return nil, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", path)
+	}
+	return parseFrontMatterSchema(content)
+}
+
+func parseFrontMatterSchema(content []byte) (schema *frontMatterSchema, err error) {
+	schema = new(frontMatterSchema)
+	if err := yaml.UnmarshalStrict(content, &schema.scopes); err != nil {
+		return nil, newMainErrf(MAIN_ERR_KIND_INVALID_SCHEMA_FORMAT, "invalid format of %s: %v", FLAG_SCHEMA, err)
+	}
+	for i, scope := range schema.scopes {
+		if scope == nil {
+			return nil, newMainErrf(MAIN_ERR_KIND_INVALID_SCHEMA_FORMAT, "invalid format of %s: scope %d is empty", FLAG_SCHEMA, i+1)
+		}
+		if err := scope.compile(); err != nil {
+			return nil, newMainErrf(MAIN_ERR_KIND_INVALID_SCHEMA_FORMAT, "invalid format of %s: scope %d: %v", FLAG_SCHEMA, i+1, err)
+		}
+	}
+	return schema, nil
+}
+
+func (scope *schemaScope) compile() error {
+	if scope.Path != "" {
+		scope.path = globToRegexp(scope.Path)
+	}
+	if scope.If != "" {
+		cond, err := compileFilter(scope.If)
+		if err != nil {
+			return fmt.Errorf("invalid condition: %w", err)
+		}
+		scope.cond = cond
+	}
+	for key, f := range scope.Fields {
+		if f == nil {
+			return fmt.Errorf("%s: no rules", key)
+		}
+		if f.Type != "" {
+			valid := false
+			for _, t := range SCHEMA_TYPES {
+				if f.Type == t {
+					valid = true
+					break
+				}
+			}
+			if !valid {
+				return fmt.Errorf("%s: unknown type: %s. Available types: %s", key, f.Type, strings.Join(SCHEMA_TYPES, ", "))
+			}
+		}
+		if f.Pattern != "" {
+			pattern, err := regexp.Compile(f.Pattern)
+			if err != nil {
+				return fmt.Errorf("%s: invalid pattern: %v", key, err)
+			}
+			f.pattern = pattern
+		}
+		scope.keys = append(scope.keys, key)
+	}
+	sort.Strings(scope.keys)
+	return nil
+}
+
+// ** は / を含む任意の文字列, * と ? は / を含まない
+func globToRegexp(glob string) *regexp.Regexp {
+	b := new(strings.Builder)
+	b.WriteString("^")
+	runes := []rune(glob)
+	for i := 0; i < len(runes); i++ {
+		switch {
+		case runes[i] == '*' && i+1 < len(runes) && runes[i+1] == '*':
+			b.WriteString(".*")
+			i++
+		case runes[i] == '*':
+			b.WriteString("[^/]*")
+		case runes[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(runes[i])))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+// path は tgt からの相対パス
+func (schema *frontMatterSchema) validate(m map[interface{}]interface{}, path string) (violations []error) {
+	env := newFilterEnv(m, path, nil)
+	for i, scope := range schema.scopes {
+		if scope.path != nil && !scope.path.MatchString(env.path) {
+			continue
+		}
+		if scope.cond != nil {
+			ok, err := evaluateNodeIn(scope.cond, env)
+			if err != nil {
+				violations = append(violations, &schemaScopeError{index: i + 1, cond: scope.If, err: err})
+				continue
+			}
+			if !ok {
+				continue
+			}
+		}
+		for _, key := range scope.keys {
+			if msg := scope.Fields[key].check(m[key], hasKey(m, key)); msg != "" {
+				violations = append(violations, &schemaViolation{key: key, message: msg})
+			}
+		}
+	}
+	return violations
+}
+
+func hasKey(m map[interface{}]interface{}, key string) bool {
+	_, ok := m[key]
+	return ok
+}
+
+func (f *schemaField) check(v interface{}, exists bool) string {
+	if !exists || v == nil {
+		if f.Required {
+			return "required but not found"
+		}
+		return ""
+	}
+	if f.Type != "" && !schemaTypeMatches(v, f.Type) {
+		return fmt.Sprintf("want %s but got %s", f.Type, schemaTypeOf(v))
+	}
+	// tags と aliases は変換の途中で []string になっている
+	values := []interface{}{v}
+	switch list := v.(type) {
+	case []interface{}:
+		values = list
+	case []string:
+		values = stringsToYaml(list)
+	}
+	for _, a := range values {
+		if f.Values != nil && !containsYamlValue(f.Values, a) {
+			return fmt.Sprintf("%v is not one of %v", a, f.Values)
+		}
+		if f.pattern != nil && !f.pattern.MatchString(fmt.Sprint(a)) {
+			return fmt.Sprintf("%v does not match %s", a, f.Pattern)
+		}
+	}
+	return ""
+}
+
+func schemaTypeMatches(v interface{}, typ string) bool {
+	if typ == SCHEMA_TYPE_DATE {
+		_, ok := dateInYaml(v)
+		return ok
+	}
+	actual := schemaTypeOf(v)
+	return actual == typ || (typ == SCHEMA_TYPE_NUMBER && actual == SCHEMA_TYPE_INT)
+}
+
+func schemaTypeOf(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return SCHEMA_TYPE_STRING
+	case bool:
+		return SCHEMA_TYPE_BOOL
+	case int, int64, uint64:
+		return SCHEMA_TYPE_INT
+	case float64:
+		return SCHEMA_TYPE_NUMBER
+	case time.Time:
+		return SCHEMA_TYPE_DATE
+	case []interface{}, []string:
+		return SCHEMA_TYPE_LIST
+	case map[interface{}]interface{}:
+		return SCHEMA_TYPE_MAP
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func containsYamlValue(values []interface{}, v interface{}) bool {
+	for _, a := range values {
+		if reflect.DeepEqual(a, v) {
+			return true
+		}
+	}
+	return false
+}
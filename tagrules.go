@@ -0,0 +1,185 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// TagFinder で見つけたタグに適用する規則.
+// 名前の変換 (rename -> lower -> slugify), prefix による振り分け, 階層の展開の順に適用する.
+type tagRules struct {
+	expand      bool
+	rename      map[string]string
+	lower       bool
+	slugify     bool
+	routes      []tagRoute
+	frontMatter bool
+}
+
+// prefix/xxx のタグを tags ではなく key に xxx として追加する
+type tagRoute struct {
+	prefix string
+	key    string
+}
+
+// 規則が 1 つもなければ nil を返す
+func newTagRules(expand bool, tagMapPath string, lower bool, slugify bool, routeTag string, frontMatter bool) (rules *tagRules, err error) {
+	rename, err := readTagMap(tagMapPath)
+	if err != nil {
+		return nil, err
+	}
+	routes, err := parseTagRoutes(routeTag)
+	if err != nil {
+		return nil, err
+	}
+	if !expand && rename == nil && !lower && !slugify && routes == nil {
+		return nil, nil
+	}
+	return &tagRules{
+		expand:      expand,
+		rename:      rename,
+		lower:       lower,
+		slugify:     slugify,
+		routes:      routes,
+		frontMatter: frontMatter,
+	}, nil
+}
+
+// old: new の形式の YAML ファイル. 複数のタグを同じタグにまとめることもできる.
+func readTagMap(path string) (rename map[string]string, err error) {
+	if path == "" {
+		return nil, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", path)
+	}
+	m := make(map[string]string)
+	if err := yaml.Unmarshal(content, &m); err != nil {
+		return nil, newMainErrf(MAIN_ERR_KIND_INVALID_TAG_MAP_FORMAT, "invalid format of %s: %v", FLAG_TAG_MAP, err)
+	}
+	rename = make(map[string]string)
+	for from, to := range m {
+		rename[strings.TrimPrefix(from, "#")] = strings.TrimPrefix(to, "#")
+	}
+	return rename, nil
+}
+
+// -routeTag の値をパースする. 例: cat>categories|series>series
+func parseTagRoutes(input string) (routes []tagRoute, err error) {
+	if input == "" {
+		return nil, nil
+	}
+	entries := strings.Split(input, "|")
+	for _, entry := range entries {
+		pair := strings.Split(entry, ">")
+		if len(pair) != 2 || pair[0] == "" || pair[1] == "" {
+			return nil, newMainErrf(MAIN_ERR_KIND_INVALID_ROUTE_TAG_FORMAT, "invalid format of %s: \"%s\"", FLAG_ROUTE_TAG, input)
+		}
+		routes = append(routes, tagRoute{prefix: strings.TrimSuffix(strings.TrimPrefix(pair[0], "#"), "/"), key: pair[1]})
+	}
+	return routes, nil
+}
+
+// tags に規則を適用する. 振り分けられたタグは routed に追加する.
+// 重複は除き, 順番は保つ.
+func (r *tagRules) apply(tags []string, routed map[string][]string) (kept []string) {
+	kept = make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = r.name(tag)
+		if tag == "" {
+			continue
+		}
+		key := ""
+		for _, route := range r.routes {
+			if strings.HasPrefix(tag, route.prefix+"/") {
+				key = route.key
+				tag = strings.TrimPrefix(tag, route.prefix+"/")
+				break
+			}
+		}
+		expanded := []string{tag}
+		if r.expand {
+			expanded = expandTag(tag)
+		}
+		if key == "" {
+			kept = appendUniqueTags(kept, expanded...)
+		} else {
+			routed[key] = appendUniqueTags(routed[key], expanded...)
+		}
+	}
+	return kept
+}
+
+// 本文のタグのリンク先のページの名前. tags に入らないタグは空にする
+func (r *tagRules) pageName(tag string) string {
+	tag = r.name(tag)
+	for _, route := range r.routes {
+		if strings.HasPrefix(tag, route.prefix+"/") {
+			return ""
+		}
+	}
+	return tag
+}
+
+func (r *tagRules) name(tag string) string {
+	if v, ok := r.rename[tag]; ok {
+		tag = v
+	}
+	if r.lower || r.slugify {
+		tag = strings.ToLower(tag)
+	}
+	if r.slugify {
+		tag = slugifyTag(tag)
+	}
+	return tag
+}
+
+// a/b/c -> a, a/b, a/b/c
+func expandTag(tag string) []string {
+	segments := strings.Split(tag, "/")
+	expanded := make([]string, 0, len(segments))
+	for i := range segments {
+		expanded = append(expanded, strings.Join(segments[:i+1], "/"))
+	}
+	return expanded
+}
+
+// 文字, 数字, _ 以外を - に置き換える. / は階層の区切りとして残す.
+func slugifyTag(tag string) string {
+	segments := make([]string, 0)
+	for _, s := range strings.Split(tag, "/") {
+		b := new(strings.Builder)
+		for _, r := range s {
+			if unicode.IsLetter(r) || unicode.IsNumber(r) || r == '_' {
+				b.WriteRune(r)
+			} else if !strings.HasSuffix(b.String(), "-") {
+				b.WriteRune('-')
+			}
+		}
+		if slug := strings.Trim(b.String(), "-"); slug != "" {
+			segments = append(segments, slug)
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func appendUniqueTags(tags []string, newtags ...string) []string {
+	for _, t := range newtags {
+		exists := false
+		for _, tt := range tags {
+			if tt == t {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/qawatake/obsdconv/convert"
+	"github.com/qawatake/obsdconv/process"
+)
+
+const (
+	TASK_REPORT_JSON     = "json"
+	TASK_REPORT_MARKDOWN = "md"
+)
+
+var TASK_REPORT_FORMATS = []string{TASK_REPORT_JSON, TASK_REPORT_MARKDOWN}
+
+type taskReportEntry struct {
+	Path       string   `json:"path"`
+	Line       int      `json:"line"`
+	Status     string   `json:"status"`
+	Text       string   `json:"text"`
+	Due        string   `json:"due,omitempty"`
+	Scheduled  string   `json:"scheduled,omitempty"`
+	Start      string   `json:"start,omitempty"`
+	Created    string   `json:"created,omitempty"`
+	Done       string   `json:"done,omitempty"`
+	Cancelled  string   `json:"cancelled,omitempty"`
+	Recurrence string   `json:"recurrence,omitempty"`
+	Priority   string   `json:"priority,omitempty"`
+	Tags       []string `json:"tags"`
+	mark       rune
+}
+
+// front matter に書き込むタスクの数 (-taskcount)
+type taskCounts struct {
+	open  int
+	done  int
+	total int // キャンセルなども含めたタスクの数
+}
+
+// tasks == nil (-taskcount がない) のときは nil.
+// タスクがないノートも, front matter に残っている古い数を消すために空の taskCounts を返す.
+func countTasks(tasks []convert.Task) *taskCounts {
+	if tasks == nil {
+		return nil
+	}
+	counts := &taskCounts{total: len(tasks)}
+	for _, t := range tasks {
+		if t.Open() {
+			counts.open++
+		} else if t.Status == convert.TASK_STATUS_DONE {
+			counts.done++
+		}
+	}
+	return counts
+}
+
+// tgt 以下のノートのタスクの一覧を format の形式で出力する (-taskReport).
+// パスは vault からの相対パス. 行番号は front matter も含めたファイルの行番号.
+func newTaskReport(vault string, tgt string, skipper process.Skipper, format string) (report string, err error) {
+	entries, err := collectTasks(vault, tgt, skipper)
+	if err != nil {
+		return "", err
+	}
+	switch format {
+	case TASK_REPORT_JSON:
+		b, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return "", errors.Wrap(err, "failed to marshal tasks")
+		}
+		return string(b), nil
+	case TASK_REPORT_MARKDOWN:
+		return renderTaskReportMarkdown(entries), nil
+	}
+	return "", newMainErr(MAIN_ERR_KIND_INVALID_TASK_REPORT_FORMAT)
+}
+
+func collectTasks(vault string, tgt string, skipper process.Skipper) (entries []taskReportEntry, err error) {
+	entries = make([]taskReportEntry, 0)
+	err = filepath.Walk(tgt, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rpath, err := filepath.Rel(vault, path)
+		if err != nil {
+			return err
+		}
+		if skipper.Skip(rpath) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() || filepath.Ext(path) != ".md" {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read %s", path)
+		}
+		doc := process.SplitFrontMatter(content)
+		body := doc.Body()
+		// front matter と区切りの行の分だけ行番号をずらす
+		offset := doc.BodyLine
+
+		tasks := make([]convert.Task, 0)
+		if _, err := convert.NewTaskFinder(&tasks).Convert(body); err != nil {
+			return errors.Wrapf(err, "TaskFinder failed in %s", path)
+		}
+		for _, t := range tasks {
+			entries = append(entries, taskReportEntry{
+				Path:       filepath.ToSlash(rpath),
+				Line:       t.Line + offset,
+				Status:     t.Status,
+				Text:       t.Text,
+				Due:        t.Due,
+				Scheduled:  t.Scheduled,
+				Start:      t.Start,
+				Created:    t.Created,
+				Done:       t.Done,
+				Cancelled:  t.Cancelled,
+				Recurrence: t.Recurrence,
+				Priority:   t.Priority,
+				Tags:       t.Tags,
+				mark:       t.Mark,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// 状態ごとに見出しを分けてタスクを並べる.
+// 未完了のタスクは期限の早い順 (期限なしは最後).
+func renderTaskReportMarkdown(entries []taskReportEntry) string {
+	sections := []struct {
+		heading string
+		match   func(e taskReportEntry) bool
+	}{
+		{"Open", func(e taskReportEntry) bool {
+			return e.Status == convert.TASK_STATUS_OPEN || e.Status == convert.TASK_STATUS_IN_PROGRESS
+		}},
+		{"Done", func(e taskReportEntry) bool { return e.Status == convert.TASK_STATUS_DONE }},
+		{"Cancelled", func(e taskReportEntry) bool { return e.Status == convert.TASK_STATUS_CANCELLED }},
+		{"Other", func(e taskReportEntry) bool { return e.Status == convert.TASK_STATUS_OTHER }},
+	}
+
+	b := new(strings.Builder)
+	b.WriteString("# Tasks\n")
+	for i, s := range sections {
+		matched := make([]taskReportEntry, 0)
+		for _, e := range entries {
+			if s.match(e) {
+				matched = append(matched, e)
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+		if i == 0 {
+			sort.SliceStable(matched, func(i, j int) bool {
+				if matched[i].Due == "" || matched[j].Due == "" {
+					return matched[i].Due != ""
+				}
+				return matched[i].Due < matched[j].Due
+			})
+		}
+		fmt.Fprintf(b, "\n## %s\n\n", s.heading)
+		for _, e := range matched {
+			name := strings.TrimSuffix(e.Path, ".md")
+			fmt.Fprintf(b, "- [%c] %s ([[%s|%s]], line %d)\n", e.mark, e.Text, name, filepath.Base(name), e.Line)
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"github.com/qawatake/obsdconv/convert"
+)
+
+// front matter に書き込む単語数と読了時間 (-readingtime)
+type textStats struct {
+	words       int // CJK の文字は 1 文字を 1 語として数える
+	readingTime int // 分
+}
+
+func newTextStats(body []rune) (*textStats, error) {
+	text, err := convert.PlainText(body)
+	if err != nil {
+		return nil, err
+	}
+	words, cjk := convert.CountWords(text)
+	return &textStats{
+		words:       words + cjk,
+		readingTime: convert.ReadingTime(words, cjk),
+	}, nil
+}
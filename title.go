@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"github.com/qawatake/obsdconv/convert"
+	"github.com/qawatake/obsdconv/process"
+)
+
+// H1 がないときの title の候補 (-fallbacktitle, -titleTemplate)
+type titleSources struct {
+	frontMatter string // front matter の title
+	heading     string // 最初の見出し. レベルは問わない
+	fileName    string // .md を除いたファイル名
+}
+
+// front matter の title, 最初の見出し, ファイル名の順に探す
+func (s *titleSources) fallback() string {
+	for _, t := range []string{s.frontMatter, s.heading, s.fileName} {
+		if t != "" {
+			return t
+		}
+	}
+	return ""
+}
+
+// body はタグを除き, リンクを表示名に置き換えたもの
+func newTitleSources(body []rune, frontMatter *process.FrontMatter, selfRelativePath string) (*titleSources, error) {
+	sources := new(titleSources)
+	var err error
+	sources.frontMatter, err = frontMatterTitle(frontMatter)
+	if err != nil {
+		return nil, err
+	}
+	headings := make([]convert.Heading, 0)
+	if _, err := convert.NewHeadingFinder(&headings).Convert(body); err != nil {
+		return nil, errors.Wrap(err, "HeadingFinder failed")
+	}
+	if len(headings) > 0 {
+		sources.heading = headings[0].Text
+	}
+	sources.fileName = strings.TrimSuffix(filepath.Base(selfRelativePath), ".md")
+	return sources, nil
+}
+
+func frontMatterTitle(frontMatter *process.FrontMatter) (title string, err error) {
+	v, ok := frontMatter.Get("title")
+	if !ok || v == nil {
+		return "", nil
+	}
+	switch v.(type) {
+	case []interface{}, map[interface{}]interface{}:
+		return "", fmt.Errorf("title field found but its field type is not string: %T", v)
+	}
+	return fmt.Sprint(v), nil
+}
+
+// -titleTemplate に渡す値.
+// 例: {{trimDate .FileName}} は 2026-10-17 Standup.md から Standup を作る.
+type titleTemplateData struct {
+	Title    string // H1. -fallbacktitle のときは代わりの title
+	H1       string
+	Heading  string
+	FileName string
+	Date     string // ファイル名の先頭の日付. なければ空
+}
+
+var titleDatePrefix = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})[\s_-]*`)
+
+func trimDatePrefix(s string) string {
+	return titleDatePrefix.ReplaceAllString(s, "")
+}
+
+func parseTitleTemplate(input string) (tmpl *template.Template, err error) {
+	if input == "" {
+		return nil, nil
+	}
+	tmpl, err = template.New(FLAG_TITLE_TEMPLATE).Funcs(template.FuncMap{"trimDate": trimDatePrefix}).Option("missingkey=error").Parse(input)
+	if err != nil {
+		return nil, newMainErrf(MAIN_ERR_KIND_INVALID_TITLE_TEMPLATE, "invalid format of %s: %v", FLAG_TITLE_TEMPLATE, err)
+	}
+	return tmpl, nil
+}
+
+func executeTitleTemplate(tmpl *template.Template, title string, h1 string, sources *titleSources) (string, error) {
+	data := titleTemplateData{Title: title, H1: h1}
+	if sources != nil {
+		data.Heading = sources.heading
+		data.FileName = sources.fileName
+		if m := titleDatePrefix.FindStringSubmatch(sources.fileName); m != nil {
+			data.Date = m[1]
+		}
+	}
+	b := new(strings.Builder)
+	if err := tmpl.Execute(b, data); err != nil {
+		return "", fmt.Errorf("failed to execute %s: %w", FLAG_TITLE_TEMPLATE, err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}